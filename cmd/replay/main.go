@@ -0,0 +1,166 @@
+// Command replay inspects capture files (SocketConfig.Capture) and feeds a captured
+// session back into a live server or client.
+//
+//	replay -file session.sscap -list
+//	replay -file session.sscap -dump [-session 2]
+//	replay -file session.sscap -connect 127.0.0.1:8080 [-session 2] [-speed 1]
+//	replay -file session.sscap -listen 127.0.0.1:8080 [-session 2] [-speed 1]
+//
+// -connect plays the client side of the session into a server, -listen waits for a
+// client and plays the server side. The transport defaults to the captured one.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/capture"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/replay"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+func main() {
+	file := flag.String("file", "", "capture file to read")
+	list := flag.Bool("list", false, "list the sessions of the capture")
+	dump := flag.Bool("dump", false, "print the frames of the capture (or of -session)")
+	session := flag.Uint("session", 0, "session to replay or dump (0 = the first one, or all for -dump)")
+	connect := flag.String("connect", "", "replay the client side into the server at this address")
+	listen := flag.String("listen", "", "replay the server side into the first client connecting to this address")
+	transport := flag.String("transport", "", "FramedTCP, TLS, SharedMemory or UDP (default: the captured transport)")
+	speed := flag.Float64("speed", 1, "pacing: 1 = as captured, 2 = twice as fast, 0 = no pauses")
+	timeout := flag.Duration("timeout", replay.DefaultReadTimeout, "wait for each message expected from the peer")
+	var tlsOpts transports.TLSOptions
+	flag.StringVar(&tlsOpts.CertFile, "cert", "", "TLS certificate (server, or mTLS client)")
+	flag.StringVar(&tlsOpts.KeyFile, "key", "", "TLS private key")
+	flag.StringVar(&tlsOpts.CAFile, "ca", "", "TLS CA bundle")
+	flag.BoolVar(&tlsOpts.InsecureSkipVerify, "insecure", false, "TLS client: skip server certificate verification")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch {
+	case *list:
+		err = listSessions(*file)
+	case *dump:
+		err = dumpRecords(*file, uint32(*session))
+	case *connect != "" || *listen != "":
+		err = play(*file, uint32(*session), *connect, *listen, interfaces.TransportType(*transport), tlsOpts,
+			replay.Options{Speed: *speed, ReadTimeout: *timeout})
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func listSessions(path string) error {
+	records, err := capture.ReadAll(path)
+	if err != nil {
+		return err
+	}
+	sessions, err := replay.Sessions(records)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		fmt.Printf("%d\t%s\t%s/%s\t%s -> %s\t%d frames\n", s.ID, s.Info.Role, s.Info.Transport, s.Info.Protocol,
+			s.Info.LocalAddr, s.Info.RemoteAddr, len(s.Records))
+	}
+	return nil
+}
+
+func dumpRecords(path string, session uint32) error {
+	records, err := capture.ReadAll(path)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if session != 0 && r.Session != session {
+			continue
+		}
+		preview := r.Body
+		if len(preview) > 32 {
+			preview = preview[:32]
+		}
+		fmt.Printf("%s\t%d\t%-5s\tv%d\ttype=%d\tflags=%#02x\t%d\t%s\n", r.Time.Format(time.RFC3339Nano), r.Session,
+			r.Kind, r.Version, r.Type, r.Flags, len(r.Body), hex.EncodeToString(preview))
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func play(path string, id uint32, connect, listen string, transport interfaces.TransportType, tlsOpts transports.TLSOptions, opts replay.Options) error {
+	s, err := replay.Load(path, id)
+	if err != nil {
+		return err
+	}
+	if transport == "" {
+		transport = interfaces.TransportType(s.Info.Transport)
+	}
+
+	var conn interfaces.TransportConnection
+	role := "client"
+	if connect != "" {
+		conn, err = dial(transport, connect, tlsOpts)
+	} else {
+		role = "server"
+		var ln interfaces.TransportListener
+		if ln, err = listenOn(transport, listen, tlsOpts); err != nil {
+			return err
+		}
+		defer func() { _ = ln.Close() }() // Closing an SHM listener ends its connection too
+		conn, err = ln.Accept()
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	report, err := s.Play(conn, role, opts)
+	fmt.Printf("session %d: %d frames sent, %d messages received (%d differing from the capture)\n",
+		s.ID, report.Sent, report.Received, report.Mismatched)
+	return err
+}
+
+func dial(transport interfaces.TransportType, address string, tlsOpts transports.TLSOptions) (interfaces.TransportConnection, error) {
+	switch transport {
+	case interfaces.TransportFramedTCP:
+		return transports.Connect(address, 0)
+	case interfaces.TransportTLS:
+		return transports.ConnectTLSWithOptions(address, 0, tlsOpts)
+	case interfaces.TransportShm:
+		return transports.ConnectShm(address, 0)
+	case interfaces.TransportUDP:
+		return transports.ConnectUDP(address, 0)
+	}
+	return nil, fmt.Errorf("unsupported transport %q", transport)
+}
+
+func listenOn(transport interfaces.TransportType, address string, tlsOpts transports.TLSOptions) (interfaces.TransportListener, error) {
+	switch transport {
+	case interfaces.TransportFramedTCP:
+		return transports.Listen(address, 0)
+	case interfaces.TransportTLS:
+		return transports.ListenTLSWithOptions(address, 0, tlsOpts)
+	case interfaces.TransportShm:
+		return transports.ListenShm(address, 0)
+	case interfaces.TransportUDP:
+		return nil, errors.New("replaying into a UDP client is not supported")
+	}
+	return nil, fmt.Errorf("unsupported transport %q", transport)
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Bastien-Antigravity/safe-socket"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+)
+
+// TestSHM_Broadcast verifies that every shm-broadcast subscriber receives every message
+// of the publisher, in order.
+func TestSHM_Broadcast(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "market-data")
+	const messages = 1000
+
+	publisher, err := factory.Create("shm-broadcast", path, "", "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create publisher: %v", err)
+	}
+	defer func() { _ = publisher.Close() }()
+
+	if _, err := publisher.Accept(); err != safesocket.ErrBroadcastNoAccept {
+		t.Errorf("expected ErrBroadcastNoAccept, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		subscriber, err := factory.Create("shm-broadcast", path, "", "client", true)
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		defer func() { _ = subscriber.Close() }()
+
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for seq := 0; seq < messages; seq++ {
+				msg, err := subscriber.Receive()
+				if err != nil {
+					t.Errorf("subscriber %d: Receive failed: %v", n, err)
+					return
+				}
+				if want := fmt.Sprintf("quote-%d", seq); string(msg) != want {
+					t.Errorf("subscriber %d: expected %q, got %q", n, want, msg)
+					return
+				}
+			}
+		}(i)
+	}
+
+	// The default 32 MB ring holds the whole burst: nobody is overrun
+	for seq := 0; seq < messages; seq++ {
+		if err := publisher.Send([]byte(fmt.Sprintf("quote-%d", seq))); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/capture"
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/replay"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// TestCaptureReplay captures a tcp-hello session on the server, checks the frames of
+// the file, then replays the client side of the session into another server.
+func TestCaptureReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.sscap")
+	w, err := capture.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. Capture: hello, ping/pong, graceful close
+	server, err := factory.CreateWithConfig("tcp-hello:capture-server", "127.0.0.1:9355",
+		models.SocketConfig{Deadline: 2 * time.Second, Capture: w}, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	client, err := factory.CreateWithConfig("tcp-hello:capture-client", "127.0.0.1:9355",
+		models.SocketConfig{Deadline: 2 * time.Second, CloseAckTimeout: time.Second}, "client", true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	if err := client.Send([]byte("ping")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if msg, err := conn.ReadMessage(); err != nil || string(msg) != "ping" {
+		t.Fatalf("expected 'ping', got %q (%v)", msg, err)
+	}
+	if _, err := conn.Write([]byte("pong")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if msg, err := client.Receive(); err != nil || string(msg) != "pong" {
+		t.Fatalf("expected 'pong', got %q (%v)", msg, err)
+	}
+	go func() { _, _ = conn.ReadMessage() }() // Acknowledges the client's goodbye
+	_ = client.Close()
+	_ = conn.Close()
+	_ = server.Close()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// 2. The file holds the whole session, as seen by the server
+	s, err := replay.Load(path, 0)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Info.Role != "server" || s.Info.Transport != string(interfaces.TransportFramedTCP) || s.Info.Protocol != string(interfaces.ProtocolHello) {
+		t.Errorf("unexpected session %+v", s.Info)
+	}
+	type frame struct {
+		kind      capture.Kind
+		version   uint8
+		frameType interfaces.FrameType
+	}
+	want := []frame{
+		{capture.KindInbound, transports.FrameVersionLegacy, interfaces.FrameData},  // Client Hello
+		{capture.KindOutbound, transports.FrameVersionLegacy, interfaces.FrameData}, // Server Hello
+		{capture.KindInbound, transports.FrameVersionTyped, interfaces.FrameData},   // ping
+		{capture.KindOutbound, transports.FrameVersionTyped, interfaces.FrameData},  // pong
+		{capture.KindInbound, transports.FrameVersionTyped, interfaces.FrameGoodbye},
+		{capture.KindOutbound, transports.FrameVersionTyped, interfaces.FrameGoodbyeAck},
+	}
+	var got []frame
+	for _, r := range s.Records {
+		if f := (frame{r.Kind, r.Version, interfaces.FrameType(r.Type)}); f.frameType != interfaces.FrameHeartbeat {
+			got = append(got, f)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected frames %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("frame %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+	if string(s.Records[2].Body) != "ping" {
+		t.Errorf("expected the 'ping' body, got %q", s.Records[2].Body)
+	}
+
+	// 3. Replay the client side into a fresh server
+	replayServer, err := factory.CreateWithConfig("tcp-hello:replay-server", "127.0.0.1:9356",
+		models.SocketConfig{Deadline: 2 * time.Second}, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = replayServer.Close() }()
+
+	served := make(chan error, 1)
+	go func() {
+		conn, err := replayServer.Accept()
+		if err != nil {
+			served <- err
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		if name, _ := facade.IdentityOf(conn).FromName(); name != "capture-client" {
+			t.Errorf("expected the captured identity, got %q", name)
+		}
+		msg, err := conn.ReadMessage()
+		if err == nil && string(msg) != "ping" {
+			t.Errorf("expected the replayed 'ping', got %q", msg)
+		}
+		if err == nil {
+			_, err = conn.Write([]byte("pong"))
+		}
+		served <- err
+		_, _ = conn.ReadMessage() // Replayed goodbye
+	}()
+
+	raw, err := transports.Connect("127.0.0.1:9356", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer func() { _ = raw.Close() }()
+	report, err := s.Play(raw, "client", replay.Options{})
+	if err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("replay server failed: %v", err)
+	}
+	// The Server Hello differs (timestamps), the pong must not
+	if report.Received != 2 || report.Mismatched > 1 || report.Sent < 3 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
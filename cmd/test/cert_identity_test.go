@@ -0,0 +1,196 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket"
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// TestTLS_CertIdentityBinding verifies that a BindCertIdentity server only accepts Hello
+// names vouched for by the client certificate, and that both ends see the verified chain.
+func TestTLS_CertIdentityBinding(t *testing.T) {
+	addr := "127.0.0.1:9330"
+	pki := newTestPKI(t)
+	serverCert, serverKey := pki.issue(t, "server", nil, []net.IP{net.ParseIP("127.0.0.1")})
+
+	serverConfig := models.SocketConfig{
+		CertFile:         serverCert,
+		KeyFile:          serverKey,
+		CAFile:           pki.caFile,
+		BindCertIdentity: true,
+		CertNameMap:      map[string][]string{"spiffe://corp/worker": {"worker-*"}},
+	}
+	server, err := factory.CreateWithConfig("tls-hello:cert-server", addr, serverConfig, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	accepted := make(chan *safesocket.CertIdentity, 4)
+	go func() {
+		for {
+			conn, err := server.Accept()
+			if err != nil {
+				select {
+				case <-stop:
+					return
+				default:
+					continue
+				}
+			}
+			accepted <- safesocket.GetCertIdentity(conn)
+		}
+	}()
+
+	dial := func(name, cn string, uris []string) (safesocket.Socket, error) {
+		certFile, keyFile := pki.issue(t, cn, uris, nil)
+		config := models.SocketConfig{CertFile: certFile, KeyFile: keyFile, CAFile: pki.caFile}
+		return factory.CreateWithConfig("tls-hello:"+name, addr, config, "client", true)
+	}
+
+	// 1. Name equal to the certificate CN
+	client, err := dial("alice", "alice", nil)
+	if err != nil {
+		t.Fatalf("matching client failed to open: %v", err)
+	}
+	if cert := client.(*facade.SocketClient).GetCertIdentity(); cert == nil || cert.Leaf().Subject.CommonName != "server" {
+		t.Errorf("expected the verified server certificate on the client, got %+v", cert)
+	}
+	_ = client.Close()
+	select {
+	case cert := <-accepted:
+		if cert == nil || cert.Leaf().Subject.CommonName != "alice" || len(cert.Chain) != 2 {
+			t.Errorf("expected alice's verified chain on the server, got %+v", cert)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("matching client was not accepted")
+	}
+
+	// 2. Name not vouched for by the certificate
+	_, err = dial("mallory", "alice", nil)
+	var rejected *safesocket.HandshakeRejectedError
+	if !errors.As(err, &rejected) || !strings.Contains(rejected.Reason, safesocket.ErrCertIdentityMismatch.Error()) {
+		t.Errorf("expected a certificate mismatch rejection, got %v", err)
+	}
+
+	// 3. Name mapped from a SPIFFE URI SAN
+	client, err = dial("worker-07", "", []string{"spiffe://corp/worker"})
+	if err != nil {
+		t.Fatalf("mapped client failed to open: %v", err)
+	}
+	_ = client.Close()
+	select {
+	case cert := <-accepted:
+		if cert == nil || cert.SpiffeID != "spiffe://corp/worker" {
+			t.Errorf("expected SPIFFE ID 'spiffe://corp/worker', got %+v", cert)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("mapped client was not accepted")
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+// -----------------------------------------------------------------------------
+
+// testPKI is a throwaway CA issuing leaf certificates into a temporary directory.
+type testPKI struct {
+	dir    string
+	caFile string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SafeSocket Test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+
+	pki := &testPKI{dir: t.TempDir(), ca: ca, caKey: key, serial: 1}
+	pki.caFile = filepath.Join(pki.dir, "ca.crt")
+	writePEM(t, pki.caFile, "CERTIFICATE", der)
+	return pki
+}
+
+// issue signs a leaf usable as client and server certificate and returns its files.
+func (p *testPKI) issue(t *testing.T, cn string, uris []string, ips []net.IP) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(p.serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  ips,
+	}
+	for _, raw := range uris {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.URIs = append(template.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := filepath.Join(p.dir, big.NewInt(p.serial).String())
+	certFile, keyFile = base+".crt", base+".key"
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
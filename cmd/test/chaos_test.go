@@ -0,0 +1,108 @@
+package test
+
+import (
+	"errors"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/chaos"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// TestChaos drives the faults of a "+chaos" client at runtime and checks what the
+// server receives.
+func TestChaos(t *testing.T) {
+	server, err := factory.CreateWithConfig("tcp-hello:chaos-server", "127.0.0.1:9358",
+		models.SocketConfig{Deadline: 2 * time.Second}, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	inj := chaos.New(chaos.Config{}, 42) // No faults during the handshake
+	client, err := factory.CreateWithConfig("tcp-hello+chaos:chaos-client", "127.0.0.1:9358",
+		models.SocketConfig{Deadline: 2 * time.Second, Chaos: inj}, "client", true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	send := func(msgs ...string) {
+		t.Helper()
+		for _, m := range msgs {
+			if err := client.Send([]byte(m)); err != nil {
+				t.Fatalf("Send %q failed: %v", m, err)
+			}
+		}
+	}
+	expect := func(msgs ...string) {
+		t.Helper()
+		for _, m := range msgs {
+			if got, err := conn.ReadMessage(); err != nil || string(got) != m {
+				t.Fatalf("expected %q, got %q (%v)", m, got, err)
+			}
+		}
+	}
+
+	// 1. Loss, duplication, reordering
+	inj.Set(chaos.Config{Loss: 1})
+	send("lost")
+	inj.Set(chaos.Config{Duplicate: 1})
+	send("twice")
+	expect("twice", "twice")
+	inj.Set(chaos.Config{Reorder: 1})
+	send("first", "second")
+	expect("second", "first")
+
+	// 2. Corruption flips exactly one bit
+	inj.Set(chaos.Config{Corrupt: 1})
+	send("payload")
+	got, err := conn.ReadMessage()
+	if err != nil || len(got) != len("payload") {
+		t.Fatalf("expected a corrupted payload, got %q (%v)", got, err)
+	}
+	var flipped int
+	for i := range got {
+		flipped += bits.OnesCount8(got[i] ^ "payload"[i])
+	}
+	if flipped != 1 {
+		t.Errorf("expected one flipped bit, got %d", flipped)
+	}
+
+	// 3. Latency, then a disabled injector lets everything through
+	inj.Set(chaos.Config{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	send("slow")
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("expected at least 50ms of latency, got %v", d)
+	}
+	expect("slow")
+	inj.Set(chaos.Config{Loss: 1})
+	inj.Disable()
+	send("through")
+	expect("through")
+	inj.Enable()
+
+	// 4. An abrupt disconnect is seen as a failure, not as a graceful close
+	inj.Set(chaos.Config{Disconnect: 1})
+	if err := client.Send([]byte("never")); !errors.Is(err, chaos.ErrDisconnected) {
+		t.Fatalf("expected chaos.ErrDisconnected, got %v", err)
+	}
+	if msg, err := conn.ReadMessage(); err == nil || errors.Is(err, transports.ErrPeerClosed) {
+		t.Errorf("expected a broken connection, got %q (%v)", msg, err)
+	}
+
+	// The reordering was drawn for both messages, the second one going out at once
+	want := chaos.Stats{Delayed: 1, Dropped: 1, Duplicated: 1, Reordered: 2, Corrupted: 1, Disconnected: 1}
+	if s := inj.Stats(); s != want {
+		t.Errorf("expected stats %+v, got %+v", want, s)
+	}
+}
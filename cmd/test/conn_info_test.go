@@ -0,0 +1,80 @@
+package test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// TestConnInfo checks that every layer of the wrapper chain contributes to ConnInfo,
+// on an accepted connection and on the client end.
+func TestConnInfo(t *testing.T) {
+	config := models.SocketConfig{Deadline: 2 * time.Second}
+	server, err := factory.CreateWithConfig("tcp-hello:info-server", "127.0.0.1:9354", config, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	start := time.Now()
+	client, err := factory.CreateWithConfig("tcp-hello:info-client", "127.0.0.1:9354", config, "client", true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := client.Send([]byte("hello")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+
+	// 1. Server end
+	info := conn.ConnInfo()
+	if want := []string{"heartbeat", "handshake", "tracking", "framed-tcp"}; !slices.Equal(info.Layers, want) {
+		t.Errorf("layers: expected %v, got %v", want, info.Layers)
+	}
+	if info.Transport != interfaces.TransportFramedTCP || info.Protocol != interfaces.ProtocolHello || info.Profile != "info-server" {
+		t.Errorf("unexpected transport/protocol/profile: %s %s %q", info.Transport, info.Protocol, info.Profile)
+	}
+	if name, _ := info.Identity.FromName(); info.Identity == nil || name != "info-client" {
+		t.Errorf("expected the client identity, got %v", info.Identity)
+	}
+	if info.RemoteAddr == nil || info.LocalAddr.String() != "127.0.0.1:9354" || info.TLS != nil {
+		t.Errorf("unexpected addresses or TLS state: %v %v %v", info.LocalAddr, info.RemoteAddr, info.TLS)
+	}
+	if info.FrameVersion != transports.FrameVersionTyped || info.IdleTimeout != 2*time.Second || info.HeartbeatInterval <= 0 {
+		t.Errorf("unexpected frame version/timeouts: %d %v %v", info.FrameVersion, info.IdleTimeout, info.HeartbeatInterval)
+	}
+	if info.OpenedAt.Before(start) || info.LastActivity.Before(info.OpenedAt) {
+		t.Errorf("unexpected times: opened %v, last activity %v", info.OpenedAt, info.LastActivity)
+	}
+	if info.MessagesReceived != 1 || info.BytesReceived != 5 || info.MessagesSent != 0 {
+		t.Errorf("unexpected counters: %+v", info)
+	}
+
+	// 2. Client end
+	info = client.(*facade.SocketClient).ConnInfo()
+	if want := []string{"heartbeat", "handshake", "framed-tcp"}; !slices.Equal(info.Layers, want) {
+		t.Errorf("client layers: expected %v, got %v", want, info.Layers)
+	}
+	if name, _ := info.Identity.FromName(); info.Identity == nil || name != "info-server" {
+		t.Errorf("expected the server identity, got %v", info.Identity)
+	}
+	if info.Profile != "info-client" || info.MessagesSent != 1 || info.BytesSent != 5 {
+		t.Errorf("unexpected client profile/counters: %+v", info)
+	}
+}
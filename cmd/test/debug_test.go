@@ -0,0 +1,85 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/debug"
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// debugSockets fetches the JSON form of the debug handler, keeping the sockets of addr.
+func debugSockets(t *testing.T, addr string) map[string]facade.SocketState {
+	rec := httptest.NewRecorder()
+	debug.Handler().ServeHTTP(rec, httptest.NewRequest("GET", debug.Path+"?format=json", nil))
+
+	var all []facade.SocketState
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	found := map[string]facade.SocketState{}
+	for _, s := range all {
+		if s.Address == addr {
+			found[s.Role] = s
+		}
+	}
+	return found
+}
+
+// TestDebugHandler checks that sockets created through the factory are listed with
+// their connections, as JSON and HTML.
+func TestDebugHandler(t *testing.T) {
+	config := models.SocketConfig{Deadline: 2 * time.Second}
+	server, err := factory.CreateWithConfig("tcp-hello:debug-server", "127.0.0.1:9357", config, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	client, err := factory.CreateWithConfig("tcp-hello:debug-client", "127.0.0.1:9357", config, "client", true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := client.Send([]byte("hello")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+
+	// 1. JSON: both ends, with their connections
+	sockets := debugSockets(t, "127.0.0.1:9357")
+	s, c := sockets["server"], sockets["client"]
+	if s.State != "listening" || s.Profile != "debug-server" || len(s.Connections) != 1 {
+		t.Fatalf("unexpected server %+v", s)
+	}
+	if sc := s.Connections[0]; !strings.HasPrefix(sc.Identity, "debug-client@") || sc.MessagesReceived != 1 || sc.HeartbeatInterval <= 0 {
+		t.Errorf("unexpected accepted connection %+v", sc)
+	}
+	if c.State != "open" || len(c.Connections) != 1 || c.Connections[0].MessagesSent != 1 || c.Stats.FramesSent == 0 {
+		t.Errorf("unexpected client %+v", c)
+	}
+
+	// 2. HTML
+	rec := httptest.NewRecorder()
+	debug.Handler().ServeHTTP(rec, httptest.NewRequest("GET", debug.Path, nil))
+	if body := rec.Body.String(); !strings.Contains(body, "debug-server") || !strings.Contains(body, "debug-client@") {
+		t.Errorf("HTML page misses the sockets:\n%s", body)
+	}
+
+	// 3. A closed socket stays listed while the application holds it
+	_ = client.Close()
+	if c := debugSockets(t, "127.0.0.1:9357")["client"]; c.State != "closed" || len(c.Connections) != 0 {
+		t.Errorf("expected a closed client, got %+v", c)
+	}
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/profiles"
+	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
+)
+
+// TestEnvelopeAuth verifies that signed envelopes are accepted once, and that unsigned,
+// forged, replayed and stale envelopes are rejected.
+func TestEnvelopeAuth(t *testing.T) {
+	proto := protocols.NewHelloProtocol().(*protocols.HelloProtocol)
+	profile := profiles.NewUdpHelloProfile("sender", "127.0.0.1:0", 1)
+	keys := models.SocketConfig{AuthKeys: map[string][]byte{"k1": []byte("udp-secret")}}
+
+	seal := func(t *testing.T, config models.SocketConfig) []byte {
+		packet, err := proto.Encapsulate([]byte("payload"), profile, config)
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+		return packet
+	}
+
+	t.Run("ValidThenReplayed", func(t *testing.T) {
+		guard := protocols.NewReplayGuard(0)
+		packet := seal(t, keys)
+
+		payload, identity, err := proto.DecapsulateAuthenticated(packet, keys, guard)
+		if err != nil {
+			t.Fatalf("expected valid envelope, got %v", err)
+		}
+		if string(payload) != "payload" {
+			t.Errorf("expected 'payload', got %q", payload)
+		}
+		if name, _ := identity.FromName(); name != "sender" {
+			t.Errorf("expected sender 'sender', got %q", name)
+		}
+
+		if _, _, err := proto.DecapsulateAuthenticated(packet, keys, guard); !errors.Is(err, protocols.ErrEnvelopeReplayed) {
+			t.Errorf("expected ErrEnvelopeReplayed, got %v", err)
+		}
+
+		// Out-of-order but unseen counters are still accepted
+		later, earlier := seal(t, keys), seal(t, keys)
+		if _, _, err := proto.DecapsulateAuthenticated(earlier, keys, guard); err != nil {
+			t.Errorf("expected newer envelope to pass, got %v", err)
+		}
+		if _, _, err := proto.DecapsulateAuthenticated(later, keys, guard); err != nil {
+			t.Errorf("expected reordered envelope to pass, got %v", err)
+		}
+	})
+
+	t.Run("SharedSenderID", func(t *testing.T) {
+		// Two sockets named alike (e.g. a sender and its restarted instance) keep
+		// separate windows: the second one's counters lag far behind the first one's.
+		guard := protocols.NewReplayGuard(0)
+		other := protocols.NewHelloProtocol().(*protocols.HelloProtocol)
+		first, err := other.Encapsulate([]byte("payload"), profile, keys)
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+		for i := 0; i < 2*protocols.ReplayWindowSize; i++ {
+			if _, _, err := proto.DecapsulateAuthenticated(seal(t, keys), keys, guard); err != nil {
+				t.Fatalf("expected valid envelope, got %v", err)
+			}
+		}
+		if _, _, err := proto.DecapsulateAuthenticated(first, keys, guard); err != nil {
+			t.Errorf("expected the other sender's envelope to pass, got %v", err)
+		}
+		if _, _, err := proto.DecapsulateAuthenticated(first, keys, guard); !errors.Is(err, protocols.ErrEnvelopeReplayed) {
+			t.Errorf("expected ErrEnvelopeReplayed, got %v", err)
+		}
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		packet := seal(t, models.SocketConfig{})
+		if _, _, err := proto.DecapsulateAuthenticated(packet, keys, protocols.NewReplayGuard(0)); !errors.Is(err, protocols.ErrEnvelopeUnsigned) {
+			t.Errorf("expected ErrEnvelopeUnsigned, got %v", err)
+		}
+	})
+
+	t.Run("Forged", func(t *testing.T) {
+		forger := models.SocketConfig{AuthKeys: map[string][]byte{"k1": []byte("guessed")}}
+		packet := seal(t, forger)
+		if _, _, err := proto.DecapsulateAuthenticated(packet, keys, protocols.NewReplayGuard(0)); !errors.Is(err, protocols.ErrEnvelopeForged) {
+			t.Errorf("expected ErrEnvelopeForged, got %v", err)
+		}
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		packet := seal(t, keys)
+		time.Sleep(20 * time.Millisecond)
+		if _, _, err := proto.DecapsulateAuthenticated(packet, keys, protocols.NewReplayGuard(10*time.Millisecond)); !errors.Is(err, protocols.ErrEnvelopeStale) {
+			t.Errorf("expected ErrEnvelopeStale, got %v", err)
+		}
+	})
+}
+
+// TestUDP_HelloAuthenticated verifies that a keyed udp-hello server drops unsigned
+// packets and delivers signed ones.
+func TestUDP_HelloAuthenticated(t *testing.T) {
+	addr := "127.0.0.1:9320"
+	keys := map[string][]byte{"k1": []byte("udp-secret")}
+
+	server, err := factory.CreateWithConfig("udp-hello:auth-udp-server", addr, models.SocketConfig{AuthKeys: keys}, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	send := func(config models.SocketConfig, data string) {
+		client, err := factory.CreateWithConfig("udp-hello:auth-udp-client", addr, config, "client", true)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		defer func() { _ = client.Close() }()
+		if err := client.Send([]byte(data)); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	read := func() (string, error) {
+		conn, err := server.Accept()
+		if err != nil {
+			return "", err
+		}
+		// Per-packet connections share the listener socket: not closed here
+		msg, err := conn.ReadMessage()
+		return string(msg), err
+	}
+
+	// 1. Unsigned packet is dropped
+	send(models.SocketConfig{}, "unsigned")
+	if _, err := read(); !errors.Is(err, protocols.ErrEnvelopeUnsigned) {
+		t.Errorf("expected ErrEnvelopeUnsigned, got %v", err)
+	}
+
+	// 2. Signed packet is delivered
+	send(models.SocketConfig{AuthKeys: keys}, "signed")
+	msg, err := read()
+	if err != nil {
+		t.Fatalf("expected signed packet to be delivered, got %v", err)
+	}
+	if msg != "signed" {
+		t.Errorf("expected 'signed', got %q", msg)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// TestFrameVersionNegotiation verifies that the Hello handshake agrees on typed frames
+// by default and that LegacyFraming clients keep the length-only format.
+func TestFrameVersionNegotiation(t *testing.T) {
+	cases := []struct {
+		name     string
+		addr     string
+		legacy   bool
+		expected uint8
+	}{
+		{"Negotiated", "127.0.0.1:9300", false, transports.FrameVersionTyped},
+		{"Legacy", "127.0.0.1:9301", true, transports.FrameVersionLegacy},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := models.SocketConfig{Deadline: 2 * time.Second}
+			server, err := factory.CreateWithConfig("tcp-hello:negotiation-server", tc.addr, config, "server", true)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer func() { _ = server.Close() }()
+
+			config.LegacyFraming = tc.legacy
+			client, err := factory.CreateWithConfig("tcp-hello:negotiation-client", tc.addr, config, "client", true)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			defer func() { _ = client.Close() }()
+
+			conn, err := server.Accept()
+			if err != nil {
+				t.Fatalf("Accept failed: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			// 1. Both ends switched to the same frame version
+			if v := transports.ControlOf(conn).FrameVersion(); v != tc.expected {
+				t.Errorf("server frame version: expected %d, got %d", tc.expected, v)
+			}
+			clientHB := client.(*facade.SocketClient).Heartbeat()
+			if v := transports.ControlOf(clientHB).FrameVersion(); v != tc.expected {
+				t.Errorf("client frame version: expected %d, got %d", tc.expected, v)
+			}
+
+			// 2. Data still flows after the switch
+			if err := client.Send([]byte("after-hello")); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage failed: %v", err)
+			}
+			if string(msg) != "after-hello" {
+				t.Errorf("expected 'after-hello', got %q", msg)
+			}
+		})
+	}
+}
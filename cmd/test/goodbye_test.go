@@ -0,0 +1,58 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Bastien-Antigravity/safe-socket"
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+)
+
+// TestGoodbyeProfiles verifies that Hello profiles announce a graceful close, while
+// profiles without a handshake (legacy frames, no room for a goodbye) end with a plain EOF.
+func TestGoodbyeProfiles(t *testing.T) {
+	cases := []struct {
+		profile string
+		addr    string
+		goodbye bool
+	}{
+		{"tcp-hello", "127.0.0.1:9359", true},
+		{"tcp", "127.0.0.1:9360", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.profile, func(t *testing.T) {
+			server, err := factory.Create(tc.profile, tc.addr, "", "server", true)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer func() { _ = server.Close() }()
+
+			client, err := factory.Create(tc.profile, tc.addr, "", "client", true)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			conn, err := server.Accept()
+			if err != nil {
+				t.Fatalf("Accept failed: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			if err := client.(*facade.SocketClient).CloseWithReason(safesocket.CloseGoingAway, "rolling restart"); err != nil {
+				t.Fatalf("CloseWithReason failed: %v", err)
+			}
+			_, err = conn.ReadMessage()
+
+			var pc *safesocket.PeerClosedError
+			if tc.goodbye {
+				if !errors.As(err, &pc) || pc.Code != safesocket.CloseGoingAway || pc.Reason != "rolling restart" {
+					t.Errorf("expected the goodbye, got %v", err)
+				}
+			} else if !errors.Is(err, io.EOF) || errors.Is(err, safesocket.ErrPeerClosed) {
+				t.Errorf("expected a plain EOF without handshake, got %v", err)
+			}
+		})
+	}
+}
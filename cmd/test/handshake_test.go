@@ -0,0 +1,291 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket"
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/profiles"
+	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// TestHandshake_ServerIdentity verifies that the client learns the server identity from the Hello reply.
+func TestHandshake_ServerIdentity(t *testing.T) {
+	addr := "127.0.0.1:9310"
+
+	server, err := factory.Create("tcp-hello:identity-server", addr, "", "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	client, err := factory.Create("tcp-hello:identity-client", addr, "", "client", true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	identity := client.(*facade.SocketClient).GetIdentity()
+	if identity == nil {
+		t.Fatal("expected server identity on the client")
+	}
+	if name, _ := identity.FromName(); name != "identity-server" {
+		t.Errorf("expected server name 'identity-server', got %q", name)
+	}
+}
+
+// TestHandshake_Rejected verifies that a refusal surfaces as a typed error on Open, without retries.
+func TestHandshake_Rejected(t *testing.T) {
+	addr := "127.0.0.1:9311"
+
+	ln, err := transports.Listen(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	// Minimal server refusing every client
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		proto := protocols.NewHelloProtocol()
+		hello, err := proto.WaitInitiation(conn)
+		if err != nil {
+			return
+		}
+		profile := profiles.NewTcpHelloServerProfile("gatekeeper", addr, 1000)
+		_ = proto.Respond(conn, hello, profile, models.SocketConfig{}, errors.New("maintenance window"))
+	}()
+
+	config := models.SocketConfig{MaxRetries: 5, RetryInterval: time.Second}
+	client, err := factory.CreateWithConfig("tcp-hello:rejected-client", addr, config, "client", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err = client.Open()
+	if !errors.Is(err, safesocket.ErrHandshakeRejected) {
+		t.Fatalf("expected ErrHandshakeRejected, got %v", err)
+	}
+	var rejected *safesocket.HandshakeRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != "maintenance window" {
+		t.Errorf("expected reason 'maintenance window', got %v", err)
+	}
+	if name, _ := rejected.Server.FromName(); name != "gatekeeper" {
+		t.Errorf("expected refusing server 'gatekeeper', got %q", name)
+	}
+	if time.Since(start) >= config.RetryInterval {
+		t.Error("a rejected handshake must not be retried")
+	}
+}
+
+// TestHandshake_LegacyServer verifies that a client offering typed frames to a server
+// that never answers the Hello fails within the handshake timeout, even without idle timeout.
+func TestHandshake_LegacyServer(t *testing.T) {
+	addr := "127.0.0.1:9314"
+
+	ln, err := transports.Listen(addr, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	// Server predating the Hello reply: reads the Hello and says nothing
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = protocols.NewHelloProtocol().WaitInitiation(conn)
+		_, _ = conn.ReadMessage()
+	}()
+
+	profile := profiles.NewTcpHelloClientProfile("modern-client", addr, 1000)
+	client := facade.NewSocketClient(profile, models.SocketConfig{HandshakeTimeout: 200 * time.Millisecond, MaxRetries: 3})
+	start := time.Now()
+	err = client.Open()
+	if !errors.Is(err, safesocket.ErrNoHelloReply) {
+		t.Fatalf("expected ErrNoHelloReply, got %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Open took %v, expected the handshake timeout and no retries", d)
+	}
+}
+
+// TestHandshake_ServerTimeout verifies that a peer that never sends its Hello only holds
+// a handshake slot for the handshake timeout, then gets dropped.
+func TestHandshake_ServerTimeout(t *testing.T) {
+	addr := "127.0.0.1:9315"
+
+	config := models.SocketConfig{HandshakeTimeout: 100 * time.Millisecond, MaxPendingHandshakes: 1}
+	server, err := factory.CreateWithConfig("tcp-hello:slot-server", addr, config, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	// A silent peer takes the only slot
+	idle, err := transports.Connect(addr, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = idle.Close() }()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		client, err := factory.CreateWithConfig("tcp-hello:slot-client", addr, models.SocketConfig{HandshakeTimeout: time.Second}, "client", true)
+		if err == nil {
+			_ = client.Close()
+		}
+		clientErr <- err
+	}()
+
+	start := time.Now()
+	if _, err := server.Accept(); !errors.Is(err, facade.ErrHandshakeTimeout) {
+		t.Fatalf("expected ErrHandshakeTimeout for the silent peer, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("silent peer dropped after %v, expected the handshake timeout", d)
+	}
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("expected the client to be served once the slot was freed, got %v", err)
+	}
+	_ = conn.Close()
+	if err := <-clientErr; err != nil {
+		t.Errorf("client failed: %v", err)
+	}
+}
+
+// TestHandshake_AdmissionPolicy verifies that refused peers get the reason and never reach Accept.
+func TestHandshake_AdmissionPolicy(t *testing.T) {
+	addr := "127.0.0.1:9312"
+
+	server, err := factory.Create("tcp-hello:policy-server", addr, "", "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	policy, err := facade.NewAdmissionPolicy(facade.AdmissionRules{AllowNames: []string{"worker-*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.(*safesocket.Server).SetAdmissionPolicy(policy)
+
+	accepted := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := server.Accept()
+			if err != nil {
+				return
+			}
+			name, _ := safesocket.GetIdentity(conn).FromName()
+			accepted <- name
+		}
+	}()
+
+	// 1. Refused peer
+	_, err = factory.Create("tcp-hello:intruder", addr, "", "client", true)
+	if !errors.Is(err, safesocket.ErrHandshakeRejected) {
+		t.Fatalf("expected ErrHandshakeRejected, got %v", err)
+	}
+
+	// 2. Admitted peer
+	client, err := factory.Create("tcp-hello:worker-01", addr, "", "client", true)
+	if err != nil {
+		t.Fatalf("admitted client failed to open: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	select {
+	case name := <-accepted:
+		if name != "worker-01" {
+			t.Errorf("expected only 'worker-01' to be accepted, got %q", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("admitted client was not accepted")
+	}
+}
+
+// TestHandshake_ChallengeResponse verifies pre-shared key authentication with a rotating keyring.
+func TestHandshake_ChallengeResponse(t *testing.T) {
+	addr := "127.0.0.1:9313"
+	oldKey, newKey := []byte("secret-2025"), []byte("secret-2026")
+
+	serverConfig := models.SocketConfig{AuthKeys: map[string][]byte{"2025": oldKey, "2026": newKey}}
+	server, err := factory.CreateWithConfig("tcp-hello:auth-server", addr, serverConfig, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	// Failed handshakes surface as Accept errors: keep accepting until the test ends
+	stop := make(chan struct{})
+	defer close(stop)
+	accepted := make(chan *facade.HandshakeConnection, 4)
+	go func() {
+		for {
+			conn, err := server.Accept()
+			if err != nil {
+				select {
+				case <-stop:
+					return
+				default:
+					continue
+				}
+			}
+			hc, _ := conn.(*facade.HeartbeatConnection).Unwrap().(*facade.HandshakeConnection)
+			accepted <- hc
+		}
+	}()
+
+	// 1. Both keys of the ring are accepted during rotation
+	for _, keyID := range []string{"2025", "2026"} {
+		config := models.SocketConfig{AuthKeys: serverConfig.AuthKeys, AuthKeyID: keyID}
+		client, err := factory.CreateWithConfig("tcp-hello:auth-client", addr, config, "client", true)
+		if err != nil {
+			t.Fatalf("client with key %s failed to open: %v", keyID, err)
+		}
+		_ = client.Close()
+
+		select {
+		case hc := <-accepted:
+			if hc == nil || !hc.Authenticated {
+				t.Errorf("expected an authenticated identity for key %s", keyID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("client with key %s was not accepted", keyID)
+		}
+	}
+
+	// 2. Wrong secret is rejected
+	forged := models.SocketConfig{AuthKeys: map[string][]byte{"2026": []byte("guess")}}
+	_, err = factory.CreateWithConfig("tcp-hello:auth-client", addr, forged, "client", true)
+	if !errors.Is(err, safesocket.ErrHandshakeRejected) {
+		t.Errorf("expected ErrHandshakeRejected for a forged proof, got %v", err)
+	}
+
+	// 3. No key at all
+	_, err = factory.Create("tcp-hello:auth-client", addr, "", "client", true)
+	if !errors.Is(err, protocols.ErrAuthRequired) {
+		t.Errorf("expected ErrAuthRequired without a key, got %v", err)
+	}
+
+	select {
+	case <-accepted:
+		t.Error("an unauthenticated client reached Accept")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// TestStructuredLogging checks that log events reach SocketConfig.LogHandler with
+// their attributes, including the events of an auto-connecting Create.
+func TestStructuredLogging(t *testing.T) {
+	var buf bytes.Buffer
+	config := models.SocketConfig{
+		LogHandler:    slog.NewJSONHandler(&buf, nil),
+		MaxRetries:    1,
+		RetryInterval: 10 * time.Millisecond,
+	}
+	if _, err := factory.CreateWithConfig("tcp", "127.0.0.1:9352", config, "client", true); err == nil {
+		t.Fatal("expected Open to fail without a server")
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(bytes.Split(buf.Bytes(), []byte("\n"))[0], &event); err != nil {
+		t.Fatalf("expected one JSON event, got %q (%v)", buf.String(), err)
+	}
+	for key, want := range map[string]any{
+		"level":     "WARN",
+		"msg":       "socket open failed, retrying",
+		"address":   "127.0.0.1:9352",
+		"transport": "FramedTCP",
+		"role":      "client",
+		"attempt":   float64(1),
+	} {
+		if event[key] != want {
+			t.Errorf("%s: expected %v, got %v", key, want, event[key])
+		}
+	}
+	if event["error"] == nil {
+		t.Error("expected the error attribute")
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// TestMemProfiles runs the mem profiles through the factory, in parallel: the
+// heartbeats of an idle client keep the server's read alive past its idle timeout.
+func TestMemProfiles(t *testing.T) {
+	for _, profile := range []string{"mem", "mem-hello"} {
+		t.Run(profile, func(t *testing.T) {
+			t.Parallel()
+			config := models.SocketConfig{Deadline: 200 * time.Millisecond}
+			server, err := factory.CreateWithConfig(profile+":mem-server", "test-"+profile, config, "server", true)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer func() { _ = server.Close() }()
+
+			client, err := factory.CreateWithConfig(profile+":mem-client", "test-"+profile, config, "client", true)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			defer func() { _ = client.Close() }()
+			conn, err := server.Accept()
+			if err != nil {
+				t.Fatalf("Accept failed: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			if info := conn.ConnInfo(); info.Transport != interfaces.TransportMem {
+				t.Errorf("expected the mem transport, got %+v", info)
+			}
+			if profile == "mem-hello" {
+				if name, _ := facade.IdentityOf(conn).FromName(); name != "mem-client" {
+					t.Errorf("expected the client identity, got %q", name)
+				}
+			}
+
+			go func() {
+				time.Sleep(500 * time.Millisecond)
+				_ = client.Send([]byte("late"))
+			}()
+			if msg, err := conn.ReadMessage(); err != nil || string(msg) != "late" {
+				t.Fatalf("expected 'late', got %q (%v)", msg, err)
+			}
+			if _, err := conn.Write([]byte("reply")); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if msg, err := client.Receive(); err != nil || string(msg) != "reply" {
+				t.Fatalf("expected 'reply', got %q (%v)", msg, err)
+			}
+		})
+	}
+
+	if _, err := factory.CreateWithConfig("mem", "test-nobody", models.SocketConfig{}, "client", true); err == nil {
+		t.Error("expected no listener to be found")
+	}
+}
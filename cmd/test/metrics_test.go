@@ -0,0 +1,131 @@
+package test
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// TestMetrics checks the activity recorded for sockets and its Prometheus exposition.
+func TestMetrics(t *testing.T) {
+	t.Run("Traffic", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		config := models.SocketConfig{Metrics: registry}
+		addr := "127.0.0.1:9350"
+
+		server, err := factory.CreateWithConfig("tcp-hello", addr, config, "server", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = server.Close() }()
+		client, err := factory.CreateWithConfig("tcp-hello:alice", addr, config, "client", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A second client of the same kind keeps its own counters
+		bob, err := factory.CreateWithConfig("tcp-hello:bob", addr, config, "client", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := server.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := server.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = other.Close() }()
+		for i := 0; i < 3; i++ {
+			if err := client.Send([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conn.ReadMessage(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cs := client.(*facade.SocketClient).Stats()
+		ss := server.(*facade.SocketServer).Stats()
+		if cs.FramesSent != 3 || cs.BytesSent != 15 || cs.ConnectionsOpen != 1 {
+			t.Errorf("unexpected client stats: %+v", cs)
+		}
+		if bs := bob.(*facade.SocketClient).Stats(); bs.BytesSent != 0 || bs.ConnectionsOpen != 1 {
+			t.Errorf("expected the other client's stats to be its own, got %+v", bs)
+		}
+		if ss.FramesReceived != 3 || ss.BytesReceived != 15 || ss.ConnectionsOpened != 2 {
+			t.Errorf("unexpected server stats: %+v", ss)
+		}
+
+		scrape := func() string {
+			rec := httptest.NewRecorder()
+			registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+			return rec.Body.String()
+		}
+		want := `safesocket_frames_sent_total{profile="alice",transport="FramedTCP",protocol="hello",role="client",address="127.0.0.1:9350"} 3`
+		if body := scrape(); !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+
+		_ = client.Close()
+		_ = bob.Close()
+		_ = conn.Close()
+		if open := client.(*facade.SocketClient).Stats().ConnectionsOpen; open != 0 {
+			t.Errorf("expected no open client connection, got %d", open)
+		}
+		if body := scrape(); strings.Contains(body, `role="client"`) {
+			t.Errorf("expected closed clients to leave the registry:\n%s", body)
+		}
+	})
+
+	t.Run("OpenRetries", func(t *testing.T) {
+		config := models.SocketConfig{
+			Metrics:       metrics.NewRegistry(),
+			MaxRetries:    2,
+			RetryInterval: 10 * time.Millisecond,
+		}
+		client, err := factory.CreateWithConfig("tcp", "127.0.0.1:9351", config, "client", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Open(); err == nil {
+			t.Fatal("expected Open to fail without a server")
+		}
+		if retries := client.(*facade.SocketClient).Stats().OpenRetries; retries != 2 {
+			t.Errorf("expected 2 retries, got %d", retries)
+		}
+	})
+
+	t.Run("ShmRing", func(t *testing.T) {
+		path := "test_metrics_shm"
+		defer func() { _ = os.Remove(path) }()
+		config := models.SocketConfig{Metrics: metrics.NewRegistry()}
+
+		server, err := factory.CreateWithConfig("shm", path, config, "server", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = server.Close() }()
+		client, err := factory.CreateWithConfig("shm", path, config, "client", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = client.Close() }()
+
+		// Unread payloads stay in the ring
+		if err := client.Send(make([]byte, 1000)); err != nil {
+			t.Fatal(err)
+		}
+		if ring := client.(*facade.SocketClient).Stats().ShmRingOutBytes; ring < 1000 {
+			t.Errorf("expected at least 1000 bytes in the ring, got %d", ring)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
+)
+
+// TestSecureProfiles verifies that the *-secure profiles authenticate both static keys
+// and carry encrypted data both ways.
+func TestSecureProfiles(t *testing.T) {
+	serverKey, serverPub, _ := safesocket.GenerateSecureKey()
+	clientKey, clientPub, _ := safesocket.GenerateSecureKey()
+	shmPath := filepath.Join(t.TempDir(), "secure.shm")
+
+	cases := []struct {
+		profile string
+		addr    string
+	}{
+		{"tcp-secure", "127.0.0.1:9340"},
+		{"udp-secure", "127.0.0.1:9341"},
+		{"shm-secure", shmPath},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.profile, func(t *testing.T) {
+			serverConfig := models.SocketConfig{SecureStaticKey: serverKey, SecurePeerKeys: [][]byte{clientPub}}
+			server, err := factory.CreateWithConfig(tc.profile+":secure-server", tc.addr, serverConfig, "server", true)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer func() { _ = server.Close() }()
+
+			clientConfig := models.SocketConfig{SecureStaticKey: clientKey, SecurePeerKeys: [][]byte{serverPub}}
+			client, err := factory.CreateWithConfig(tc.profile+":secure-client", tc.addr, clientConfig, "client", true)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			defer func() { _ = client.Close() }()
+
+			secret := []byte("top-secret-payload")
+			if err := client.Send(secret); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+
+			conn, err := server.Accept()
+			if err != nil {
+				t.Fatalf("Accept failed: %v", err)
+			}
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage failed: %v", err)
+			}
+			if !bytes.Equal(msg, secret) {
+				t.Errorf("expected %q, got %q", secret, msg)
+			}
+			if !bytes.Equal(safesocket.GetPeerKey(conn), clientPub) {
+				t.Error("server did not authenticate the client static key")
+			}
+
+			// Nothing readable in the shared memory file
+			if tc.profile == "shm-secure" {
+				if raw, err := os.ReadFile(shmPath); err == nil && bytes.Contains(raw, secret) {
+					t.Error("plaintext found in the shared memory file")
+				}
+			}
+
+			if _, err := conn.Write([]byte("ack")); err != nil {
+				t.Fatalf("server Write failed: %v", err)
+			}
+			reply, err := client.Receive()
+			if err != nil {
+				t.Fatalf("Receive failed: %v", err)
+			}
+			if string(reply) != "ack" {
+				t.Errorf("expected 'ack', got %q", reply)
+			}
+		})
+	}
+}
+
+// TestSecureUntrustedServer verifies that a client refuses a server whose key it does not trust.
+func TestSecureUntrustedServer(t *testing.T) {
+	addr := "127.0.0.1:9342"
+	serverKey, _, _ := safesocket.GenerateSecureKey()
+	clientKey, _, _ := safesocket.GenerateSecureKey()
+	_, otherPub, _ := safesocket.GenerateSecureKey()
+
+	server, err := factory.CreateWithConfig("tcp-secure:impostor", addr, models.SocketConfig{SecureStaticKey: serverKey}, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	clientConfig := models.SocketConfig{SecureStaticKey: clientKey, SecurePeerKeys: [][]byte{otherPub}, Deadline: time.Second}
+	_, err = factory.CreateWithConfig("tcp-secure:client", addr, clientConfig, "client", true)
+	if !errors.Is(err, protocols.ErrPeerKeyUntrusted) {
+		t.Errorf("expected ErrPeerKeyUntrusted, got %v", err)
+	}
+}
+
+// TestSecureDatagramSessions verifies that udp-secure replay windows are per sending
+// channel: the datagrams of two clients sharing a static key (e.g. one and its restarted
+// instance) do not collide, while each datagram still cannot be replayed.
+func TestSecureDatagramSessions(t *testing.T) {
+	serverKey, serverPub, _ := safesocket.GenerateSecureKey()
+	clientKey, clientPub, _ := safesocket.GenerateSecureKey()
+
+	server, err := protocols.NewDatagramChannel(models.SocketConfig{SecureStaticKey: serverKey, SecurePeerKeys: [][]byte{clientPub}}, false, protocols.NewReplayGuard(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig := models.SocketConfig{SecureStaticKey: clientKey, SecurePeerKeys: [][]byte{serverPub}}
+	other, err := protocols.NewDatagramChannel(clientConfig, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := other.Seal([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := protocols.NewDatagramChannel(clientConfig, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2*protocols.ReplayWindowSize; i++ {
+		datagram, err := client.Seal([]byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := server.Open(datagram); err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+	}
+
+	if msg, err := server.Open(first); err != nil || string(msg) != "hello" {
+		t.Errorf("expected the other session's datagram, got %q, %v", msg, err)
+	}
+	if _, err := server.Open(first); !errors.Is(err, protocols.ErrEnvelopeReplayed) {
+		t.Errorf("expected ErrEnvelopeReplayed, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+	"github.com/Bastien-Antigravity/safe-socket/src/factory"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
+)
+
+// recordedSpan is one span of the recordingTracer.
+type recordedSpan struct {
+	tracer *recordingTracer
+	name   string
+	trace  string
+	id     string
+	parent string
+	attrs  map[string]any
+	err    error
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...tracing.Attr) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.err = err
+	s.tracer.ended = append(s.tracer.ended, s)
+}
+
+type spanKey struct{}
+
+// recordingTracer records ended spans and propagates "trace/span" contexts.
+type recordingTracer struct {
+	mu    sync.Mutex
+	next  int
+	ended []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, attrs ...tracing.Attr) (context.Context, tracing.Span) {
+	r.mu.Lock()
+	r.next++
+	span := &recordedSpan{tracer: r, name: name, id: fmt.Sprintf("s%d", r.next), attrs: map[string]any{}}
+	r.mu.Unlock()
+
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.trace, span.parent = parent.trace, parent.id
+	} else {
+		span.trace = "t" + span.id
+	}
+	span.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (r *recordingTracer) Inject(ctx context.Context) []byte {
+	if span, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		return []byte(span.trace + "/" + span.id)
+	}
+	return nil
+}
+
+func (r *recordingTracer) Extract(ctx context.Context, tc []byte) context.Context {
+	trace, id, _ := strings.Cut(string(tc), "/")
+	return context.WithValue(ctx, spanKey{}, &recordedSpan{trace: trace, id: id})
+}
+
+// find returns the ended spans called name.
+func (r *recordingTracer) find(name string) []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var spans []*recordedSpan
+	for _, s := range r.ended {
+		if s.name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// -----------------------------------------------------------------------------
+
+// TestTracing checks the spans around connect, accept and the handshake, and that a
+// trace started by the client continues on the server and back through frame headers.
+func TestTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	config := models.SocketConfig{Deadline: 2 * time.Second, Tracer: tracer}
+
+	server, err := factory.CreateWithConfig("tcp-hello:tracing-server", "127.0.0.1:9353", config, "server", true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	client, err := factory.CreateWithConfig("tcp-hello:tracing-client", "127.0.0.1:9353", config, "client", true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// 1. Connection spans: each handshake is a child of its connect/accept span
+	connect, accept := tracer.find(tracing.SpanConnect), tracer.find(tracing.SpanAccept)
+	if len(connect) != 1 || len(accept) != 1 {
+		t.Fatalf("expected one connect and one accept span, got %d and %d", len(connect), len(accept))
+	}
+	handshakes := tracer.find(tracing.SpanHandshake)
+	if len(handshakes) != 2 {
+		t.Fatalf("expected two handshake spans, got %d", len(handshakes))
+	}
+	for _, h := range handshakes {
+		want := map[any]*recordedSpan{"client": connect[0], "server": accept[0]}[h.attrs["role"]]
+		if want == nil || h.parent != want.id || h.err != nil {
+			t.Errorf("handshake span %+v: expected a successful child of %s", h.attrs, want.name)
+		}
+	}
+	if accept[0].attrs["remote"] == nil || connect[0].attrs["address"] != "127.0.0.1:9353" {
+		t.Errorf("missing connection attributes: %v / %v", accept[0].attrs, connect[0].attrs)
+	}
+
+	// 2. Client request: the server's receive span continues the client's trace
+	ctx, request := tracer.Start(context.Background(), "request")
+	if err := client.(*facade.SocketClient).SendContext(ctx, []byte("ping")); err != nil {
+		t.Fatalf("SendContext failed: %v", err)
+	}
+	serverCtx, msg, err := facade.ReadMessageContext(context.Background(), conn)
+	if err != nil || string(msg) != "ping" {
+		t.Fatalf("expected 'ping', got %q (%v)", msg, err)
+	}
+	send, receive := tracer.find(tracing.SpanSend), tracer.find(tracing.SpanReceive)
+	if len(send) != 1 || len(receive) != 1 {
+		t.Fatalf("expected one send and one receive span, got %d and %d", len(send), len(receive))
+	}
+	if trace := request.(*recordedSpan).trace; receive[0].trace != trace || receive[0].parent != send[0].id {
+		t.Errorf("receive span not continuing the client trace: %+v (send %s)", receive[0], send[0].id)
+	}
+
+	// 3. Server reply within the continued trace reaches the client the same way
+	if _, err := facade.WriteContext(serverCtx, conn, []byte("pong")); err != nil {
+		t.Fatalf("WriteContext failed: %v", err)
+	}
+	_, msg, err = client.(*facade.SocketClient).ReceiveContext(context.Background())
+	if err != nil || string(msg) != "pong" {
+		t.Fatalf("expected 'pong', got %q (%v)", msg, err)
+	}
+	receive = tracer.find(tracing.SpanReceive)
+	if len(receive) != 2 || receive[1].trace != request.(*recordedSpan).trace {
+		t.Errorf("client receive span not continuing the trace: %+v", receive)
+	}
+	if receive[1].attrs["propagated"] != true || receive[1].attrs["bytes"] != 4 {
+		t.Errorf("unexpected receive attributes: %v", receive[1].attrs)
+	}
+}
@@ -5,10 +5,10 @@ go 1.25.4
 require (
 	capnproto.org/go/capnp/v3 v3.1.0-alpha.2
 	github.com/edsrzf/mmap-go v1.2.0
+	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e
 )
 
 require (
 	github.com/colega/zeropool v0.0.0-20230505084239-6fb4a4f75381 // indirect
 	golang.org/x/sync v0.7.0 // indirect
-	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e // indirect
 )
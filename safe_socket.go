@@ -1,11 +1,23 @@
 package safesocket
 
 import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/capture"
+	"github.com/Bastien-Antigravity/safe-socket/src/chaos"
+	"github.com/Bastien-Antigravity/safe-socket/src/debug"
 	"github.com/Bastien-Antigravity/safe-socket/src/facade"
 	"github.com/Bastien-Antigravity/safe-socket/src/factory"
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/logging"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
 	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
 	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
 )
 
 // Socket is an alias for the Facade to simplify usage.
@@ -14,6 +26,14 @@ type Socket = interfaces.Socket
 // Identity is an alias for the HelloMsg schema to simplify usage.
 type Identity = schemas.HelloMsg
 
+// Server is the concrete server facade returned by Create(..., "server", ...).
+// Type-assert a Socket to it to reach the connection registry (Connections, Broadcast...).
+type Server = facade.SocketServer
+
+// Client is the concrete client facade returned by Create(..., "client", ...).
+// Type-assert a Socket to it to reach the context-aware methods (OpenContext, SendContext...).
+type Client = facade.SocketClient
+
 // SocketType aliases removed to simplify API. Use "client" or "server" strings.
 
 // -----------------------------------------------------------------------------
@@ -21,8 +41,10 @@ type Identity = schemas.HelloMsg
 // Create creates a new safe-socket connection using a named profile.
 //
 // Parameters:
-//   - profileName: "tcp", "tcp-hello", "tls", "tls-hello", "udp", "udp-hello", "shm", "shm-hello"
-//   - address: destination address ("IP:Port" or "FilePath" for SHM)
+//   - profileName: "tcp", "tcp-hello", "tcp-secure", "tls", "tls-hello", "udp", "udp-hello",
+//     "udp-secure", "shm", "shm-hello", "shm-secure", "shm-broadcast", "mem", "mem-hello";
+//     add "+chaos" (e.g. "tcp-hello+chaos") to inject faults (see the chaos package)
+//   - address: destination address ("IP:Port", "FilePath" for SHM, any name for mem)
 //   - publicIP: your public IP (Optional, resolved from environment/system if empty)
 //   - socketType: "client" or "server"
 //   - autoConnect: if true, immediately calls Open() / Listen()
@@ -45,23 +67,199 @@ func GetIdentity(conn interfaces.TransportConnection) *Identity {
 	if conn == nil {
 		return nil
 	}
+	return facade.IdentityOf(conn)
+}
+
+// CertIdentity is the verified certificate chain (and SPIFFE ID) of a TLS peer.
+type CertIdentity = facade.CertIdentity
 
-	// 1. Try HandshakeConnection (TCP/SHM Handshake)
-	if hc, ok := conn.(*facade.HandshakeConnection); ok {
-		return hc.Identity
+// GetCertIdentity extracts the verified peer certificate identity from a potentially
+// wrapped TLS connection. It returns nil on other transports and unverified peers.
+func GetCertIdentity(conn interfaces.TransportConnection) *CertIdentity {
+	if conn == nil {
+		return nil
 	}
+	return facade.CertIdentityOf(conn)
+}
 
-	// 2. Try EnvelopedConnection (UDP Stateless Identity)
-	if ec, ok := conn.(*facade.EnvelopedConnection); ok {
-		return ec.LastIdentity
+// GetPeerKey returns the authenticated static public key of the peer of a *-secure
+// connection (wrapped or not), or nil on other profiles.
+func GetPeerKey(conn interfaces.TransportConnection) []byte {
+	if conn == nil {
+		return nil
 	}
+	return facade.PeerKeyOf(conn)
+}
+
+// GenerateSecureKey returns a new static key pair for SocketConfig.SecureStaticKey
+// (private) and the peers' SecurePeerKeys (public).
+func GenerateSecureKey() (private, public []byte, err error) {
+	return protocols.GenerateSecureKey()
+}
+
+// -----------------------------------------------------------------------------
+
+// CloseWithReason closes a connection returned by Accept (or any wrapped connection),
+// announcing a close code and reason to the peer.
+func CloseWithReason(conn interfaces.TransportConnection, code CloseCode, reason string) error {
+	return facade.CloseWithReason(conn, code, reason)
+}
+
+// ErrPeerClosed matches (errors.Is) the error returned by Receive/Read once the peer
+// closed the connection gracefully. Use errors.As with *PeerClosedError for the code.
+var ErrPeerClosed = transports.ErrPeerClosed
+
+// PeerClosedError carries the close code and reason announced by the peer.
+type PeerClosedError = transports.PeerClosedError
 
-	// 3. Try peeling Heartbeat wrapper
-	if hb, ok := conn.(*facade.HeartbeatConnection); ok {
-		return GetIdentity(hb.TransportConnection)
+// ErrHandshakeRejected matches (errors.Is) the error returned by Open when the server
+// refused the client. Use errors.As with *HandshakeRejectedError for the reason.
+var ErrHandshakeRejected = protocols.ErrHandshakeRejected
+
+// HandshakeRejectedError carries the reason and identity of a refusing server.
+type HandshakeRejectedError = protocols.HandshakeRejectedError
+
+// HandshakeError reports a handshake that failed before the server's verdict.
+type HandshakeError = protocols.HandshakeError
+
+// ErrNoHelloReply matches (errors.Is) the error returned by Open when the server did not
+// answer the Hello within the handshake timeout (servers predating frame negotiation
+// need LegacyFraming).
+var ErrNoHelloReply = protocols.ErrNoHelloReply
+
+// ErrCertIdentityMismatch is the rejection reason of clients whose Hello name is not
+// vouched for by their certificate (SocketConfig.BindCertIdentity).
+var ErrCertIdentityMismatch = facade.ErrCertIdentityMismatch
+
+// Errors returned by reads on a keyed udp-hello receiver (authenticated envelopes).
+var (
+	ErrEnvelopeUnsigned = protocols.ErrEnvelopeUnsigned
+	ErrEnvelopeForged   = protocols.ErrEnvelopeForged
+	ErrEnvelopeStale    = protocols.ErrEnvelopeStale
+	ErrEnvelopeReplayed = protocols.ErrEnvelopeReplayed
+)
+
+// Errors returned when opening an SHM segment that is not safe to map.
+var (
+	ErrShmSymlink      = transports.ErrShmSymlink
+	ErrShmForeignOwner = transports.ErrShmForeignOwner
+)
+
+// shm-broadcast: ErrShmOverrun matches (errors.Is) the *ShmOverrunError of a lapped
+// subscriber, who continues with BroadcastSocket.Resync (or Config.BroadcastAutoResync).
+var (
+	ErrShmOverrun        = transports.ErrShmOverrun
+	ErrBroadcastNoAccept = facade.ErrBroadcastNoAccept
+)
+
+type (
+	ShmOverrunError = transports.ShmOverrunError
+	BroadcastSocket = facade.BroadcastSocket
+)
+
+// ZeroCopyConnection is the reserve/commit and peek/release API of SHM connections.
+type ZeroCopyConnection = transports.ZeroCopyConnection
+
+// ZeroCopyOf returns the zero-copy API of a (wrapped) shm connection, or nil on other
+// profiles, *-secure ones included.
+func ZeroCopyOf(conn interfaces.TransportConnection) ZeroCopyConnection {
+	return facade.ZeroCopyOf(conn)
+}
+
+// Errors returned by misuse of the zero-copy SHM API.
+var (
+	ErrShmNoReservation = transports.ErrShmNoReservation
+	ErrShmPeekPending   = transports.ErrShmPeekPending
+	ErrShmNoPeek        = transports.ErrShmNoPeek
+)
+
+// -----------------------------------------------------------------------------
+
+// Stats is the activity snapshot returned by GetStats and metrics registries.
+type Stats = metrics.Stats
+
+// MetricsRegistry groups sockets for exposition (SocketConfig.Metrics).
+type MetricsRegistry = metrics.Registry
+
+// NewMetricsRegistry creates a registry separate from the default one.
+func NewMetricsRegistry() *MetricsRegistry {
+	return metrics.NewRegistry()
+}
+
+// MetricsHandler serves the default registry in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return metrics.Handler()
+}
+
+// DebugHandler serves the live sockets of the process (clients, servers and their
+// connections) as HTML, or JSON with ?format=json. Mount it at DebugPath, off public
+// listeners.
+func DebugHandler() http.Handler {
+	return debug.Handler()
+}
+
+// DebugPath is the conventional mount point of DebugHandler.
+const DebugPath = debug.Path
+
+// GetStats returns the activity recorded for a socket created by this package.
+func GetStats(s Socket) Stats {
+	if st, ok := s.(interface{ Stats() metrics.Stats }); ok {
+		return st.Stats()
 	}
+	return Stats{}
+}
+
+// NewLogHandler adapts a Logger to slog, for SocketConfig.LogHandler or any other
+// slog-based code: events at Info level and above become "message key=value ..."
+// lines. Use logging.NewLoggerHandler(logger).WithLevel(slog.LevelDebug) for Debug.
+func NewLogHandler(logger interfaces.Logger) slog.Handler {
+	return logging.NewLoggerHandler(logger)
+}
+
+// -----------------------------------------------------------------------------
+
+// Tracing types (SocketConfig.Tracer).
+type (
+	Tracer          = tracing.Tracer
+	Span            = tracing.Span
+	SpanAttr        = tracing.Attr
+	TracePropagator = tracing.Propagator
+)
+
+// WriteContext writes p to an accepted connection within a send span, carrying the
+// trace context of ctx when the peer supports it.
+func WriteContext(ctx context.Context, conn interfaces.TransportConnection, p []byte) (int, error) {
+	return facade.WriteContext(ctx, conn, p)
+}
+
+// ReadMessageContext reads one message from an accepted connection within a receive
+// span, and returns the context holding it (continuing the sender's trace if any).
+func ReadMessageContext(ctx context.Context, conn interfaces.TransportConnection) (context.Context, []byte, error) {
+	return facade.ReadMessageContext(ctx, conn)
+}
+
+// -----------------------------------------------------------------------------
+
+// CaptureWriter records the frames of sockets into a capture file (SocketConfig.Capture).
+type CaptureWriter = capture.Writer
 
-	return nil
+// CreateCapture creates (or truncates) a capture file. Replay it with cmd/replay.
+func CreateCapture(path string) (*CaptureWriter, error) {
+	return capture.Create(path)
+}
+
+// -----------------------------------------------------------------------------
+
+// ChaosConfig describes the faults injected for chaos testing (SocketConfig.Chaos).
+type ChaosConfig = chaos.Config
+
+// ChaosInjector draws the faults injected into connections; it can be reconfigured
+// and toggled at runtime.
+type ChaosInjector = chaos.Injector
+
+// NewChaosInjector returns an enabled injector whose RNG is seeded with seed.
+func NewChaosInjector(c ChaosConfig, seed uint64) *ChaosInjector {
+	return chaos.New(c, seed)
 }
 
 // -----------------------------------------------------------------------------
@@ -72,6 +270,8 @@ type (
 	SocketProfile = interfaces.SocketProfile
 	TransportType = interfaces.TransportType
 	ProtocolType  = interfaces.ProtocolType
+	CloseCode     = interfaces.CloseCode
+	ConnInfo      = interfaces.ConnInfo
 )
 
 // -----------------------------------------------------------------------------
@@ -85,3 +285,11 @@ const (
 	TransportUDP       = interfaces.TransportUDP
 	TransportSHM       = interfaces.TransportShm
 )
+
+const (
+	CloseNormal          = interfaces.CloseNormal
+	CloseGoingAway       = interfaces.CloseGoingAway
+	CloseProtocolError   = interfaces.CloseProtocolError
+	ClosePolicyViolation = interfaces.ClosePolicyViolation
+	CloseIdleTimeout     = interfaces.CloseIdleTimeout
+)
@@ -0,0 +1,275 @@
+// Package capture records the frames of connections to a file and reads them back.
+//
+// File Format (all integers BigEndian):
+//
+// [0-7]   : Magic "SSCAP\x00\x00\x01" (format version 1)
+// Records, each:
+// [0-3]   : Length of the record, these 4 bytes excluded
+// [4-11]  : Timestamp, Unix nanoseconds
+// [12-15] : Session id, unique within the file (one per captured connection)
+// [16]    : Kind (KindOutbound, KindInbound, KindOpen, KindClose)
+// [17]    : Frame header version (frames only)
+// [18]    : Frame type, as in interfaces.FrameType (frames only)
+// [19]    : Frame flags (frames only)
+// [20-.]  : Body - frame body as on the wire (trace context included), Session JSON for KindOpen
+//
+// Every session starts with a KindOpen record and normally ends with a KindClose one.
+// Records of concurrent sessions interleave in the order they were observed.
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Magic starts every capture file.
+const Magic = "SSCAP\x00\x00\x01"
+
+const (
+	recordHeaderSize = 20
+
+	// MaxRecordSize bounds a record read back, against corrupted files.
+	MaxRecordSize = 64*1024*1024 + 1024
+)
+
+// ErrBadCapture is returned when a file is not a capture or a record is malformed.
+var ErrBadCapture = errors.New("malformed capture file")
+
+// Kind tells what a record holds.
+type Kind uint8
+
+const (
+	KindOutbound Kind = 0 // Frame written by the captured end
+	KindInbound  Kind = 1 // Frame read by the captured end
+	KindOpen     Kind = 2 // Start of a session, body = Session
+	KindClose    Kind = 3 // End of a session
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindOutbound:
+		return "out"
+	case KindInbound:
+		return "in"
+	case KindOpen:
+		return "open"
+	case KindClose:
+		return "close"
+	}
+	return fmt.Sprintf("kind(%d)", uint8(k))
+}
+
+// Session describes the captured end of a connection.
+type Session struct {
+	Role       string `json:"role"` // "client" or "server"
+	Transport  string `json:"transport"`
+	Protocol   string `json:"protocol,omitempty"`
+	Address    string `json:"address,omitempty"` // Profile address
+	LocalAddr  string `json:"local,omitempty"`
+	RemoteAddr string `json:"remote,omitempty"`
+}
+
+// Record is one entry of a capture file.
+type Record struct {
+	Time    time.Time
+	Session uint32
+	Kind    Kind
+	Version uint8
+	Type    uint8
+	Flags   uint8
+	Body    []byte
+}
+
+// -----------------------------------------------------------------------------
+
+// Writer appends records to a capture file. It is safe for concurrent use, so one
+// Writer can capture every connection of a server.
+type Writer struct {
+	mu       sync.Mutex
+	w        *bufio.Writer
+	closer   io.Closer
+	err      error
+	sessions atomic.Uint32
+}
+
+// NewWriter writes the file magic to w and returns a Writer appending to it.
+func NewWriter(w io.Writer) (*Writer, error) {
+	cw := &Writer{w: bufio.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		cw.closer = c
+	}
+	if _, err := cw.w.WriteString(Magic); err != nil {
+		return nil, err
+	}
+	return cw, cw.w.Flush()
+}
+
+// Create creates (or truncates) the file at path and returns a Writer appending to it.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Open starts a new session and returns its id.
+func (w *Writer) Open(s Session) (uint32, error) {
+	id := w.sessions.Add(1)
+	body, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	return id, w.Write(Record{Time: time.Now(), Session: id, Kind: KindOpen, Body: body})
+}
+
+// Write appends r and flushes it, so that a crash loses nothing already captured.
+// After a first error every write fails with it.
+func (w *Writer) Write(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(header[0:], uint32(recordHeaderSize-4+len(r.Body)))
+	binary.BigEndian.PutUint64(header[4:], uint64(r.Time.UnixNano()))
+	binary.BigEndian.PutUint32(header[12:], r.Session)
+	header[16] = byte(r.Kind)
+	header[17] = r.Version
+	header[18] = r.Type
+	header[19] = r.Flags
+
+	if _, err := w.w.Write(header); err != nil {
+		w.err = err
+		return err
+	}
+	if _, err := w.w.Write(r.Body); err != nil {
+		w.err = err
+		return err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// Err returns the error that stopped the capture, if any.
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close flushes the file and closes the underlying writer if it is an io.Closer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.w.Flush()
+	if w.err == nil {
+		w.err = os.ErrClosed
+	}
+	if w.closer != nil {
+		err = errors.Join(err, w.closer.Close())
+		w.closer = nil
+	}
+	return err
+}
+
+// -----------------------------------------------------------------------------
+
+// Reader reads the records of a capture file back.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the file magic and returns a Reader positioned on the first record.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != Magic {
+		return nil, ErrBadCapture
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the file. A record cut short
+// (capture interrupted by a crash) is reported as io.ErrUnexpectedEOF.
+func (r *Reader) Next() (Record, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return Record{}, err
+	}
+	length := binary.BigEndian.Uint32(header[0:])
+	if length < recordHeaderSize-4 || length > MaxRecordSize {
+		return Record{}, ErrBadCapture
+	}
+	body := make([]byte, length-(recordHeaderSize-4))
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, err
+	}
+	return Record{
+		Time:    time.Unix(0, int64(binary.BigEndian.Uint64(header[4:]))),
+		Session: binary.BigEndian.Uint32(header[12:]),
+		Kind:    Kind(header[16]),
+		Version: header[17],
+		Type:    header[18],
+		Flags:   header[19],
+		Body:    body,
+	}, nil
+}
+
+// ReadAll loads a whole capture file.
+func ReadAll(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// SessionOf decodes the Session carried by a KindOpen record.
+func SessionOf(r Record) (Session, error) {
+	var s Session
+	if r.Kind != KindOpen {
+		return s, ErrBadCapture
+	}
+	if err := json.Unmarshal(r.Body, &s); err != nil {
+		return s, fmt.Errorf("%w: %v", ErrBadCapture, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCaptureRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := w.Open(Session{Role: "client", Transport: "FramedTCP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, time.Now().UnixNano())
+	frames := []Record{
+		{Time: now, Session: id, Kind: KindOutbound, Version: 1, Type: 0, Flags: 1, Body: []byte("\x03ctxhello")},
+		{Time: now.Add(time.Millisecond), Session: id, Kind: KindInbound, Version: 1, Type: 1},
+		{Time: now.Add(2 * time.Millisecond), Session: id, Kind: KindClose},
+	}
+	for _, r := range frames {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 1. Every record reads back as written
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := SessionOf(open); err != nil || s.Role != "client" || s.Transport != "FramedTCP" {
+		t.Errorf("unexpected session %+v (%v)", s, err)
+	}
+	for i, want := range frames {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !got.Time.Equal(want.Time) || got.Session != want.Session || got.Kind != want.Kind ||
+			got.Version != want.Version || got.Type != want.Type || got.Flags != want.Flags || !bytes.Equal(got.Body, want.Body) {
+			t.Errorf("record %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	// 2. A record cut short is reported as such
+	r, _ = NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	for err == nil {
+		_, err = r.Next()
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	// 3. Anything else is refused
+	if _, err := NewReader(bytes.NewReader([]byte("not a capture"))); !errors.Is(err, ErrBadCapture) {
+		t.Errorf("expected ErrBadCapture, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package chaos decides the faults injected into connections for chaos testing:
+// latency, jitter, bandwidth limits, packet loss, duplication, reordering, bit
+// corruption, stalled reads and abrupt disconnects.
+//
+// An Injector only draws the faults, from a seedable RNG: the same seed and the same
+// sequence of operations give the same faults. The facade applies them around any
+// connection or listener (facade.NewChaosConnection, facade.NewChaosListener), or
+// around every connection of a socket created with a "+chaos" profile suffix
+// (e.g. "tcp-hello+chaos:worker"), which uses SocketConfig.Chaos, or Default.
+//
+// Faults can be changed and toggled at runtime (Set, Enable, Disable), e.g. to cut a
+// link in the middle of a test and restore it afterwards.
+package chaos
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDisconnected is returned by the operation that injected an abrupt disconnect.
+var ErrDisconnected = errors.New("chaos: injected disconnect")
+
+// Config describes the faults to inject. Probabilities range from 0 (never) to 1
+// (every message). Message faults apply to the messages written through the wrapper;
+// wrap both ends to disturb both directions.
+type Config struct {
+	Latency   time.Duration // Added before each message is written, and each accepted connection
+	Jitter    time.Duration // Latency varies uniformly within ±Jitter
+	Bandwidth int           // Bytes per second for writes (0 = unlimited)
+
+	Loss      float64 // Message silently dropped
+	Duplicate float64 // Message sent twice
+	Reorder   float64 // Message held back and sent after the next one (or on Close)
+	Corrupt   float64 // Message sent with one random bit flipped
+
+	Stall    float64       // Read blocked for StallFor before it proceeds
+	StallFor time.Duration // 0 = 1s
+
+	Disconnect float64 // Connection dropped without a goodbye, per read, write or accept
+}
+
+// Plan is what an Injector decided for one operation.
+type Plan struct {
+	Delay      time.Duration // Latency, jitter and transmission time (writes), or stall (reads)
+	Drop       bool
+	Duplicate  bool
+	Reorder    bool
+	Corrupt    int // Bit to flip, -1 = none
+	Disconnect bool
+}
+
+// Stats counts the faults drawn so far (a reordering drawn while a message is already
+// held back has no effect).
+type Stats struct {
+	Delayed      uint64
+	Dropped      uint64
+	Duplicated   uint64
+	Reordered    uint64
+	Corrupted    uint64
+	Stalled      uint64
+	Disconnected uint64
+}
+
+// -----------------------------------------------------------------------------
+
+// Injector draws faults according to its Config. It is safe for concurrent use and
+// may be shared by several connections and sockets.
+type Injector struct {
+	enabled atomic.Bool
+	config  atomic.Pointer[Config]
+
+	mu  sync.Mutex
+	rng *rand.Rand
+
+	delayed, dropped, duplicated, reordered, corrupted, stalled, disconnected atomic.Uint64
+}
+
+// Default is the injector of sockets created with a "+chaos" profile suffix and no
+// SocketConfig.Chaos. It starts enabled with no faults (seed 1): tests Set theirs.
+var Default = New(Config{}, 1)
+
+// New returns an enabled injector drawing from an RNG seeded with seed.
+func New(c Config, seed uint64) *Injector {
+	i := &Injector{}
+	i.Seed(seed)
+	i.Set(c)
+	i.enabled.Store(true)
+	return i
+}
+
+// Set replaces the faults to inject.
+func (i *Injector) Set(c Config) {
+	i.config.Store(&c)
+}
+
+// Config returns the faults to inject.
+func (i *Injector) Config() Config {
+	return *i.config.Load()
+}
+
+// Seed restarts the RNG from seed.
+func (i *Injector) Seed(seed uint64) {
+	i.mu.Lock()
+	i.rng = rand.New(rand.NewPCG(seed, seed))
+	i.mu.Unlock()
+}
+
+// Enable resumes fault injection.
+func (i *Injector) Enable() { i.enabled.Store(true) }
+
+// Disable suspends fault injection: operations go through untouched until Enable.
+func (i *Injector) Disable() { i.enabled.Store(false) }
+
+// Enabled reports whether faults are being injected.
+func (i *Injector) Enabled() bool { return i.enabled.Load() }
+
+// Stats returns the faults drawn so far.
+func (i *Injector) Stats() Stats {
+	return Stats{
+		Delayed:      i.delayed.Load(),
+		Dropped:      i.dropped.Load(),
+		Duplicated:   i.duplicated.Load(),
+		Reordered:    i.reordered.Load(),
+		Corrupted:    i.corrupted.Load(),
+		Stalled:      i.stalled.Load(),
+		Disconnected: i.disconnected.Load(),
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// PlanWrite draws the faults of a message of size bytes about to be written.
+func (i *Injector) PlanWrite(size int) Plan {
+	p := Plan{Corrupt: -1}
+	if !i.Enabled() {
+		return p
+	}
+	c := i.Config()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.hit(c.Disconnect) {
+		i.disconnected.Add(1)
+		p.Disconnect = true
+		return p
+	}
+	if p.Delay = i.latency(c); c.Bandwidth > 0 {
+		p.Delay += time.Duration(size) * time.Second / time.Duration(c.Bandwidth)
+	}
+	if p.Delay > 0 {
+		i.delayed.Add(1)
+	}
+	if i.hit(c.Loss) {
+		i.dropped.Add(1)
+		p.Drop = true
+		return p
+	}
+	if size > 0 && i.hit(c.Corrupt) {
+		i.corrupted.Add(1)
+		p.Corrupt = i.rng.IntN(size * 8)
+	}
+	if i.hit(c.Duplicate) {
+		i.duplicated.Add(1)
+		p.Duplicate = true
+	}
+	if i.hit(c.Reorder) {
+		i.reordered.Add(1)
+		p.Reorder = true
+	}
+	return p
+}
+
+// PlanRead draws the faults of a read about to start.
+func (i *Injector) PlanRead() Plan {
+	p := Plan{Corrupt: -1}
+	if !i.Enabled() {
+		return p
+	}
+	c := i.Config()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.hit(c.Disconnect) {
+		i.disconnected.Add(1)
+		p.Disconnect = true
+		return p
+	}
+	if i.hit(c.Stall) {
+		i.stalled.Add(1)
+		if p.Delay = c.StallFor; p.Delay <= 0 {
+			p.Delay = time.Second
+		}
+	}
+	return p
+}
+
+// PlanAccept draws the faults of a connection just accepted by a listener.
+func (i *Injector) PlanAccept() Plan {
+	p := Plan{Corrupt: -1}
+	if !i.Enabled() {
+		return p
+	}
+	c := i.Config()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.hit(c.Disconnect) {
+		i.disconnected.Add(1)
+		p.Disconnect = true
+		return p
+	}
+	if p.Delay = i.latency(c); p.Delay > 0 {
+		i.delayed.Add(1)
+	}
+	return p
+}
+
+// hit draws an event of probability prob. Callers hold mu.
+func (i *Injector) hit(prob float64) bool {
+	return prob > 0 && i.rng.Float64() < prob
+}
+
+// latency draws the latency and jitter of one operation. Callers hold mu.
+func (i *Injector) latency(c Config) time.Duration {
+	d := c.Latency
+	if c.Jitter > 0 {
+		d += time.Duration(i.rng.Int64N(int64(2*c.Jitter)+1)) - c.Jitter
+	}
+	return max(d, 0)
+}
+
+// -----------------------------------------------------------------------------
+
+// FlipBit returns a copy of p with bit n flipped (see Plan.Corrupt).
+func FlipBit(p []byte, n int) []byte {
+	out := append([]byte(nil), p...)
+	out[n/8] ^= 1 << (n % 8)
+	return out
+}
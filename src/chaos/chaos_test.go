@@ -0,0 +1,70 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInjector(t *testing.T) {
+	c := Config{Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond, Loss: 0.3, Duplicate: 0.3, Reorder: 0.3, Corrupt: 0.3}
+	draw := func(i *Injector) []Plan {
+		var plans []Plan
+		for range 100 {
+			plans = append(plans, i.PlanWrite(16))
+		}
+		return plans
+	}
+
+	// 1. The same seed gives the same faults, within the configured bounds
+	a, b := draw(New(c, 7)), draw(New(c, 7))
+	var dropped int
+	for n := range a {
+		if a[n] != b[n] {
+			t.Fatalf("plan %d differs: %+v vs %+v", n, a[n], b[n])
+		}
+		if d := a[n].Delay; d < 5*time.Millisecond || d > 15*time.Millisecond {
+			t.Errorf("plan %d: delay %v out of bounds", n, d)
+		}
+		if a[n].Corrupt >= 16*8 {
+			t.Errorf("plan %d: bit %d out of the message", n, a[n].Corrupt)
+		}
+		if a[n].Drop {
+			dropped++
+		}
+	}
+	if dropped < 10 || dropped > 50 {
+		t.Errorf("expected about 30 drops, got %d", dropped)
+	}
+
+	// 2. Disabled or reset injectors leave operations alone
+	i := New(c, 7)
+	i.Disable()
+	if p := i.PlanWrite(16); p != (Plan{Corrupt: -1}) {
+		t.Errorf("disabled injector planned %+v", p)
+	}
+	if s := i.Stats(); s != (Stats{}) {
+		t.Errorf("disabled injector counted %+v", s)
+	}
+	i.Enable()
+	i.Set(Config{Bandwidth: 1000})
+	if p := i.PlanWrite(500); p.Delay != 500*time.Millisecond || p.Drop {
+		t.Errorf("expected the transmission time alone, got %+v", p)
+	}
+
+	// 3. Stalls and disconnects
+	i.Set(Config{Stall: 1, StallFor: time.Millisecond})
+	if p := i.PlanRead(); p.Delay != time.Millisecond {
+		t.Errorf("expected a stall, got %+v", p)
+	}
+	i.Set(Config{Disconnect: 1})
+	if !i.PlanRead().Disconnect || !i.PlanWrite(1).Disconnect || !i.PlanAccept().Disconnect {
+		t.Error("expected disconnects")
+	}
+	if s := i.Stats(); s.Stalled != 1 || s.Disconnected != 3 || s.Delayed != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	if got := FlipBit([]byte{0x00, 0xff}, 9); got[0] != 0x00 || got[1] != 0xfd {
+		t.Errorf("unexpected flip %x", got)
+	}
+}
@@ -0,0 +1,93 @@
+// Package debug serves the live safe-sockets of the process over HTTP, in the spirit
+// of net/http/pprof: every client, server and accepted connection created through the
+// factory, with its profile, addresses, identity, state, heartbeat status, queue
+// depths and counters.
+//
+// Mount the handler next to pprof:
+//
+//	http.Handle(debug.Path, debug.Handler())
+//	go http.ListenAndServe("localhost:6060", nil) // http://localhost:6060/debug/safesocket
+//
+// The page is HTML; add ?format=json for the JSON form. Like pprof, it exposes
+// internals: keep it off public listeners.
+package debug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
+)
+
+// Path is the conventional mount point of the handler.
+const Path = "/debug/safesocket"
+
+// Handler serves the live sockets as HTML, or as JSON with ?format=json.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	sockets := facade.Sockets()
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(sockets)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = page.Execute(w, struct {
+		Now     time.Time
+		Sockets []facade.SocketState
+	}{time.Now(), sockets})
+}
+
+// -----------------------------------------------------------------------------
+
+var page = template.Must(template.New("sockets").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return time.Since(t).Round(time.Millisecond).String() + " ago"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>safe-socket</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin: 4px 0 16px; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; }
+th { background: #eee; }
+.closed { color: #999; }
+</style>
+</head>
+<body>
+<h1>safe-socket</h1>
+<p>{{len .Sockets}} sockets at {{.Now.Format "2006-01-02 15:04:05.000"}} - <a href="?format=json">JSON</a></p>
+{{range .Sockets}}
+<h2 class="{{.State}}">#{{.ID}} {{.Role}} {{.Profile}} ({{.Transport}}/{{.Protocol}}) {{.Address}} - {{.State}}</h2>
+<table>
+<tr><th>Created</th><th>Handshaking</th><th>Accept queue</th><th>Open</th><th>Opened</th><th>Bytes out</th><th>Bytes in</th><th>Msgs out</th><th>Msgs in</th><th>Heartbeats out</th><th>Heartbeats in</th><th>Handshake failures</th><th>Retries</th><th>Retransmits</th><th>SHM ring out</th><th>SHM ring in</th></tr>
+<tr><td>{{ago .Created}}</td><td>{{.Handshaking}}</td><td>{{.AcceptQueue}}</td>
+{{with .Stats}}<td>{{.ConnectionsOpen}}</td><td>{{.ConnectionsOpened}}</td><td>{{.BytesSent}}</td><td>{{.BytesReceived}}</td><td>{{.FramesSent}}</td><td>{{.FramesReceived}}</td><td>{{.HeartbeatsSent}}</td><td>{{.HeartbeatsSkipped}}</td><td>{{.HandshakeFailures}}</td><td>{{.OpenRetries}}</td><td>{{.Retransmits}}</td><td>{{.ShmRingOutBytes}}</td><td>{{.ShmRingInBytes}}</td>{{end}}</tr>
+</table>
+{{if .Connections}}
+<table>
+<tr><th>Local</th><th>Remote</th><th>Identity</th><th>Layers</th><th>TLS</th><th>Frames</th><th>Heartbeat</th><th>Idle timeout</th><th>Ping/pong</th><th>Missed pongs</th><th>RTT</th><th>Unacked</th><th>Opened</th><th>Last activity</th><th>Bytes out</th><th>Bytes in</th><th>Msgs out</th><th>Msgs in</th></tr>
+{{range .Connections}}
+<tr><td>{{.LocalAddr}}</td><td>{{.RemoteAddr}}</td><td>{{.Identity}}{{if .Authenticated}} (authenticated){{end}}</td><td>{{range $i, $l := .Layers}}{{if $i}} &gt; {{end}}{{$l}}{{end}}</td><td>{{.TLS}}</td><td>v{{.FrameVersion}}</td><td>{{.HeartbeatInterval}}</td><td>{{.IdleTimeout}}</td><td>{{.PingPong}}</td><td>{{.MissedPongs}}</td><td>{{.LastRTT}} (avg {{.SmoothedRTT}})</td><td>{{.Unacked}}</td><td>{{ago .OpenedAt}}</td><td>{{ago .LastActivity}}</td><td>{{.BytesSent}}</td><td>{{.BytesReceived}}</td><td>{{.MessagesSent}}</td><td>{{.MessagesReceived}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
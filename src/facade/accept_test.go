@@ -0,0 +1,70 @@
+package facade
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// TestHeartbeatStartsOnAccept verifies that connections waiting for Accept are not
+// heartbeated: the heartbeat layer only starts once Accept hands them out.
+func TestHeartbeatStartsOnAccept(t *testing.T) {
+	config := models.SocketConfig{Deadline: time.Second, HeartbeatInterval: 20 * time.Millisecond, Metrics: metrics.NewRegistry()}
+	server := NewSocketServer(&mockProfile{}, config)
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+	addr, _ := server.GetAddr()
+
+	client, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	time.Sleep(100 * time.Millisecond)
+	if sent := server.Stats().HeartbeatsSent; sent != 0 {
+		t.Errorf("expected no heartbeat before Accept, got %d", sent)
+	}
+
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	time.Sleep(100 * time.Millisecond)
+	if sent := server.Stats().HeartbeatsSent; sent == 0 {
+		t.Error("expected heartbeats once accepted")
+	}
+}
+
+// TestCloseWhileConnecting closes servers while clients are still connecting, so that
+// the accept loop counts connections concurrently with Close (run with -race).
+func TestCloseWhileConnecting(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		server := NewSocketServer(&mockProfile{}, models.SocketConfig{Deadline: time.Second})
+		if err := server.Listen(); err != nil {
+			t.Fatal(err)
+		}
+		addr, _ := server.GetAddr()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for j := 0; j < 5; j++ {
+				if c, err := net.Dial("tcp", addr); err == nil {
+					_ = c.Close()
+				}
+			}
+		}()
+		time.Sleep(time.Duration(i%5) * time.Millisecond)
+		if err := server.Close(); err != nil {
+			t.Fatal(err)
+		}
+		<-done
+	}
+}
@@ -0,0 +1,142 @@
+package facade
+
+import (
+	"fmt"
+	"net"
+	"path"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// AdmissionRules configures the rule-based admission policy built by NewAdmissionPolicy.
+// Deny lists win over allow lists; a non-empty allow list admits only matching peers.
+// Name and host patterns use path.Match globs ("worker-*"), CIDRs are matched
+// against the remote address of the connection.
+type AdmissionRules struct {
+	AllowNames []string // HelloMsg fromName globs
+	DenyNames  []string
+	AllowHosts []string // HelloMsg fromHost globs
+	DenyHosts  []string
+	AllowCIDRs []string // Remote address ranges ("10.0.0.0/8")
+	DenyCIDRs  []string
+
+	// Check is an optional hook run after the lists; a non-nil error rejects the peer.
+	Check interfaces.AdmissionFunc
+}
+
+// -----------------------------------------------------------------------------
+
+// ruleAdmission is the compiled form of AdmissionRules.
+type ruleAdmission struct {
+	rules     AdmissionRules
+	allowNets []*net.IPNet
+	denyNets  []*net.IPNet
+}
+
+// NewAdmissionPolicy validates the rules (globs, CIDRs) and returns the policy.
+func NewAdmissionPolicy(rules AdmissionRules) (interfaces.AdmissionPolicy, error) {
+	for _, list := range [][]string{rules.AllowNames, rules.DenyNames, rules.AllowHosts, rules.DenyHosts} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid admission pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	allowNets, err := parseCIDRs(rules.AllowCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	denyNets, err := parseCIDRs(rules.DenyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ruleAdmission{rules: rules, allowNets: allowNets, denyNets: denyNets}, nil
+}
+
+// Admit evaluates deny lists, then allow lists, then the Check hook.
+func (p *ruleAdmission) Admit(hello *schemas.HelloMsg, remote net.Addr) error {
+	name, _ := hello.FromName()
+	host, _ := hello.FromHost()
+	ip := remoteIP(remote)
+
+	// 1. Deny Lists
+	if matchAny(p.rules.DenyNames, name) {
+		return fmt.Errorf("name %q is denied", name)
+	}
+	if matchAny(p.rules.DenyHosts, host) {
+		return fmt.Errorf("host %q is denied", host)
+	}
+	if ip != nil && containsIP(p.denyNets, ip) {
+		return fmt.Errorf("address %s is denied", ip)
+	}
+
+	// 2. Allow Lists
+	if len(p.rules.AllowNames) > 0 && !matchAny(p.rules.AllowNames, name) {
+		return fmt.Errorf("name %q is not allowed", name)
+	}
+	if len(p.rules.AllowHosts) > 0 && !matchAny(p.rules.AllowHosts, host) {
+		return fmt.Errorf("host %q is not allowed", host)
+	}
+	if len(p.allowNets) > 0 && (ip == nil || !containsIP(p.allowNets, ip)) {
+		return fmt.Errorf("address %v is not allowed", remote)
+	}
+
+	// 3. Callback Hook
+	if p.rules.Check != nil {
+		return p.rules.Check(hello, remote)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admission CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP extracts the IP of a remote address (nil for SHM and unknown address types).
+func remoteIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	if addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,73 @@
+package facade
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"capnproto.org/go/capnp/v3"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+func newTestHello(t *testing.T, name, host string) *schemas.HelloMsg {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello, err := schemas.NewRootHelloMsg(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = hello.SetFromName(name)
+	_ = hello.SetFromHost(host)
+	return &hello
+}
+
+func TestAdmissionPolicy(t *testing.T) {
+	policy, err := NewAdmissionPolicy(AdmissionRules{
+		AllowNames: []string{"worker-*", "admin"},
+		DenyNames:  []string{"worker-banned"},
+		DenyHosts:  []string{"*.untrusted"},
+		AllowCIDRs: []string{"10.0.0.0/8", "127.0.0.1/32"},
+		Check: func(hello *schemas.HelloMsg, remote net.Addr) error {
+			if host, _ := hello.FromHost(); host == "maintenance" {
+				return errors.New("host under maintenance")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lan := &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 4000}
+	wan := &net.TCPAddr{IP: net.ParseIP("8.8.8.8"), Port: 4000}
+
+	cases := []struct {
+		name, peer, host string
+		remote           net.Addr
+		admitted         bool
+	}{
+		{"Allowed_Glob", "worker-01", "node1", lan, true},
+		{"Allowed_Exact", "admin", "node1", lan, true},
+		{"Name_Not_Allowed", "intruder", "node1", lan, false},
+		{"Name_Denied", "worker-banned", "node1", lan, false},
+		{"Host_Denied", "worker-01", "box.untrusted", lan, false},
+		{"CIDR_Not_Allowed", "worker-01", "node1", wan, false},
+		{"No_IP", "worker-01", "node1", nil, false},
+		{"Hook_Rejects", "worker-01", "maintenance", lan, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Admit(newTestHello(t, tc.peer, tc.host), tc.remote)
+			if admitted := err == nil; admitted != tc.admitted {
+				t.Errorf("expected admitted=%v, got verdict %v", tc.admitted, err)
+			}
+		})
+	}
+
+	if _, err := NewAdmissionPolicy(AdmissionRules{DenyCIDRs: []string{"not-a-cidr"}}); err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+}
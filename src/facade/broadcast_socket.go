@@ -0,0 +1,264 @@
+package facade
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// ErrBroadcastNoAccept is returned by Accept on a shm-broadcast publisher.
+var ErrBroadcastNoAccept = errors.New("shm-broadcast publishers have no connections to accept: use Send")
+
+// BroadcastSocket implements interfaces.Socket for the shm-broadcast profile.
+// The server end publishes (Listen, then Send/Write); client ends subscribe
+// (Open, then Receive/Read) and follow the ring independently.
+type BroadcastSocket struct {
+	Profile interfaces.SocketProfile
+	Config  models.SocketConfig
+	Role    interfaces.SocketType
+	Logger  interfaces.Logger
+
+	mu      sync.RWMutex
+	ring    *transports.ShmBroadcast
+	metrics *metrics.Socket
+}
+
+// -----------------------------------------------------------------------------
+
+func NewBroadcastSocket(p interfaces.SocketProfile, c models.SocketConfig, role interfaces.SocketType) *BroadcastSocket {
+	name := "client"
+	if role == interfaces.SocketTypeServer {
+		name = "server"
+	}
+	return &BroadcastSocket{
+		Profile: p,
+		Config:  c,
+		Role:    role,
+		metrics: socketMetrics(p, c, name),
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Listen creates the ring and starts publishing (server end).
+func (b *BroadcastSocket) Listen() error {
+	if b.Role != interfaces.SocketTypeServer {
+		return errors.New("method Listen not supported for Client socket")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ring != nil {
+		return errors.New("publisher already listening")
+	}
+
+	ring, err := transports.PublishShmBroadcast(b.Profile.GetAddress(), b.Config.ShmBroadcastSize, shmOptions(b.Config))
+	if err != nil {
+		return err
+	}
+	b.ring = ring
+	b.metrics.Register()
+	b.metrics.ConnOpened()
+	return nil
+}
+
+// Open attaches to the publisher's ring (client end), retrying like SocketClient.Open
+// while the publisher is not up yet.
+func (b *BroadcastSocket) Open() error {
+	if b.Role != interfaces.SocketTypeClient {
+		return errors.New("method Open not supported for Server socket")
+	}
+
+	interval := b.Config.RetryInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	for retries := 0; ; retries++ {
+		b.mu.Lock()
+		if b.ring != nil {
+			b.mu.Unlock()
+			return errors.New("socket already open")
+		}
+		ring, err := transports.SubscribeShmBroadcast(b.Profile.GetAddress(), shmOptions(b.Config))
+		if err == nil {
+			ring.SetAutoResync(b.Config.BroadcastAutoResync)
+			b.ring = ring
+			b.mu.Unlock()
+			b.metrics.Register()
+			b.metrics.ConnOpened()
+			return nil
+		}
+		b.mu.Unlock()
+
+		if b.Config.MaxRetries >= 0 && retries >= b.Config.MaxRetries {
+			return fmt.Errorf("failed to subscribe after %d attempts: %w", retries+1, err)
+		}
+		b.metrics.OpenRetried()
+		b.logger().Warn("subscribe failed, retrying",
+			slog.Any("error", err),
+			slog.Duration("retry_in", interval),
+			slog.Int("attempt", retries+1),
+			slog.Int("max_retries", b.Config.MaxRetries))
+		time.Sleep(interval)
+	}
+}
+
+// Resync moves a subscriber to the latest message after an overrun
+// (errors.Is(err, transports.ErrShmOverrun)).
+func (b *BroadcastSocket) Resync() error {
+	ring, err := b.subscriber()
+	if err != nil {
+		return err
+	}
+	ring.Resync()
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *BroadcastSocket) publisher() (*transports.ShmBroadcast, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.Role != interfaces.SocketTypeServer {
+		return nil, errors.New("shm-broadcast subscribers cannot publish")
+	}
+	if b.ring == nil {
+		return nil, errors.New("socket not listening")
+	}
+	return b.ring, nil
+}
+
+func (b *BroadcastSocket) subscriber() (*transports.ShmBroadcast, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.Role != interfaces.SocketTypeClient {
+		return nil, errors.New("shm-broadcast publishers cannot receive")
+	}
+	if b.ring == nil {
+		return nil, errors.New("socket not open")
+	}
+	return b.ring, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// Send publishes one message to every subscriber (server end).
+func (b *BroadcastSocket) Send(data []byte) error {
+	_, err := b.Write(data)
+	return err
+}
+
+func (b *BroadcastSocket) Write(data []byte) (int, error) {
+	ring, err := b.publisher()
+	if err != nil {
+		return 0, err
+	}
+	n, err := ring.Write(data)
+	if err == nil {
+		b.metrics.Sent(n)
+	}
+	return n, err
+}
+
+// Receive returns the next message (client end).
+func (b *BroadcastSocket) Receive() ([]byte, error) {
+	ring, err := b.subscriber()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := ring.ReadMessage()
+	if err == nil {
+		b.metrics.Received(len(msg))
+	}
+	return msg, err
+}
+
+func (b *BroadcastSocket) Read(p []byte) (int, error) {
+	ring, err := b.subscriber()
+	if err != nil {
+		return 0, err
+	}
+	n, err := ring.Read(p)
+	if err == nil {
+		b.metrics.Received(n)
+	}
+	return n, err
+}
+
+// Accept is not supported: subscribers attach to the ring without a connection.
+func (b *BroadcastSocket) Accept() (interfaces.TransportConnection, error) {
+	return nil, ErrBroadcastNoAccept
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *BroadcastSocket) Close() error {
+	b.mu.Lock()
+	ring := b.ring
+	b.ring = nil
+	b.mu.Unlock()
+
+	if ring != nil {
+		b.metrics.ConnClosed()
+		b.metrics.Unregister()
+		return ring.Close()
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the activity recorded for this socket (see metrics.Stats).
+func (b *BroadcastSocket) Stats() metrics.Stats {
+	return b.metrics.Stats()
+}
+
+func (b *BroadcastSocket) SetLogger(logger interfaces.Logger) {
+	b.Logger = logger
+}
+
+// SetLogHandler routes the socket's structured log events to h (see SocketConfig.LogHandler).
+func (b *BroadcastSocket) SetLogHandler(h slog.Handler) {
+	b.Config.LogHandler = h
+}
+
+func (b *BroadcastSocket) logger() *slog.Logger {
+	role := "client"
+	if b.Role == interfaces.SocketTypeServer {
+		role = "server"
+	}
+	return socketLogger(b.Config.LogHandler, b.Logger, b.Profile, role)
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *BroadcastSocket) SetDeadline(t time.Time) error {
+	return b.SetReadDeadline(t)
+}
+
+func (b *BroadcastSocket) SetReadDeadline(t time.Time) error {
+	ring, err := b.subscriber()
+	if err != nil {
+		return err
+	}
+	return ring.SetReadDeadline(t)
+}
+
+// SetWriteDeadline is a no-op: publishing never blocks.
+func (b *BroadcastSocket) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// SetIdleTimeout makes a subscriber's reads fail when no message arrived for d.
+// Subscribers wait forever by default: the publisher's liveness is checked by PID.
+func (b *BroadcastSocket) SetIdleTimeout(d time.Duration) error {
+	ring, err := b.subscriber()
+	if err != nil {
+		return err
+	}
+	return ring.SetIdleTimeout(d)
+}
@@ -0,0 +1,143 @@
+package facade
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/capture"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// CaptureConnection records the frames of the wrapped connection into a capture file
+// (see the capture package). Transports able to report their frames (TCP, TLS, SHM)
+// hand over every frame they write or read, control frames and heartbeats included;
+// on the others (UDP) the wrapper records the data going through it.
+//
+// It sits right above the transport, so the file holds what went over the wire:
+// handshakes included, and ciphertext on *-secure profiles. Capture errors never fail
+// the connection; see capture.Writer.Err.
+type CaptureConnection struct {
+	interfaces.TransportConnection
+	w       *capture.Writer
+	session uint32
+	tap     interfaces.FrameTap
+	once    sync.Once
+}
+
+// Ensure CaptureConnection implements TransportConnection
+var _ interfaces.TransportConnection = (*CaptureConnection)(nil)
+
+// -----------------------------------------------------------------------------
+
+// NewCaptureConnection starts a capture session described by s and wraps conn.
+func NewCaptureConnection(conn interfaces.TransportConnection, w *capture.Writer, s capture.Session) *CaptureConnection {
+	c := &CaptureConnection{
+		TransportConnection: conn,
+		w:                   w,
+		tap:                 transports.FrameTapOf(conn),
+	}
+	c.session, _ = w.Open(s)
+	if c.tap != nil {
+		c.tap.SetFrameObserver(c.observe)
+	}
+	return c
+}
+
+// withCapture wraps conn in a CaptureConnection when the config asks for traffic capture.
+func withCapture(conn interfaces.TransportConnection, p interfaces.SocketProfile, c models.SocketConfig, role string) interfaces.TransportConnection {
+	if c.Capture == nil {
+		return conn
+	}
+	return NewCaptureConnection(conn, c.Capture, capture.Session{
+		Role:       role,
+		Transport:  string(p.GetTransport()),
+		Protocol:   string(p.GetProtocol()),
+		Address:    p.GetAddress(),
+		LocalAddr:  addrString(conn.LocalAddr()),
+		RemoteAddr: addrString(conn.RemoteAddr()),
+	})
+}
+
+func addrString(a net.Addr) string {
+	if a == nil {
+		return ""
+	}
+	return a.String()
+}
+
+// -----------------------------------------------------------------------------
+
+// observe records a frame reported by the transport.
+func (c *CaptureConnection) observe(f interfaces.Frame) {
+	kind := capture.KindOutbound
+	if f.Direction == interfaces.FrameInbound {
+		kind = capture.KindInbound
+	}
+	_ = c.w.Write(capture.Record{
+		Time:    time.Now(),
+		Session: c.session,
+		Kind:    kind,
+		Version: f.Version,
+		Type:    uint8(f.Type),
+		Flags:   f.Flags,
+		Body:    f.Body,
+	})
+}
+
+// record captures the data going through the wrapper, for transports without a tap.
+func (c *CaptureConnection) record(dir interfaces.FrameDirection, p []byte) {
+	frameType := interfaces.FrameData
+	if len(p) == 0 {
+		frameType = interfaces.FrameHeartbeat
+	}
+	c.observe(interfaces.Frame{Direction: dir, Type: frameType, Body: p})
+}
+
+func (c *CaptureConnection) Write(p []byte) (int, error) {
+	n, err := c.TransportConnection.Write(p)
+	if err == nil && c.tap == nil {
+		c.record(interfaces.FrameOutbound, p[:n])
+	}
+	return n, err
+}
+
+func (c *CaptureConnection) Read(p []byte) (int, error) {
+	n, err := c.TransportConnection.Read(p)
+	if err == nil && c.tap == nil {
+		c.record(interfaces.FrameInbound, p[:n])
+	}
+	return n, err
+}
+
+func (c *CaptureConnection) ReadMessage() ([]byte, error) {
+	msg, err := c.TransportConnection.ReadMessage()
+	if err == nil && c.tap == nil {
+		c.record(interfaces.FrameInbound, msg)
+	}
+	return msg, err
+}
+
+// Close closes the wrapped connection (capturing its goodbye), then ends the session.
+func (c *CaptureConnection) Close() error {
+	err := c.TransportConnection.Close()
+	c.once.Do(func() {
+		if c.tap != nil {
+			c.tap.SetFrameObserver(nil)
+		}
+		_ = c.w.Write(capture.Record{Time: time.Now(), Session: c.session, Kind: capture.KindClose})
+	})
+	return err
+}
+
+// Unwrap returns the wrapped connection.
+func (c *CaptureConnection) Unwrap() interfaces.TransportConnection {
+	return c.TransportConnection
+}
+
+// ConnInfo describes the wrapped connection.
+func (c *CaptureConnection) ConnInfo() interfaces.ConnInfo {
+	return c.TransportConnection.ConnInfo().WithLayer("capture")
+}
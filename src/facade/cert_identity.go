@@ -0,0 +1,87 @@
+package facade
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+var (
+	ErrCertRequired         = errors.New("verified client certificate required")
+	ErrCertIdentityMismatch = errors.New("hello name does not match the client certificate")
+)
+
+// CertIdentity is the verified certificate side of a TLS peer's identity.
+type CertIdentity struct {
+	// Chain is the verified chain, leaf first.
+	Chain []*x509.Certificate
+	// SpiffeID is the first spiffe:// URI SAN of the leaf, or "".
+	SpiffeID string
+}
+
+// Leaf returns the peer's own certificate.
+func (c *CertIdentity) Leaf() *x509.Certificate {
+	return c.Chain[0]
+}
+
+// Names returns every identity the leaf vouches for: CN, DNS SANs and URI SANs.
+func (c *CertIdentity) Names() []string {
+	leaf := c.Leaf()
+	var names []string
+	if leaf.Subject.CommonName != "" {
+		names = append(names, leaf.Subject.CommonName)
+	}
+	names = append(names, leaf.DNSNames...)
+	for _, uri := range leaf.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// -----------------------------------------------------------------------------
+
+// CertIdentityOf returns the verified certificate identity of the peer of a TLS
+// connection (wrapped or not), or nil on other transports and unverified peers.
+func CertIdentityOf(conn interfaces.TransportConnection) *CertIdentity {
+	state, ok := transports.TLSStateOf(conn)
+	if !ok || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cert := &CertIdentity{Chain: state.VerifiedChains[0]}
+	for _, uri := range cert.Leaf().URIs {
+		if uri.Scheme == "spiffe" {
+			cert.SpiffeID = uri.String()
+			break
+		}
+	}
+	return cert
+}
+
+// -----------------------------------------------------------------------------
+
+// bindCertIdentity checks that the Hello name is vouched for by the certificate:
+// it must equal one of its names, or match a glob mapped to one of them.
+func bindCertIdentity(hello *schemas.HelloMsg, cert *CertIdentity, mapping map[string][]string) error {
+	if cert == nil {
+		return ErrCertRequired
+	}
+
+	name, _ := hello.FromName()
+	for _, certName := range cert.Names() {
+		if name == certName {
+			return nil
+		}
+		for _, pattern := range mapping[certName] {
+			if ok, _ := path.Match(pattern, name); ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrCertIdentityMismatch, name)
+}
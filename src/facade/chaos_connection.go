@@ -0,0 +1,204 @@
+package facade
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/chaos"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// ChaosConnection injects the faults drawn by a chaos.Injector into the wrapped
+// connection: latency, bandwidth, loss, duplication, reordering and corruption of the
+// messages it writes, stalled reads, and abrupt disconnects (no goodbye).
+//
+// It sits right above the transport. Control frames (heartbeats, ping/pong, goodbye)
+// and traced writes reach the transport directly and are not disturbed.
+type ChaosConnection struct {
+	interfaces.TransportConnection
+	inj *chaos.Injector
+
+	writeMu sync.Mutex
+	held    []byte // Reordered message, sent after the next one
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Ensure ChaosConnection implements TransportConnection
+var _ interfaces.TransportConnection = (*ChaosConnection)(nil)
+
+// -----------------------------------------------------------------------------
+
+// NewChaosConnection wraps conn, injecting the faults drawn by inj.
+func NewChaosConnection(conn interfaces.TransportConnection, inj *chaos.Injector) *ChaosConnection {
+	return &ChaosConnection{
+		TransportConnection: conn,
+		inj:                 inj,
+		closed:              make(chan struct{}),
+	}
+}
+
+// withChaos wraps conn in a ChaosConnection when the config asks for fault injection.
+func withChaos(conn interfaces.TransportConnection, c models.SocketConfig) interfaces.TransportConnection {
+	if c.Chaos == nil {
+		return conn
+	}
+	return NewChaosConnection(conn, c.Chaos)
+}
+
+// -----------------------------------------------------------------------------
+
+func (c *ChaosConnection) Write(p []byte) (int, error) {
+	plan := c.inj.PlanWrite(len(p))
+	if plan.Disconnect {
+		return 0, c.abort()
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if !c.sleep(plan.Delay) {
+		return 0, net.ErrClosed
+	}
+	if plan.Drop {
+		return len(p), nil
+	}
+
+	msg := p
+	if plan.Corrupt >= 0 {
+		msg = chaos.FlipBit(p, plan.Corrupt)
+	}
+	if plan.Reorder && c.held == nil {
+		c.held = append([]byte(nil), msg...)
+		return len(p), nil
+	}
+	n, err := c.TransportConnection.Write(msg)
+	if err == nil && plan.Duplicate {
+		_, err = c.TransportConnection.Write(msg)
+	}
+	if err == nil && c.held != nil {
+		_, err = c.TransportConnection.Write(c.held)
+		c.held = nil
+	}
+	return n, err
+}
+
+func (c *ChaosConnection) Read(p []byte) (int, error) {
+	if err := c.beforeRead(); err != nil {
+		return 0, err
+	}
+	return c.TransportConnection.Read(p)
+}
+
+func (c *ChaosConnection) ReadMessage() ([]byte, error) {
+	if err := c.beforeRead(); err != nil {
+		return nil, err
+	}
+	return c.TransportConnection.ReadMessage()
+}
+
+// beforeRead applies the faults drawn for a read.
+func (c *ChaosConnection) beforeRead() error {
+	plan := c.inj.PlanRead()
+	if plan.Disconnect {
+		return c.abort()
+	}
+	if !c.sleep(plan.Delay) {
+		return net.ErrClosed
+	}
+	return nil
+}
+
+// sleep waits for d, or returns false as soon as the connection is closed.
+func (c *ChaosConnection) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+// abort drops the connection without a goodbye and returns chaos.ErrDisconnected.
+func (c *ChaosConnection) abort() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	if a := transports.AborterOf(c.TransportConnection); a != nil {
+		_ = a.Abort()
+	} else {
+		_ = c.TransportConnection.Close()
+	}
+	return chaos.ErrDisconnected
+}
+
+// Close sends the message still held back by a reordering, then closes the wrapped connection.
+func (c *ChaosConnection) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.writeMu.Lock()
+	if c.held != nil {
+		_, _ = c.TransportConnection.Write(c.held)
+		c.held = nil
+	}
+	c.writeMu.Unlock()
+	return c.TransportConnection.Close()
+}
+
+// Unwrap returns the wrapped connection.
+func (c *ChaosConnection) Unwrap() interfaces.TransportConnection {
+	return c.TransportConnection
+}
+
+// ConnInfo describes the wrapped connection.
+func (c *ChaosConnection) ConnInfo() interfaces.ConnInfo {
+	return c.TransportConnection.ConnInfo().WithLayer("chaos")
+}
+
+// -----------------------------------------------------------------------------
+
+// ChaosListener delays or drops the connections accepted by the wrapped listener, and
+// wraps the others in a ChaosConnection sharing its injector.
+type ChaosListener struct {
+	interfaces.TransportListener
+	inj *chaos.Injector
+}
+
+// NewChaosListener wraps ln, injecting the faults drawn by inj.
+func NewChaosListener(ln interfaces.TransportListener, inj *chaos.Injector) *ChaosListener {
+	return &ChaosListener{TransportListener: ln, inj: inj}
+}
+
+// withChaosListener wraps ln in a ChaosListener when the config asks for fault injection.
+func withChaosListener(ln interfaces.TransportListener, c models.SocketConfig) interfaces.TransportListener {
+	if c.Chaos == nil {
+		return ln
+	}
+	return NewChaosListener(ln, c.Chaos)
+}
+
+// Accept returns the next connection that survived the injected faults.
+func (l *ChaosListener) Accept() (interfaces.TransportConnection, error) {
+	for {
+		conn, err := l.TransportListener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		plan := l.inj.PlanAccept()
+		if plan.Disconnect {
+			if a := transports.AborterOf(conn); a != nil {
+				_ = a.Abort()
+			} else {
+				_ = conn.Close()
+			}
+			continue
+		}
+		time.Sleep(plan.Delay)
+		return NewChaosConnection(conn, l.inj), nil
+	}
+}
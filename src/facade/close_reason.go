@@ -0,0 +1,17 @@
+package facade
+
+import (
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// CloseWithReason closes a (possibly wrapped) connection, announcing code and reason to
+// the peer when the transport supports graceful close. The peer's next read then fails
+// with a *transports.PeerClosedError. Other transports are simply closed, as are tcp and
+// tls connections without a Hello handshake (legacy frames cannot carry the goodbye).
+func CloseWithReason(conn interfaces.TransportConnection, code interfaces.CloseCode, reason string) error {
+	if gc := transports.CloserOf(conn); gc != nil {
+		gc.SetCloseReason(code, reason)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,157 @@
+package facade
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// connRegistry keeps track of the connections handed out by SocketServer.Accept.
+// Entries are keyed by the trackingConnection sitting at the bottom of the wrapper
+// chain (so removal can happen from its Close hook), while the value is the
+// outermost wrapper returned to the application (so writes go through heartbeat
+// locking and envelope/reliability layers exactly like user writes).
+type connRegistry struct {
+	mu    sync.RWMutex
+	conns map[*trackingConnection]interfaces.TransportConnection
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *connRegistry) add(key *trackingConnection, conn interfaces.TransportConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The connection may already have been closed during the tail of Accept;
+	// its removal hook has run, so registering it now would leak the entry.
+	if key.closed.Load() {
+		return
+	}
+	if r.conns == nil {
+		r.conns = make(map[*trackingConnection]interfaces.TransportConnection)
+	}
+	r.conns[key] = conn
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *connRegistry) remove(key *trackingConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, key)
+}
+
+// -----------------------------------------------------------------------------
+
+// snapshot returns a copy of the registered connections, safe to iterate without the lock.
+func (r *connRegistry) snapshot() []interfaces.TransportConnection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]interfaces.TransportConnection, 0, len(r.conns))
+	for _, conn := range r.conns {
+		list = append(list, conn)
+	}
+	return list
+}
+
+// -----------------------------------------------------------------------------
+
+// IdentityOf extracts the peer identity from a potentially wrapped connection.
+// It traverses through Heartbeat, Handshake, or Envelope wrappers to find the HelloMsg.
+func IdentityOf(conn interfaces.TransportConnection) *schemas.HelloMsg {
+	switch c := conn.(type) {
+	case *HandshakeConnection:
+		// TCP/SHM Handshake
+		return c.Identity
+	case *EnvelopedConnection:
+		// UDP Stateless Identity
+		return c.LastIdentity
+	case *HeartbeatConnection:
+		return IdentityOf(c.TransportConnection)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// SocketServer Registry API
+// -----------------------------------------------------------------------------
+
+// Connections returns the connections accepted by this server that are still open.
+// The returned slice is a snapshot; connections closed afterwards remain in it.
+func (s *SocketServer) Connections() []interfaces.TransportConnection {
+	return s.registry.snapshot()
+}
+
+// -----------------------------------------------------------------------------
+
+// LookupByName returns the open connections whose Hello identity carries the given name.
+// Several peers may legitimately share a name, hence the slice.
+func (s *SocketServer) LookupByName(name string) []interfaces.TransportConnection {
+	var found []interfaces.TransportConnection
+	for _, conn := range s.registry.snapshot() {
+		identity := IdentityOf(conn)
+		if identity == nil {
+			continue
+		}
+		if fromName, err := identity.FromName(); err == nil && fromName == name {
+			found = append(found, conn)
+		}
+	}
+	return found
+}
+
+// -----------------------------------------------------------------------------
+
+// LookupByAddr returns the open connections whose remote address matches addr ("IP:Port").
+func (s *SocketServer) LookupByAddr(addr string) []interfaces.TransportConnection {
+	var found []interfaces.TransportConnection
+	for _, conn := range s.registry.snapshot() {
+		if remote := conn.RemoteAddr(); remote != nil && remote.String() == addr {
+			found = append(found, conn)
+		}
+	}
+	return found
+}
+
+// -----------------------------------------------------------------------------
+
+// Broadcast writes payload to every open connection concurrently, so one slow peer
+// does not delay the others. Failed writes are reported together; the connections
+// are left open (the heartbeat layer is responsible for tearing down dead peers).
+func (s *SocketServer) Broadcast(payload []byte) error {
+	conns := s.registry.snapshot()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(conns))
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn interfaces.TransportConnection) {
+			defer wg.Done()
+			if _, err := conn.Write(payload); err != nil {
+				errs[i] = fmt.Errorf("broadcast to %v: %w", conn.RemoteAddr(), err)
+			}
+		}(i, conn)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// -----------------------------------------------------------------------------
+
+// CloseWhere closes every open connection for which predicate returns true
+// and returns the number of connections closed.
+func (s *SocketServer) CloseWhere(predicate func(conn interfaces.TransportConnection) bool) int {
+	closed := 0
+	for _, conn := range s.registry.snapshot() {
+		if predicate(conn) {
+			_ = conn.Close()
+			closed++
+		}
+	}
+	return closed
+}
@@ -0,0 +1,87 @@
+package facade
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+func TestConnectionRegistry(t *testing.T) {
+	server := NewSocketServer(&mockProfile{}, models.SocketConfig{Deadline: 2 * time.Second})
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+	addr, _ := server.GetAddr()
+
+	accepted := make(chan interfaces.TransportConnection, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := server.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	// 1. Connect two raw clients and wait for both to be registered
+	clients := make([]net.Conn, 2)
+	for i := range clients {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = c.Close() }()
+		clients[i] = c
+		<-accepted
+	}
+
+	if n := len(server.Connections()); n != 2 {
+		t.Fatalf("expected 2 registered connections, got %d", n)
+	}
+	if found := server.LookupByAddr(clients[0].LocalAddr().String()); len(found) != 1 {
+		t.Fatalf("expected LookupByAddr to find 1 connection, got %d", len(found))
+	}
+
+	// 2. Broadcast reaches every client
+	if err := server.Broadcast([]byte("news")); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+	for _, c := range clients {
+		_ = c.SetReadDeadline(time.Now().Add(time.Second))
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(c, header); err != nil {
+			t.Fatalf("client read header failed: %v", err)
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(c, body); err != nil {
+			t.Fatalf("client read body failed: %v", err)
+		}
+		if string(body) != "news" {
+			t.Errorf("expected 'news', got %q", body)
+		}
+	}
+
+	// 3. CloseWhere removes matching connections from the registry
+	target := clients[0].LocalAddr().String()
+	closed := server.CloseWhere(func(conn interfaces.TransportConnection) bool {
+		return conn.RemoteAddr().String() == target
+	})
+	if closed != 1 {
+		t.Fatalf("expected CloseWhere to close 1 connection, got %d", closed)
+	}
+	if n := len(server.Connections()); n != 1 {
+		t.Fatalf("expected 1 registered connection after CloseWhere, got %d", n)
+	}
+
+	server.CloseWhere(func(interfaces.TransportConnection) bool { return true })
+	if n := len(server.Connections()); n != 0 {
+		t.Fatalf("expected empty registry, got %d", n)
+	}
+}
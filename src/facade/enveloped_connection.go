@@ -24,6 +24,10 @@ type EnvelopedConnection struct {
 	// LastIdentity holds the identity of the sender of the last packet read.
 	// This allows UDP users to inspect who sent the data.
 	LastIdentity *schemas.HelloMsg
+
+	// Guard holds the per-sender replay windows used when Config.AuthKeys is set.
+	// Connections receiving from the same senders must share it (see SocketServer).
+	Guard *protocols.ReplayGuard
 }
 
 // -----------------------------------------------------------------------------
@@ -34,6 +38,7 @@ func NewEnvelopedConnection(conn interfaces.TransportConnection, p interfaces.So
 		Profile: p,
 		Config:  c,
 		Proto:   protocols.NewHelloProtocol().(*protocols.HelloProtocol),
+		Guard:   protocols.NewReplayGuard(c.EnvelopeMaxAge),
 	}
 }
 
@@ -69,7 +74,7 @@ func (e *EnvelopedConnection) Read(p []byte) (n int, err error) {
 	}
 
 	// 2. Decapsulate
-	payload, identity, err := e.Proto.Decapsulate(rawBuf[:nRaw])
+	payload, identity, err := e.decapsulate(rawBuf[:nRaw])
 	if err != nil {
 		return 0, err
 	}
@@ -97,7 +102,7 @@ func (e *EnvelopedConnection) ReadMessage() ([]byte, error) {
 	}
 
 	// 2. Decapsulate
-	payload, identity, err := e.Proto.Decapsulate(rawBuf)
+	payload, identity, err := e.decapsulate(rawBuf)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +120,17 @@ func (e *EnvelopedConnection) ReadMessage() ([]byte, error) {
 
 // -----------------------------------------------------------------------------
 
+// decapsulate verifies signed envelopes when a keyring is configured; unsigned,
+// forged, stale and replayed packets are then rejected.
+func (e *EnvelopedConnection) decapsulate(packet []byte) ([]byte, *schemas.HelloMsg, error) {
+	if len(e.Config.AuthKeys) > 0 {
+		return e.Proto.DecapsulateAuthenticated(packet, e.Config, e.Guard)
+	}
+	return e.Proto.Decapsulate(packet)
+}
+
+// -----------------------------------------------------------------------------
+
 func (e *EnvelopedConnection) Close() error {
 	return e.Conn.Close()
 }
@@ -133,6 +149,14 @@ func (e *EnvelopedConnection) RemoteAddr() net.Addr {
 	return e.Conn.RemoteAddr()
 }
 
+// ConnInfo describes the wrapped connection, with the identity of the last sender.
+func (e *EnvelopedConnection) ConnInfo() interfaces.ConnInfo {
+	info := e.Conn.ConnInfo().WithLayer("envelope")
+	info.Identity = e.LastIdentity
+	info.Authenticated = len(e.Config.AuthKeys) > 0
+	return info
+}
+
 // -----------------------------------------------------------------------------
 
 func (e *EnvelopedConnection) SetDeadline(t time.Time) error {
@@ -155,4 +179,9 @@ func (e *EnvelopedConnection) SetIdleTimeout(d time.Duration) error {
 	return e.Conn.SetIdleTimeout(d)
 }
 
+// Unwrap returns the wrapped connection.
+func (e *EnvelopedConnection) Unwrap() interfaces.TransportConnection {
+	return e.Conn
+}
+
 // -----------------------------------------------------------------------------
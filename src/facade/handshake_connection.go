@@ -12,6 +12,10 @@ import (
 type HandshakeConnection struct {
 	interfaces.TransportConnection
 	Identity *schemas.HelloMsg
+	// Authenticated is true when the identity was proven by the challenge-response
+	// (server keyring) or bound to the client certificate, false when it is merely
+	// declared by the peer.
+	Authenticated bool
 }
 
 // -----------------------------------------------------------------------------
@@ -39,3 +43,16 @@ func NewHandshakeConnection(conn interfaces.TransportConnection, identity *schem
 func (h *HandshakeConnection) SetIdleTimeout(d time.Duration) error {
 	return h.TransportConnection.SetIdleTimeout(d)
 }
+
+// Unwrap returns the wrapped connection.
+func (h *HandshakeConnection) Unwrap() interfaces.TransportConnection {
+	return h.TransportConnection
+}
+
+// ConnInfo adds the peer identity to the description of the wrapped connection.
+func (h *HandshakeConnection) ConnInfo() interfaces.ConnInfo {
+	info := h.TransportConnection.ConnInfo().WithLayer("handshake")
+	info.Identity = h.Identity
+	info.Authenticated = h.Authenticated
+	return info
+}
@@ -1,13 +1,36 @@
 package facade
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
 )
 
+// DefaultMaxMissedPongs is the number of consecutive unanswered pings tolerated
+// in ping/pong mode before the connection is closed.
+const DefaultMaxMissedPongs = 3
+
+// ErrControlUnsupported is returned when ping/pong is requested on a transport
+// that cannot carry control frames (e.g. UDP) or that did not negotiate frame version 1.
+var ErrControlUnsupported = transports.ErrControlUnsupported
+
 // HeartbeatConnection wraps a transport and periodically sends 0-length heartbeats.
+//
+// In ping/pong mode (EnablePingPong) the heartbeat becomes a timestamped ping control
+// frame that the peer echoes back, which measures round-trip time and detects peers
+// that accept writes but never read. Pongs are processed by the read path; when the
+// local side does not read (e.g. a server that only writes), each ping first pumps
+// the control frames already received, so the pongs are not missed. Pongs queued
+// behind unread data frames still wait for a reader.
+// Incoming pings are always answered when the transport supports control frames.
 type HeartbeatConnection struct {
 	interfaces.TransportConnection
 	stopHeartbeat chan struct{}
@@ -16,12 +39,36 @@ type HeartbeatConnection struct {
 	writeMu       sync.Mutex
 	readMu        sync.Mutex
 	interval      time.Duration
+
+	// Ping/Pong state
+	control     interfaces.ControlConnection
+	maxMissed   atomic.Int32 // 0 = ping/pong disabled
+	pingSeq     atomic.Uint64
+	pongSeq     atomic.Uint64
+	missedPongs atomic.Int32
+	lastRTT     atomic.Int64
+	smoothedRTT atomic.Int64
+
+	metrics atomic.Pointer[metrics.Socket]
+	tracer  tracing.Tracer
+
+	// Introspection (ConnInfo)
+	profile          interfaces.SocketProfile
+	bytesSent        atomic.Uint64
+	bytesReceived    atomic.Uint64
+	messagesSent     atomic.Uint64
+	messagesReceived atomic.Uint64
+	lastActivity     atomic.Int64
 }
 
 func NewHeartbeatConnection(conn interfaces.TransportConnection, interval time.Duration) *HeartbeatConnection {
 	h := &HeartbeatConnection{
 		TransportConnection: conn,
 		interval:            interval,
+		control:             transports.ControlOf(conn),
+	}
+	if h.control != nil {
+		h.control.SetControlHandler(h.handleControl)
 	}
 	if interval > 0 {
 		h.stopHeartbeat = make(chan struct{})
@@ -30,41 +77,243 @@ func NewHeartbeatConnection(conn interfaces.TransportConnection, interval time.D
 	return h
 }
 
+// -----------------------------------------------------------------------------
+
+// EnablePingPong switches heartbeats to ping/pong mode. The connection is closed
+// once maxMissed consecutive pings went unanswered (DefaultMaxMissedPongs if <= 0).
+func (h *HeartbeatConnection) EnablePingPong(maxMissed int) error {
+	if h.control == nil || h.control.FrameVersion() == transports.FrameVersionLegacy {
+		return ErrControlUnsupported
+	}
+	if maxMissed <= 0 {
+		maxMissed = DefaultMaxMissedPongs
+	}
+	h.maxMissed.Store(int32(maxMissed))
+	return nil
+}
+
+// LastRTT returns the round-trip time measured by the most recent pong (0 if none yet).
+func (h *HeartbeatConnection) LastRTT() time.Duration {
+	return time.Duration(h.lastRTT.Load())
+}
+
+// SmoothedRTT returns the exponentially weighted RTT average (alpha = 1/8, as TCP's SRTT).
+func (h *HeartbeatConnection) SmoothedRTT() time.Duration {
+	return time.Duration(h.smoothedRTT.Load())
+}
+
+// MissedPongs returns the number of consecutive pings left unanswered so far.
+func (h *HeartbeatConnection) MissedPongs() int {
+	return int(h.missedPongs.Load())
+}
+
+// SetMetrics makes the connection record its traffic and heartbeats into m.
+func (h *HeartbeatConnection) SetMetrics(m *metrics.Socket) {
+	h.metrics.Store(m)
+}
+
+// SetTracer sets the tracer of WriteContext and ReadMessageContext. Call it before
+// the connection is used.
+func (h *HeartbeatConnection) SetTracer(t tracing.Tracer) {
+	h.tracer = t
+}
+
+// -----------------------------------------------------------------------------
+
 func (h *HeartbeatConnection) start(interval time.Duration, stopChan chan struct{}) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			//nolint:staticcheck // QF1008: Explicit selector preferred for clarity and future-proofing
-			_, err := h.Write([]byte{})
+			var err error
+			if h.maxMissed.Load() > 0 {
+				err = h.ping()
+			} else {
+				//nolint:staticcheck // QF1008: Explicit selector preferred for clarity and future-proofing
+				_, err = h.Write([]byte{})
+			}
 			if err != nil {
 				// FAIL-FAST: Close the connection if heartbeat fails.
 				_ = h.TransportConnection.Close()
 				return
 			}
+			h.metrics.Load().HeartbeatSent()
 		case <-stopChan:
 			return
 		}
 	}
 }
 
+// ping accounts for the previous ping (answered or not) and sends the next one.
+// Ping payload: [Seq(8)] [SentAt UnixNano(8)].
+func (h *HeartbeatConnection) ping() error {
+	if sent := h.pingSeq.Load(); sent > 0 && h.pongSeq.Load() != sent {
+		_ = h.control.PumpControl() // Nobody may be reading: the reader reports its errors
+	}
+	if sent := h.pingSeq.Load(); sent > 0 && h.pongSeq.Load() != sent {
+		if h.missedPongs.Add(1) >= h.maxMissed.Load() {
+			return errors.New("peer stopped answering pings")
+		}
+	}
+
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint64(payload[0:8], h.pingSeq.Add(1))
+	binary.BigEndian.PutUint64(payload[8:16], uint64(time.Now().UnixNano()))
+
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	if err := h.control.WriteControl(interfaces.FramePing, payload); err != nil {
+		return err
+	}
+	h.lastActivity.Store(time.Now().UnixNano())
+	return nil
+}
+
+// handleControl runs in the read path: it answers pings and records pong RTTs.
+func (h *HeartbeatConnection) handleControl(frameType interfaces.FrameType, payload []byte) {
+	switch frameType {
+	case interfaces.FramePing:
+		h.writeMu.Lock()
+		_ = h.control.WriteControl(interfaces.FramePong, payload)
+		h.writeMu.Unlock()
+
+	case interfaces.FramePong:
+		if len(payload) < 16 {
+			return
+		}
+		seq := binary.BigEndian.Uint64(payload[0:8])
+		sentAt := int64(binary.BigEndian.Uint64(payload[8:16]))
+		rtt := time.Now().UnixNano() - sentAt
+
+		h.pongSeq.Store(seq)
+		h.missedPongs.Store(0)
+		h.lastRTT.Store(rtt)
+		if srtt := h.smoothedRTT.Load(); srtt == 0 {
+			h.smoothedRTT.Store(rtt)
+		} else {
+			h.smoothedRTT.Store((7*srtt + rtt) / 8)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Write, Read and ReadMessage also record the application traffic (SetMetrics):
+// the heartbeat layer is the outermost wrapper of every connection.
 func (h *HeartbeatConnection) Write(p []byte) (n int, err error) {
 	h.writeMu.Lock()
 	defer h.writeMu.Unlock()
-	return h.TransportConnection.Write(p)
+	n, err = h.TransportConnection.Write(p)
+	if err == nil {
+		h.sent(len(p), n)
+	}
+	return n, err
 }
 
 func (h *HeartbeatConnection) Read(p []byte) (n int, err error) {
 	h.readMu.Lock()
 	defer h.readMu.Unlock()
-	return h.TransportConnection.Read(p)
+	n, err = h.TransportConnection.Read(p)
+	if err == nil {
+		h.received(n)
+	}
+	return n, err
 }
 
 func (h *HeartbeatConnection) ReadMessage() ([]byte, error) {
 	h.readMu.Lock()
 	defer h.readMu.Unlock()
-	return h.TransportConnection.ReadMessage()
+	msg, err := h.TransportConnection.ReadMessage()
+	if err == nil {
+		h.received(len(msg))
+	}
+	return msg, err
+}
+
+// sent records a successful write of p bytes (n written); empty writes are heartbeats.
+func (h *HeartbeatConnection) sent(p, n int) {
+	h.lastActivity.Store(time.Now().UnixNano())
+	if p > 0 {
+		h.metrics.Load().Sent(n)
+		h.messagesSent.Add(1)
+		h.bytesSent.Add(uint64(n))
+	}
+}
+
+// received records a successful read of n bytes.
+func (h *HeartbeatConnection) received(n int) {
+	h.lastActivity.Store(time.Now().UnixNano())
+	h.metrics.Load().Received(n)
+	h.messagesReceived.Add(1)
+	h.bytesReceived.Add(uint64(n))
+}
+
+// -----------------------------------------------------------------------------
+
+// WriteContext writes p as one message within a tracing.SpanSend span, child of the
+// span in ctx. When the tracer propagates traces and the peer agreed on it during the
+// Hello handshake, the trace context travels in the frame header.
+func (h *HeartbeatConnection) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	ctx, span := tracing.Start(h.tracer, ctx, tracing.SpanSend, tracing.Int("bytes", len(p)))
+	defer func() { span.End(err) }()
+
+	tc := tracing.Inject(h.tracer, ctx)
+	carrier := h.traceCarrier()
+	if len(tc) == 0 || len(tc) > transports.MaxTraceContextSize || carrier == nil {
+		return h.Write(p)
+	}
+	span.SetAttributes(tracing.Bool("propagated", true))
+
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	n, err = carrier.WriteTraced(p, tc)
+	if err == nil {
+		h.sent(len(p), n)
+	}
+	return n, err
+}
+
+// ReadMessageContext reads one message and records it in a tracing.SpanReceive span,
+// started once the message arrived. The span continues the sender's trace when its
+// context came along (see WriteContext), and is a child of ctx otherwise. The returned
+// context holds the span, for the processing of the message.
+func (h *HeartbeatConnection) ReadMessageContext(ctx context.Context) (context.Context, []byte, error) {
+	var msg, tc []byte
+	var err error
+	if carrier := h.traceCarrier(); carrier != nil {
+		h.readMu.Lock()
+		msg, tc, err = carrier.ReadMessageTraced()
+		h.readMu.Unlock()
+		if err == nil {
+			h.received(len(msg))
+		}
+	} else {
+		msg, err = h.ReadMessage()
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = tracing.Extract(h.tracer, ctx, tc)
+	ctx, span := tracing.Start(h.tracer, ctx, tracing.SpanReceive, tracing.Int("bytes", len(msg)))
+	if tc != nil {
+		span.SetAttributes(tracing.Bool("propagated", true))
+	}
+	span.End(err)
+	return ctx, msg, err
+}
+
+// traceCarrier returns the transport carrying trace contexts, once negotiated.
+func (h *HeartbeatConnection) traceCarrier() interfaces.TraceContextConnection {
+	if h.tracer == nil {
+		return nil
+	}
+	carrier := transports.TraceContextOf(h.TransportConnection)
+	if carrier == nil || !carrier.TraceContextEnabled() {
+		return nil
+	}
+	return carrier
 }
 
 func (h *HeartbeatConnection) Close() error {
@@ -75,10 +324,38 @@ func (h *HeartbeatConnection) Close() error {
 			h.stopHeartbeat = nil
 		}
 		h.mu.Unlock()
+		h.metrics.Load().ConnClosed()
 	})
 	return h.TransportConnection.Close()
 }
 
+// Unwrap returns the wrapped connection.
+func (h *HeartbeatConnection) Unwrap() interfaces.TransportConnection {
+	return h.TransportConnection
+}
+
+// ConnInfo completes the description of the wrapped connection with the profile,
+// the heartbeat interval and the traffic of this connection.
+func (h *HeartbeatConnection) ConnInfo() interfaces.ConnInfo {
+	info := h.TransportConnection.ConnInfo().WithLayer("heartbeat")
+	if h.profile != nil {
+		info.Profile = h.profile.GetName()
+		info.Protocol = h.profile.GetProtocol()
+	}
+	h.mu.Lock()
+	info.HeartbeatInterval = h.interval
+	h.mu.Unlock()
+
+	if last := h.lastActivity.Load(); last > 0 {
+		info.LastActivity = time.Unix(0, last)
+	}
+	info.BytesSent = h.bytesSent.Load()
+	info.BytesReceived = h.bytesReceived.Load()
+	info.MessagesSent = h.messagesSent.Load()
+	info.MessagesReceived = h.messagesReceived.Load()
+	return info
+}
+
 func (h *HeartbeatConnection) SetIdleTimeout(d time.Duration) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -0,0 +1,150 @@
+package facade
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+func framedPair(t *testing.T) (*transports.FramedTCPSocket, *transports.FramedTCPSocket) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := ln.Accept()
+		accepted <- conn
+	}()
+
+	clientConn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, server := transports.NewFramedTCPSocket(clientConn, 0), transports.NewFramedTCPSocket(<-accepted, 0)
+
+	// Ping/pong needs typed frames, normally agreed by the Hello handshake
+	_ = client.SetFrameVersion(transports.FrameVersionTyped)
+	_ = server.SetFrameVersion(transports.FrameVersionTyped)
+	return client, server
+}
+
+func shmPair(t *testing.T) (*transports.ShmTransport, *transports.ShmTransport) {
+	path := filepath.Join(t.TempDir(), "pingpong.shm")
+	ln, err := transports.ListenShm(path, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	conn, err := transports.ConnectShm(path, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, server := conn.(*transports.ShmTransport), accepted.(*transports.ShmTransport)
+	_ = client.SetFrameVersion(transports.FrameVersionTyped)
+	_ = server.SetFrameVersion(transports.FrameVersionTyped)
+	return client, server
+}
+
+func TestHeartbeatPingPong(t *testing.T) {
+	t.Run("RTT_Measured", func(t *testing.T) {
+		a, b := framedPair(t)
+		pinger := NewHeartbeatConnection(a, 50*time.Millisecond)
+		if err := pinger.EnablePingPong(3); err != nil {
+			t.Fatal(err)
+		}
+		echoer := NewHeartbeatConnection(b, 0)
+		defer func() { _ = pinger.Close() }()
+		defer func() { _ = echoer.Close() }()
+
+		// Both sides read: the echoer to answer pings, the pinger to process pongs.
+		go func() { _, _ = echoer.ReadMessage() }()
+		go func() { _, _ = pinger.ReadMessage() }()
+
+		time.Sleep(300 * time.Millisecond)
+
+		if pinger.LastRTT() <= 0 || pinger.SmoothedRTT() <= 0 {
+			t.Errorf("expected RTT samples, got last=%v smoothed=%v", pinger.LastRTT(), pinger.SmoothedRTT())
+		}
+		if missed := pinger.MissedPongs(); missed != 0 {
+			t.Errorf("expected no missed pongs, got %d", missed)
+		}
+	})
+
+	t.Run("Silent_Peer_Closed", func(t *testing.T) {
+		a, b := framedPair(t)
+		pinger := NewHeartbeatConnection(a, 50*time.Millisecond)
+		if err := pinger.EnablePingPong(2); err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = b.Close() }()
+
+		// The peer accepts bytes but never reads, so pings are never answered.
+		done := make(chan error, 1)
+		go func() {
+			_, err := pinger.ReadMessage()
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Error("expected read error after missed pongs")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("connection to a non-reading peer was not closed")
+		}
+	})
+
+	// A pinger that only writes (e.g. a broadcasting server) must not close a peer
+	// answering its pings: pongs are processed without an application read.
+	writeOnly := func(t *testing.T, a, b interfaces.TransportConnection) {
+		pinger := NewHeartbeatConnection(a, 50*time.Millisecond)
+		if err := pinger.EnablePingPong(2); err != nil {
+			t.Fatal(err)
+		}
+		echoer := NewHeartbeatConnection(b, 0)
+		defer func() { _ = pinger.Close() }()
+		defer func() { _ = echoer.Close() }()
+
+		go func() {
+			for {
+				if _, err := echoer.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for i := 0; i < 8; i++ {
+			if _, err := pinger.Write([]byte("update")); err != nil {
+				t.Fatalf("write %d: %v", i, err)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if missed := pinger.MissedPongs(); missed >= 2 {
+			t.Errorf("expected the pongs to be processed, got %d missed", missed)
+		}
+		if pinger.LastRTT() <= 0 {
+			t.Error("expected RTT samples without reads")
+		}
+	}
+	t.Run("WriteOnly_Peer_Kept", func(t *testing.T) {
+		a, b := framedPair(t)
+		writeOnly(t, a, b)
+	})
+	t.Run("WriteOnly_Peer_Kept_Shm", func(t *testing.T) {
+		a, b := shmPair(t)
+		writeOnly(t, a, b)
+	})
+}
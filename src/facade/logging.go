@@ -0,0 +1,42 @@
+package facade
+
+import (
+	"log/slog"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/logging"
+)
+
+// discardLogger is used by sockets with neither a log handler nor a Logger:
+// the library never writes to stdout or stderr by itself.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// socketLogger returns the structured logger of a socket. Events carry the profile
+// and role; handler (SocketConfig.LogHandler, SetLogHandler) wins over a legacy
+// Logger, which is adapted with logging.NewLoggerHandler (Info and above, as the
+// Logger got before the slog events).
+func socketLogger(handler slog.Handler, legacy interfaces.Logger, p interfaces.SocketProfile, role string) *slog.Logger {
+	if handler == nil {
+		if legacy == nil {
+			return discardLogger
+		}
+		handler = logging.NewLoggerHandler(legacy)
+	}
+	return slog.New(handler).With(
+		slog.String("transport", string(p.GetTransport())),
+		slog.String("protocol", string(p.GetProtocol())),
+		slog.String("address", p.GetAddress()),
+		slog.String("role", role),
+	)
+}
+
+// peerAttr returns the Hello name of the peer of conn (an empty, ignored attribute
+// when the profile has no identity).
+func peerAttr(conn interfaces.TransportConnection) slog.Attr {
+	hello := IdentityOf(conn)
+	if hello == nil {
+		return slog.Attr{}
+	}
+	name, _ := hello.FromName()
+	return slog.String("peer", name)
+}
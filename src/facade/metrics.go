@@ -0,0 +1,41 @@
+package facade
+
+import (
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// socketMetrics returns the counters of a new socket in the configured registry. The
+// socket registers them once opened or listening, and unregisters them on Close.
+func socketMetrics(p interfaces.SocketProfile, c models.SocketConfig, role string) *metrics.Socket {
+	registry := c.Metrics
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	return registry.NewSocket(metrics.Labels{
+		Profile:   p.GetName(),
+		Transport: string(p.GetTransport()),
+		Protocol:  string(p.GetProtocol()),
+		Role:      role,
+		Address:   p.GetAddress(),
+	})
+}
+
+// instrument hands m to every layer of the wrapper chain able to record into it
+// (heartbeat, reliability and transport layers), and counts the new connection.
+func instrument(conn interfaces.TransportConnection, m *metrics.Socket) {
+	m.ConnOpened()
+	for conn != nil {
+		if i, ok := conn.(metrics.Instrumented); ok {
+			i.SetMetrics(m)
+		}
+		u, ok := conn.(interface {
+			Unwrap() interfaces.TransportConnection
+		})
+		if !ok {
+			return
+		}
+		conn = u.Unwrap()
+	}
+}
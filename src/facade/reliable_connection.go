@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
 )
 
 // RUDP Header Constants
@@ -36,6 +38,7 @@ type ReliableConnection struct {
 
 	retryInterval time.Duration
 	maxRetries    int
+	metrics       atomic.Pointer[metrics.Socket]
 }
 
 type pendingPacket struct {
@@ -226,6 +229,9 @@ func (c *ReliableConnection) performRetries() {
 	for _, data := range toRetry {
 		_, _ = c.TransportConnection.Write(data)
 	}
+	if len(toRetry) > 0 {
+		c.metrics.Load().Retransmitted(len(toRetry))
+	}
 
 	// Periodic cleanup of receivedSeqs
 	c.mu.Lock()
@@ -245,3 +251,18 @@ func (c *ReliableConnection) Close() error {
 	})
 	return c.TransportConnection.Close()
 }
+
+// SetMetrics makes the connection record its retransmissions into m.
+func (c *ReliableConnection) SetMetrics(m *metrics.Socket) {
+	c.metrics.Store(m)
+}
+
+// Unwrap returns the wrapped connection.
+func (c *ReliableConnection) Unwrap() interfaces.TransportConnection {
+	return c.TransportConnection
+}
+
+// ConnInfo describes the wrapped connection.
+func (c *ReliableConnection) ConnInfo() interfaces.ConnInfo {
+	return c.TransportConnection.ConnInfo().WithLayer("reliable")
+}
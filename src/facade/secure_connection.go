@@ -0,0 +1,155 @@
+package facade
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
+)
+
+// SecureConnection wraps a TransportConnection of a *-secure profile.
+// Every non-empty Write is sealed into one frame (datagram on UDP) and every
+// frame read is authenticated and decrypted. Empty writes (heartbeats) pass through.
+type SecureConnection struct {
+	Conn    interfaces.TransportConnection
+	Channel protocols.SecureChannel
+
+	writeMu sync.Mutex // Counter nonces: frames must hit the wire in sealing order
+	readMu  sync.Mutex
+}
+
+// -----------------------------------------------------------------------------
+
+func NewSecureConnection(conn interfaces.TransportConnection, channel protocols.SecureChannel) *SecureConnection {
+	return &SecureConnection{
+		Conn:    conn,
+		Channel: channel,
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// PeerKey returns the authenticated static public key of the peer.
+func (s *SecureConnection) PeerKey() []byte {
+	return s.Channel.PeerKey()
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return s.Conn.Write(p)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	sealed, err := s.Channel.Seal(p)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.Conn.Write(sealed); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) Read(p []byte) (n int, err error) {
+	msg, err := s.ReadMessage()
+	if err != nil {
+		return 0, err
+	}
+	if len(msg) > len(p) {
+		return 0, errors.New("short buffer")
+	}
+	return copy(p, msg), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) ReadMessage() ([]byte, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	sealed, err := s.Conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) == 0 {
+		return sealed, nil // Heartbeat datagram (UDP)
+	}
+	return s.Channel.Open(sealed)
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) Close() error {
+	return s.Conn.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) LocalAddr() net.Addr {
+	return s.Conn.LocalAddr()
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) RemoteAddr() net.Addr {
+	return s.Conn.RemoteAddr()
+}
+
+// ConnInfo adds the authenticated peer key to the description of the wrapped connection.
+func (s *SecureConnection) ConnInfo() interfaces.ConnInfo {
+	info := s.Conn.ConnInfo().WithLayer("secure")
+	info.PeerKey = s.PeerKey()
+	return info
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *SecureConnection) SetDeadline(t time.Time) error {
+	return s.Conn.SetDeadline(t)
+}
+
+func (s *SecureConnection) SetReadDeadline(t time.Time) error {
+	return s.Conn.SetReadDeadline(t)
+}
+
+func (s *SecureConnection) SetWriteDeadline(t time.Time) error {
+	return s.Conn.SetWriteDeadline(t)
+}
+
+func (s *SecureConnection) SetIdleTimeout(d time.Duration) error {
+	return s.Conn.SetIdleTimeout(d)
+}
+
+// Unwrap returns the wrapped connection.
+func (s *SecureConnection) Unwrap() interfaces.TransportConnection {
+	return s.Conn
+}
+
+// -----------------------------------------------------------------------------
+
+// PeerKeyOf returns the authenticated static key of the peer of a *-secure connection
+// (wrapped or not), or nil on other profiles.
+func PeerKeyOf(conn interfaces.TransportConnection) []byte {
+	for conn != nil {
+		if sc, ok := conn.(*SecureConnection); ok {
+			return sc.PeerKey()
+		}
+		u, ok := conn.(interface {
+			Unwrap() interfaces.TransportConnection
+		})
+		if !ok {
+			return nil
+		}
+		conn = u.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package facade
+
+import (
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// shmOptions maps the SHM settings of a SocketConfig onto the transport options.
+func shmOptions(c models.SocketConfig) transports.ShmOptions {
+	return transports.ShmOptions{
+		Mode:  c.ShmMode,
+		Owner: c.ShmOwner,
+		Group: c.ShmGroup,
+		Dir:   c.ShmDir,
+		Memfd: c.ShmMemfd,
+	}
+}
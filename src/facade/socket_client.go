@@ -1,15 +1,20 @@
 package facade
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
 	"github.com/Bastien-Antigravity/safe-socket/src/models"
 	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
 	"github.com/Bastien-Antigravity/safe-socket/src/transports"
 	"sync"
 )
@@ -23,6 +28,7 @@ type SocketClient struct {
 	transport interfaces.TransportConnection
 	Logger    interfaces.Logger
 	mu        sync.RWMutex
+	metrics   *metrics.Socket
 }
 
 // -----------------------------------------------------------------------------
@@ -31,6 +37,7 @@ func NewSocketClient(p interfaces.SocketProfile, c models.SocketConfig) *SocketC
 	return &SocketClient{
 		Profile: p,
 		Config:  c,
+		metrics: socketMetrics(p, c, "client"),
 	}
 }
 
@@ -39,6 +46,12 @@ func NewSocketClient(p interfaces.SocketProfile, c models.SocketConfig) *SocketC
 // Open establishes the connection using the configured transport and protocol.
 // If MaxRetries > 0, it will attempt reconnection on failure.
 func (c *SocketClient) Open() error {
+	return c.OpenContext(context.Background())
+}
+
+// OpenContext is Open, recording each attempt in a tracing.SpanConnect span child of
+// ctx. Retries stop once ctx is done.
+func (c *SocketClient) OpenContext(ctx context.Context) (err error) {
 	c.mu.Lock()
 	if c.transport != nil {
 		c.mu.Unlock()
@@ -46,6 +59,14 @@ func (c *SocketClient) Open() error {
 	}
 	c.mu.Unlock()
 
+	// Retries are exported while they last; a socket that failed to open is not
+	c.metrics.Register()
+	defer func() {
+		if err != nil {
+			c.metrics.Unregister()
+		}
+	}()
+
 	retries := 0
 	currentInterval := c.Config.RetryInterval
 	if currentInterval <= 0 {
@@ -53,23 +74,36 @@ func (c *SocketClient) Open() error {
 	}
 
 	for {
-		err := c.attemptOpen()
+		err := c.attemptOpen(ctx)
 		if err == nil {
 			return nil
 		}
 
+		// A refusal is a verdict, not a transient failure: retrying would not change it
+		// (nor would it make a server predating the Hello reply answer)
+		if errors.Is(err, protocols.ErrHandshakeRejected) || errors.Is(err, protocols.ErrNoHelloReply) {
+			return fmt.Errorf("failed to open socket: %w", err)
+		}
+
 		// Check if we should retry
 		if c.Config.MaxRetries == 0 || (c.Config.MaxRetries > 0 && retries >= c.Config.MaxRetries) {
 			return fmt.Errorf("failed to open socket after %d attempts: %w", retries+1, err)
 		}
 
 		retries++
-		if c.Logger != nil {
-			c.Logger.Warning(fmt.Sprintf("Socket open failed: %v. Retrying in %v (Attempt %d/%d)...", err, currentInterval, retries, c.Config.MaxRetries))
+		c.metrics.OpenRetried()
+		c.logger().Warn("socket open failed, retrying",
+			slog.Any("error", err),
+			slog.Duration("retry_in", currentInterval),
+			slog.Int("attempt", retries),
+			slog.Int("max_retries", c.Config.MaxRetries))
+
+		select {
+		case <-time.After(currentInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("failed to open socket after %d attempts: %w", retries, errors.Join(ctx.Err(), err))
 		}
 
-		time.Sleep(currentInterval)
-
 		// Exponential Backoff with Jitter (FEAT-005)
 		// Max interval capped at 30s
 		nextInterval := float64(currentInterval) * 1.5
@@ -82,10 +116,15 @@ func (c *SocketClient) Open() error {
 	}
 }
 
-func (c *SocketClient) attemptOpen() error {
+func (c *SocketClient) attemptOpen(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(c.Config.Tracer, ctx, tracing.SpanConnect,
+		tracing.String("transport", string(c.Profile.GetTransport())),
+		tracing.String("protocol", string(c.Profile.GetProtocol())),
+		tracing.String("address", c.Profile.GetAddress()))
+	defer func() { span.End(err) }()
+
 	// 1. Create Transport & Connect
 	var conn interfaces.TransportConnection
-	var err error
 
 	// Connect Timeout (Dial)
 	connectTimeout := time.Duration(c.Profile.GetConnectTimeout()) * time.Millisecond
@@ -102,13 +141,15 @@ func (c *SocketClient) attemptOpen() error {
 
 	switch c.Profile.GetTransport() {
 	case interfaces.TransportTLS:
-		conn, err = transports.ConnectTLS(c.Profile.GetAddress(), idleTimeout, c.Config.CertFile, c.Config.KeyFile, c.Config.CAFile, c.Config.ServerName, c.Config.InsecureSkipVerify)
+		conn, err = transports.ConnectTLSWithOptions(c.Profile.GetAddress(), idleTimeout, tlsOptions(c.Config))
 	case interfaces.TransportFramedTCP:
 		conn, err = transports.Connect(c.Profile.GetAddress(), idleTimeout)
 	case interfaces.TransportShm:
-		conn, err = transports.ConnectShm(c.Profile.GetName(), idleTimeout)
+		conn, err = transports.ConnectShmWithOptions(c.Profile.GetName(), idleTimeout, shmOptions(c.Config))
 	case interfaces.TransportUDP:
 		conn, err = transports.ConnectUDP(c.Profile.GetAddress(), idleTimeout)
+	case interfaces.TransportMem:
+		conn, err = transports.ConnectMem(c.Profile.GetAddress(), idleTimeout)
 	default:
 		return errors.New("unsupported transport type")
 	}
@@ -116,6 +157,11 @@ func (c *SocketClient) attemptOpen() error {
 	if err != nil {
 		return err
 	}
+	if gc, ok := conn.(interfaces.GracefulCloser); ok {
+		gc.SetCloseAckTimeout(c.Config.CloseAckTimeout)
+	}
+	conn = withChaos(conn, c.Config)
+	conn = withCapture(conn, c.Profile, c.Config, "client")
 
 	// 1b. Apply Reliability Layer if requested (UDP only)
 	if c.Config.Reliable && c.Profile.GetTransport() == interfaces.TransportUDP {
@@ -126,12 +172,33 @@ func (c *SocketClient) attemptOpen() error {
 	if c.Profile.GetTransport() == interfaces.TransportUDP &&
 		c.Profile.GetProtocol() == interfaces.ProtocolHello {
 		conn = NewEnvelopedConnection(conn, c.Profile, c.Config)
+	} else if c.Profile.GetProtocol() == interfaces.ProtocolSecure {
+		// Noise handshake (datagram sealing on UDP), then encrypted frames
+		var channel protocols.SecureChannel
+		if c.Profile.GetTransport() == interfaces.TransportUDP {
+			channel, err = protocols.NewDatagramChannel(c.Config, true, protocols.NewReplayGuard(c.Config.EnvelopeMaxAge))
+		} else {
+			channel, err = protocols.SecureHandshake(conn, c.Config, true)
+		}
+		if err != nil {
+			c.metrics.HandshakeFailed()
+			_ = conn.Close()
+			return err
+		}
+		conn = NewSecureConnection(conn, channel)
 	} else if c.Profile.GetProtocol() != "" && c.Profile.GetProtocol() != interfaces.ProtocolNone {
-		proto := protocols.NewHelloProtocol()
-		if err := proto.Initiate(conn, c.Profile, c.Config); err != nil {
+		proto := &protocols.HelloProtocol{Tracer: c.Config.Tracer}
+		serverHello, err := proto.InitiateContext(ctx, conn, c.Profile, c.Config)
+		if err != nil {
+			c.metrics.HandshakeFailed()
 			_ = conn.Close()
 			return err
 		}
+
+		// Keep the server identity reachable (GetIdentity)
+		if serverHello != nil {
+			conn = NewHandshakeConnection(conn, serverHello)
+		}
 	}
 
 	// 3. Heartbeat Optimization & Safety Ratio
@@ -141,20 +208,21 @@ func (c *SocketClient) attemptOpen() error {
 	} else if idleTimeout > 0 && float64(heartbeatInterval)*2.5 > float64(idleTimeout) {
 		// If user provided an unsafe heartbeat (too close to deadline), adjust it
 		newHeartbeat := time.Duration(float64(idleTimeout) / 2.5)
-		if c.Logger != nil {
-			c.Logger.Warning(fmt.Sprintf("User HeartbeatInterval (%v) is too close to IdleTimeout (%v). Adjusting to safety ratio: %v",
-				heartbeatInterval, idleTimeout, newHeartbeat))
-		}
+		c.logger().Warn("heartbeat interval too close to the idle timeout, adjusted to the safety ratio",
+			slog.Duration("heartbeat_interval", heartbeatInterval),
+			slog.Duration("idle_timeout", idleTimeout),
+			slog.Duration("adjusted_interval", newHeartbeat))
 		heartbeatInterval = newHeartbeat
 	}
 
-	// Threshold Check (Network: 300ms, Local: 150ms, SHM: 50ms)
+	// Threshold Check (Network: 300ms, Local: 150ms, SHM and mem: 50ms)
 	threshold := 300 * time.Millisecond
 	addr := c.Profile.GetAddress()
 	isLocal := strings.Contains(addr, "127.0.0.1") || strings.Contains(addr, "localhost")
 	isShm := c.Profile.GetTransport() == interfaces.TransportShm
+	isMem := c.Profile.GetTransport() == interfaces.TransportMem
 
-	if isShm {
+	if isShm || isMem {
 		threshold = 50 * time.Millisecond
 	} else if isLocal {
 		threshold = 150 * time.Millisecond
@@ -164,14 +232,103 @@ func (c *SocketClient) attemptOpen() error {
 		heartbeatInterval = 0 // Disabled
 	}
 
+	hb := NewHeartbeatConnection(conn, heartbeatInterval)
+	if c.Config.HeartbeatPingPong {
+		if err := hb.EnablePingPong(c.Config.MaxMissedPongs); err != nil {
+			c.logger().Warn("ping/pong heartbeat unavailable, falling back to one-way heartbeats", slog.Any("error", err))
+		}
+	}
+	instrument(hb, c.metrics)
+	hb.SetTracer(c.Config.Tracer)
+	hb.profile = c.Profile
+	c.logger().Debug("socket open",
+		peerAttr(hb),
+		slog.Duration("idle_timeout", idleTimeout),
+		slog.Duration("heartbeat_interval", heartbeatInterval))
+
 	c.mu.Lock()
-	c.transport = NewHeartbeatConnection(conn, heartbeatInterval)
+	c.transport = hb
 	c.mu.Unlock()
 	return nil
 }
 
 // -----------------------------------------------------------------------------
 
+// Stats returns a snapshot of the activity recorded for this socket (see metrics.Stats).
+func (c *SocketClient) Stats() metrics.Stats {
+	return c.metrics.Stats()
+}
+
+// ConnInfo describes the open connection (see interfaces.ConnInfo), or returns the
+// zero value if the socket is not open.
+func (c *SocketClient) ConnInfo() interfaces.ConnInfo {
+	c.mu.RLock()
+	tr := c.transport
+	c.mu.RUnlock()
+
+	if tr == nil {
+		return interfaces.ConnInfo{}
+	}
+	return tr.ConnInfo()
+}
+
+// -----------------------------------------------------------------------------
+
+// Heartbeat returns the heartbeat layer of the open connection (RTT and missed-pong
+// statistics in ping/pong mode), or nil if the socket is not open.
+func (c *SocketClient) Heartbeat() *HeartbeatConnection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hb, _ := c.transport.(*HeartbeatConnection)
+	return hb
+}
+
+// -----------------------------------------------------------------------------
+
+// GetIdentity returns the server identity received in the Hello reply, or nil if the
+// socket is not open or the profile/server does not exchange identities.
+func (c *SocketClient) GetIdentity() *schemas.HelloMsg {
+	c.mu.RLock()
+	tr := c.transport
+	c.mu.RUnlock()
+
+	if tr == nil {
+		return nil
+	}
+	return IdentityOf(tr)
+}
+
+// -----------------------------------------------------------------------------
+
+// GetCertIdentity returns the verified certificate identity of the server (TLS
+// profiles), or nil if the socket is not open or the server was not verified.
+func (c *SocketClient) GetCertIdentity() *CertIdentity {
+	c.mu.RLock()
+	tr := c.transport
+	c.mu.RUnlock()
+
+	if tr == nil {
+		return nil
+	}
+	return CertIdentityOf(tr)
+}
+
+// -----------------------------------------------------------------------------
+
+// ZeroCopy returns the zero-copy API of an open shm or shm-hello connection, or nil
+// on other profiles (see transports.ZeroCopyConnection).
+func (c *SocketClient) ZeroCopy() transports.ZeroCopyConnection {
+	c.mu.RLock()
+	tr := c.transport
+	c.mu.RUnlock()
+
+	if tr == nil {
+		return nil
+	}
+	return ZeroCopyOf(tr)
+}
+
 // -----------------------------------------------------------------------------
 
 // Send writes the raw data to the transport.
@@ -187,6 +344,20 @@ func (c *SocketClient) Send(data []byte) error {
 	return err
 }
 
+// SendContext writes data within a send span child of ctx, carrying the trace
+// context to the server when both ends support it (see HeartbeatConnection.WriteContext).
+func (c *SocketClient) SendContext(ctx context.Context, data []byte) error {
+	c.mu.RLock()
+	tr := c.transport
+	c.mu.RUnlock()
+
+	if tr == nil {
+		return errors.New("socket not open")
+	}
+	_, err := WriteContext(ctx, tr, data)
+	return err
+}
+
 // Write implements the io.Writer interface in logger.
 func (c *SocketClient) Write(data []byte) (int, error) {
 	c.mu.RLock()
@@ -215,6 +386,20 @@ func (c *SocketClient) Receive() ([]byte, error) {
 	return tr.ReadMessage()
 }
 
+// ReceiveContext reads one message within a receive span and returns the context
+// holding it, which continues the server's trace when its context came along
+// (see HeartbeatConnection.ReadMessageContext).
+func (c *SocketClient) ReceiveContext(ctx context.Context) (context.Context, []byte, error) {
+	c.mu.RLock()
+	tr := c.transport
+	c.mu.RUnlock()
+
+	if tr == nil {
+		return ctx, nil, errors.New("socket not open")
+	}
+	return ReadMessageContext(ctx, tr)
+}
+
 // Read reads from the transport into the provided buffer (io.Reader compliance).
 func (c *SocketClient) Read(p []byte) (int, error) {
 	c.mu.RLock()
@@ -234,6 +419,7 @@ func (c *SocketClient) Close() error {
 	tr := c.transport
 	c.transport = nil
 	c.mu.Unlock()
+	c.metrics.Unregister()
 
 	if tr != nil {
 		return tr.Close()
@@ -241,6 +427,21 @@ func (c *SocketClient) Close() error {
 	return nil
 }
 
+// CloseWithReason closes the socket, announcing code and reason to the peer (see
+// CloseWithReason: plain tcp and tls profiles cannot announce it).
+func (c *SocketClient) CloseWithReason(code interfaces.CloseCode, reason string) error {
+	c.mu.Lock()
+	tr := c.transport
+	c.transport = nil
+	c.mu.Unlock()
+	c.metrics.Unregister()
+
+	if tr != nil {
+		return CloseWithReason(tr, code, reason)
+	}
+	return nil
+}
+
 // -----------------------------------------------------------------------------
 
 // SetDeadline sets the read and write deadlines associated with the connection.
@@ -305,6 +506,16 @@ func (c *SocketClient) SetLogger(logger interfaces.Logger) {
 	c.Logger = logger
 }
 
+// SetLogHandler routes the socket's structured log events to h (see SocketConfig.LogHandler).
+func (c *SocketClient) SetLogHandler(h slog.Handler) {
+	c.Config.LogHandler = h
+}
+
+// logger returns the structured logger of the socket (see socketLogger).
+func (c *SocketClient) logger() *slog.Logger {
+	return socketLogger(c.Config.LogHandler, c.Logger, c.Profile, "client")
+}
+
 // -----------------------------------------------------------------------------
 // Server Methods (Not Supported for Client)
 // -----------------------------------------------------------------------------
@@ -0,0 +1,255 @@
+package facade
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+	"weak"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+)
+
+// socketRegistry keeps track of the sockets created through the factory, for the debug
+// handler. It only holds weak pointers: a socket dropped by the application leaves the
+// registry once garbage collected, whether it was closed or not.
+type socketRegistry struct {
+	mu      sync.Mutex
+	nextID  uint64
+	entries []socketEntry
+	pruneAt int // Entry count at which Track forgets the collected sockets
+}
+
+type socketEntry struct {
+	id      uint64
+	created time.Time
+	socket  func() interfaces.Socket // nil once collected
+}
+
+var liveSockets socketRegistry
+
+// minPruneAt is the smallest registry size Track prunes at. Past it, Track prunes
+// whenever the registry doubled since the last pruning: amortized O(1) per socket.
+const minPruneAt = 64
+
+// -----------------------------------------------------------------------------
+
+// Track adds s to the sockets described by Sockets. The factory tracks every socket
+// it creates; sockets of other types are ignored.
+func Track(s interfaces.Socket) {
+	var resolve func() interfaces.Socket
+	switch v := s.(type) {
+	case *SocketClient:
+		resolve = weakSocket(v)
+	case *SocketServer:
+		resolve = weakSocket(v)
+	case *BroadcastSocket:
+		resolve = weakSocket(v)
+	default:
+		return
+	}
+
+	liveSockets.mu.Lock()
+	defer liveSockets.mu.Unlock()
+	liveSockets.nextID++
+	liveSockets.entries = append(liveSockets.entries, socketEntry{id: liveSockets.nextID, created: time.Now(), socket: resolve})
+	if len(liveSockets.entries) >= liveSockets.pruneAt {
+		liveSockets.prune()
+		liveSockets.pruneAt = max(2*len(liveSockets.entries), minPruneAt)
+	}
+}
+
+// prune forgets the collected sockets, keeping the order. r.mu must be held.
+func (r *socketRegistry) prune() {
+	kept := r.entries[:0]
+	for _, e := range r.entries {
+		if e.socket() != nil {
+			kept = append(kept, e)
+		}
+	}
+	clear(r.entries[len(kept):])
+	r.entries = kept
+}
+
+// weakSocket returns a resolver of p that does not keep it alive.
+func weakSocket[T any, P interface {
+	*T
+	interfaces.Socket
+}](p P) func() interfaces.Socket {
+	wp := weak.Make((*T)(p))
+	return func() interfaces.Socket {
+		if v := wp.Value(); v != nil {
+			return P(v)
+		}
+		return nil
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// SocketState is a point-in-time description of a socket, for debugging.
+type SocketState struct {
+	ID        uint64
+	Role      string // "client" or "server"
+	Profile   string
+	Transport interfaces.TransportType
+	Protocol  interfaces.ProtocolType
+	Address   string
+	State     string // "open", "listening" or "closed"
+	Created   time.Time
+
+	// Servers: connections in the handshake pipeline, and ready but not yet accepted
+	Handshaking int
+	AcceptQueue int
+
+	Stats       metrics.Stats
+	Connections []ConnState // Open connection (client) or accepted connections (server)
+}
+
+// ConnState is a point-in-time description of a connection (see interfaces.ConnInfo).
+type ConnState struct {
+	Layers        []string
+	LocalAddr     string
+	RemoteAddr    string
+	Identity      string // Hello name@host of the peer
+	Authenticated bool
+	TLS           string // Version and cipher suite
+	FrameVersion  uint8
+
+	HeartbeatInterval time.Duration
+	IdleTimeout       time.Duration
+	PingPong          bool
+	MissedPongs       int
+	LastRTT           time.Duration
+	SmoothedRTT       time.Duration
+	Unacked           int // UDP reliability layer: packets awaiting an ACK
+
+	OpenedAt         time.Time
+	LastActivity     time.Time
+	BytesSent        uint64
+	BytesReceived    uint64
+	MessagesSent     uint64
+	MessagesReceived uint64
+}
+
+// Sockets describes the tracked sockets still referenced by the application, oldest
+// first.
+func Sockets() []SocketState {
+	liveSockets.mu.Lock()
+	var live []interfaces.Socket
+	var kept []socketEntry
+	for _, e := range liveSockets.entries {
+		if s := e.socket(); s != nil {
+			live = append(live, s)
+			kept = append(kept, e)
+		}
+	}
+	liveSockets.entries = kept // Forget the collected ones
+	liveSockets.mu.Unlock()
+
+	states := make([]SocketState, 0, len(live))
+	for i, s := range live {
+		state := describeSocket(s)
+		state.ID, state.Created = kept[i].id, kept[i].created
+		states = append(states, state)
+	}
+	return states
+}
+
+// describeSocket snapshots one socket.
+func describeSocket(s interfaces.Socket) SocketState {
+	var state SocketState
+	var profile interfaces.SocketProfile
+	switch v := s.(type) {
+	case *SocketClient:
+		profile, state.Role, state.Stats = v.Profile, "client", v.Stats()
+		state.State = "closed"
+		v.mu.RLock()
+		conn := v.transport
+		v.mu.RUnlock()
+		if conn != nil {
+			state.State = "open"
+			state.Connections = []ConnState{describeConn(conn)}
+		}
+	case *SocketServer:
+		profile, state.Role, state.Stats = v.Profile, "server", v.Stats()
+		state.State = "closed"
+		v.mu.RLock()
+		if q := v.queue; q != nil {
+			state.State = "listening"
+			state.Handshaking, state.AcceptQueue = int(q.handshaking.Load()), int(q.waiting.Load())
+		}
+		v.mu.RUnlock()
+		for _, conn := range v.Connections() {
+			state.Connections = append(state.Connections, describeConn(conn))
+		}
+	case *BroadcastSocket:
+		profile, state.Stats = v.Profile, v.Stats()
+		state.Role = "client"
+		if v.Role == interfaces.SocketTypeServer {
+			state.Role = "server"
+		}
+		state.State = "closed"
+		v.mu.RLock()
+		if v.ring != nil {
+			state.State = "open"
+		}
+		v.mu.RUnlock()
+	}
+
+	state.Profile = profile.GetName()
+	state.Transport = profile.GetTransport()
+	state.Protocol = profile.GetProtocol()
+	state.Address = profile.GetAddress()
+	return state
+}
+
+// describeConn snapshots one connection: its ConnInfo, plus the heartbeat and
+// reliability state found along the wrapper chain.
+func describeConn(conn interfaces.TransportConnection) ConnState {
+	info := conn.ConnInfo()
+	state := ConnState{
+		Layers:            info.Layers,
+		LocalAddr:         addrString(info.LocalAddr),
+		RemoteAddr:        addrString(info.RemoteAddr),
+		Authenticated:     info.Authenticated,
+		FrameVersion:      info.FrameVersion,
+		HeartbeatInterval: info.HeartbeatInterval,
+		IdleTimeout:       info.IdleTimeout,
+		OpenedAt:          info.OpenedAt,
+		LastActivity:      info.LastActivity,
+		BytesSent:         info.BytesSent,
+		BytesReceived:     info.BytesReceived,
+		MessagesSent:      info.MessagesSent,
+		MessagesReceived:  info.MessagesReceived,
+	}
+	if info.Identity != nil {
+		name, _ := info.Identity.FromName()
+		host, _ := info.Identity.FromHost()
+		state.Identity = name + "@" + host
+	}
+	if info.TLS != nil {
+		state.TLS = tls.VersionName(info.TLS.Version) + " " + tls.CipherSuiteName(info.TLS.CipherSuite)
+	}
+
+	for c := conn; c != nil; {
+		switch v := c.(type) {
+		case *HeartbeatConnection:
+			state.PingPong = v.maxMissed.Load() > 0
+			state.MissedPongs = v.MissedPongs()
+			state.LastRTT, state.SmoothedRTT = v.LastRTT(), v.SmoothedRTT()
+		case *ReliableConnection:
+			v.mu.Lock()
+			state.Unacked = len(v.unacked)
+			v.mu.Unlock()
+		}
+		u, ok := c.(interface {
+			Unwrap() interfaces.TransportConnection
+		})
+		if !ok {
+			break
+		}
+		c = u.Unwrap()
+	}
+	return state
+}
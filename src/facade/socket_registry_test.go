@@ -0,0 +1,33 @@
+package facade
+
+import (
+	"runtime"
+	"testing"
+)
+
+// TestSocketRegistryPrunes verifies that sockets dropped by the application do not pile
+// up in the registry when Sockets is never called.
+func TestSocketRegistryPrunes(t *testing.T) {
+	kept := &SocketClient{}
+	Track(kept)
+	for i := 0; i < 100*minPruneAt; i++ {
+		Track(&SocketClient{})
+		if i%minPruneAt == 0 {
+			runtime.GC()
+		}
+	}
+
+	liveSockets.mu.Lock()
+	defer liveSockets.mu.Unlock()
+	if size := len(liveSockets.entries); size > 4*minPruneAt {
+		t.Errorf("expected collected sockets to be pruned, registry holds %d entries", size)
+	}
+	found := false
+	for _, e := range liveSockets.entries {
+		found = found || e.socket() == kept
+	}
+	if !found {
+		t.Error("a referenced socket was pruned")
+	}
+	runtime.KeepAlive(kept)
+}
@@ -1,22 +1,42 @@
 package facade
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"strings"
 	"time"
 
 	"sync"
+	"sync/atomic"
 
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
 	"github.com/Bastien-Antigravity/safe-socket/src/models"
 	"github.com/Bastien-Antigravity/safe-socket/src/protocols"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
 	"github.com/Bastien-Antigravity/safe-socket/src/transports"
 )
 
+// DefaultMaxPendingHandshakes is the number of connections a server handshakes at once
+// when SocketConfig.MaxPendingHandshakes is not set.
+const DefaultMaxPendingHandshakes = 64
+
+// ErrHandshakeTimeout is returned by Accept for a connection whose handshake did not
+// complete within the handshake timeout (see protocols.HandshakeTimeout).
+var ErrHandshakeTimeout = errors.New("handshake timed out")
+
+// -----------------------------------------------------------------------------
+
 // SocketServer implements the interfaces.Socket interface for Server-side operations.
 // It handles listening for connections (Listen) and accepting them (Accept).
-// It also executes the handshake protocol (if configured) during Accept.
+// Incoming connections are accepted and handshaked in the background as soon as the
+// server listens, so clients waiting for the Hello reply are not held up until the
+// application calls Accept; Accept hands out the connections ready for use. At most
+// MaxPendingHandshakes handshakes run at once, each bounded by the handshake timeout.
 // Client-side methods (Open, Send, Receive) will return errors, as the Server itself
 // does not send/receive data directly; the *accepted connection* does.
 type SocketServer struct {
@@ -26,6 +46,30 @@ type SocketServer struct {
 	Logger   interfaces.Logger
 	wg       sync.WaitGroup
 	mu       sync.RWMutex
+	registry connRegistry
+	queue    *acceptQueue
+	policy   interfaces.AdmissionPolicy
+	metrics  *metrics.Socket
+
+	replayGuard *protocols.ReplayGuard // Shared by udp-hello packets (authenticated envelopes)
+}
+
+// acceptResult is a connection that went through the accept pipeline (or its error).
+type acceptResult struct {
+	tracked *trackingConnection
+	conn    interfaces.TransportConnection
+	err     error
+}
+
+// acceptQueue hands connections from the background accept loop over to Accept.
+type acceptQueue struct {
+	ready   chan acceptResult
+	done    chan struct{} // closed by Close
+	drained chan struct{} // closed once the loop stopped and every result was taken
+	err     error         // listener error that stopped the loop (read after drained)
+
+	handshaking atomic.Int32 // Connections in the handshake pipeline
+	waiting     atomic.Int32 // Connections ready, waiting for Accept
 }
 
 // -----------------------------------------------------------------------------
@@ -33,8 +77,10 @@ type SocketServer struct {
 // NewSocketServer creates a new instance of SocketServer.
 func NewSocketServer(p interfaces.SocketProfile, config models.SocketConfig) *SocketServer {
 	return &SocketServer{
-		Profile: p,
-		Config:  config,
+		Profile:     p,
+		Config:      config,
+		replayGuard: protocols.NewReplayGuard(config.EnvelopeMaxAge),
+		metrics:     socketMetrics(p, config, "server"),
 	}
 }
 
@@ -49,6 +95,11 @@ func (s *SocketServer) Listen() error {
 		return errors.New("server already listening")
 	}
 
+	if s.Config.BindCertIdentity && (s.Profile.GetTransport() != interfaces.TransportTLS ||
+		s.Profile.GetProtocol() != interfaces.ProtocolHello) {
+		return errors.New("BindCertIdentity requires a tls-hello profile")
+	}
+
 	var ln interfaces.TransportListener
 	var err error
 
@@ -56,13 +107,15 @@ func (s *SocketServer) Listen() error {
 
 	switch s.Profile.GetTransport() {
 	case interfaces.TransportTLS:
-		ln, err = transports.ListenTLS(s.Profile.GetAddress(), timeout, s.Config.CertFile, s.Config.KeyFile, s.Config.CAFile)
+		ln, err = transports.ListenTLSWithOptions(s.Profile.GetAddress(), timeout, tlsOptions(s.Config))
 	case interfaces.TransportFramedTCP:
 		ln, err = transports.Listen(s.Profile.GetAddress(), timeout)
 	case interfaces.TransportUDP:
 		ln, err = transports.ListenUDP(s.Profile.GetAddress(), timeout)
 	case interfaces.TransportShm:
-		ln, err = transports.ListenShm(s.Profile.GetAddress(), timeout)
+		ln, err = transports.ListenShmWithOptions(s.Profile.GetAddress(), timeout, shmOptions(s.Config))
+	case interfaces.TransportMem:
+		ln, err = transports.ListenMem(s.Profile.GetAddress(), timeout)
 	default:
 		return errors.New("unsupported transport type for listening")
 	}
@@ -71,42 +124,206 @@ func (s *SocketServer) Listen() error {
 		return err
 	}
 
+	ln = withChaosListener(ln, s.Config)
 	s.listener = ln
+	s.metrics.Register()
+	s.queue = &acceptQueue{
+		ready:   make(chan acceptResult),
+		done:    make(chan struct{}),
+		drained: make(chan struct{}),
+	}
+	go s.acceptLoop(ln, s.queue)
 	return nil
 }
 
 // -----------------------------------------------------------------------------
 
-// Accept accepts a new connection and performs the handshake if defined.
+// acceptLoop accepts raw connections and runs each one through the handshake pipeline
+// concurrently, queueing the results for Accept. At most MaxPendingHandshakes run at
+// once, each within the handshake timeout. It stops on the first listener error
+// other than a timeout (closed listener, SHM single-client limit...), which Accept
+// reports once the connections still in flight have been handed out. Heartbeats
+// only start once Accept hands a connection out (see finish).
+func (s *SocketServer) acceptLoop(ln interfaces.TransportListener, q *acceptQueue) {
+	var inflight sync.WaitGroup
+	defer func() {
+		go func() {
+			inflight.Wait()
+			close(q.drained)
+		}()
+	}()
+
+	limit := s.Config.MaxPendingHandshakes
+	if limit <= 0 {
+		limit = DefaultMaxPendingHandshakes
+	}
+	slots := make(chan struct{}, limit)
+	handshakeTimeout := protocols.HandshakeTimeout(s.Profile, s.Config)
+	// UDP "connections" share the listener socket: closing one would close them all
+	bounded := s.Profile.GetTransport() != interfaces.TransportUDP
+
+	for {
+		// Wait for a handshake slot before taking the next connection off the backlog
+		select {
+		case slots <- struct{}{}:
+		case <-q.done:
+			return
+		}
+		conn, err := ln.Accept()
+		if err != nil {
+			// Idle listeners (UDP) time out: report it to Accept and keep listening
+			if isTimeout(err) {
+				<-slots
+				select {
+				case q.ready <- acceptResult{err: err}:
+					continue
+				case <-q.done:
+					return
+				}
+			}
+			q.err = err
+			return
+		}
+
+		// Count the connection for Close before handing it over, unless Close already
+		// started waiting
+		s.mu.RLock()
+		open := s.queue == q
+		if open {
+			s.wg.Add(1)
+		}
+		s.mu.RUnlock()
+		if !open {
+			<-slots
+			_ = conn.Close()
+			return
+		}
+
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+
+			// Abort a handshake still in flight when the server closes, or when it
+			// outlasts the handshake timeout
+			var expired atomic.Bool
+			finished := make(chan struct{})
+			go func() {
+				var timeout <-chan time.Time
+				if bounded {
+					timer := time.NewTimer(handshakeTimeout)
+					defer timer.Stop()
+					timeout = timer.C
+				}
+				select {
+				case <-q.done:
+					_ = conn.Close()
+				case <-timeout:
+					expired.Store(true)
+					_ = conn.Close()
+				case <-finished:
+				}
+			}()
+
+			q.handshaking.Add(1)
+			res := s.prepare(conn)
+			close(finished)
+			q.handshaking.Add(-1)
+			<-slots
+			if expired.Load() {
+				if res.conn != nil {
+					_ = res.conn.Close()
+					s.metrics.HandshakeFailed() // Failed handshakes were already counted
+				}
+				res = acceptResult{err: ErrHandshakeTimeout}
+			} else if bounded && isTimeout(res.err) {
+				// The handshake's own read deadline fired first
+				res.err = fmt.Errorf("%w: %w", ErrHandshakeTimeout, res.err)
+			}
+			if res.conn == nil && res.err == nil {
+				return // Refused by the admission policy (already logged)
+			}
+			q.waiting.Add(1)
+			defer q.waiting.Add(-1)
+			select {
+			case q.ready <- res:
+			case <-q.done:
+				if res.err == nil {
+					_ = res.conn.Close()
+				}
+			}
+		}()
+	}
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// -----------------------------------------------------------------------------
+
+// Accept returns the next connection that completed the handshake (if defined).
+// Handshake failures are returned as errors; callers usually just Accept again.
 func (s *SocketServer) Accept() (interfaces.TransportConnection, error) {
 	s.mu.RLock()
-	ln := s.listener
+	q := s.queue
 	s.mu.RUnlock()
 
-	if ln == nil {
+	if q == nil {
 		return nil, errors.New("server not listening")
 	}
 
-	// 1. Accept raw transport connection
-	conn, err := ln.Accept()
-	if err != nil {
-		return nil, err
+	select {
+	case res := <-q.ready:
+		if res.err != nil {
+			return nil, res.err
+		}
+		conn := s.finish(res)
+		s.registry.add(res.tracked, conn)
+		return conn, nil
+	case <-q.drained:
+		return nil, q.err
+	case <-q.done:
+		return nil, errors.New("server not listening")
 	}
+}
+
+// -----------------------------------------------------------------------------
 
-	// 1a. Track connection for synchronous shutdown
-	s.wg.Add(1)
-	conn = &trackingConnection{
-		TransportConnection: conn,
-		onClose:             s.wg.Done,
+// prepare runs a raw transport connection through tracking, deadlines, reliability
+// and the handshake, within a tracing.SpanAccept span. The heartbeat wrapper is left
+// to finish, so that no heartbeat starts before Accept hands the connection out.
+func (s *SocketServer) prepare(conn interfaces.TransportConnection) (res acceptResult) {
+	ctx, span := tracing.Start(s.Config.Tracer, context.Background(), tracing.SpanAccept,
+		tracing.String("transport", string(s.Profile.GetTransport())),
+		tracing.String("protocol", string(s.Profile.GetProtocol())),
+		tracing.Attr{Key: "remote", Value: conn.RemoteAddr()})
+	var rejected error
+	defer func() {
+		if res.err != nil {
+			span.End(res.err)
+			return
+		}
+		span.End(rejected)
+	}()
+
+	if gc := transports.CloserOf(conn); gc != nil {
+		gc.SetCloseAckTimeout(s.Config.CloseAckTimeout)
+	}
+	conn = withCapture(conn, s.Profile, s.Config, "server")
+
+	// 1a. Track connection for synchronous shutdown and the connection registry (the
+	// accept loop already counted it)
+	tracked := &trackingConnection{TransportConnection: conn}
+	tracked.onClose = func() {
+		s.registry.remove(tracked)
+		s.wg.Done()
 	}
+	conn = tracked
 
 	// 1b. Apply Server Config Deadline (Idle Timeout)
-	// If s.Config.Deadline is set (even to 0), we use it as the Idle Timeout.
-	idleTimeout := time.Duration(s.Profile.GetConnectTimeout()) * time.Millisecond
-	if s.Config.Deadline >= 0 {
-		idleTimeout = s.Config.Deadline
-	}
-	_ = conn.SetIdleTimeout(idleTimeout)
+	_ = conn.SetIdleTimeout(s.idleTimeout())
 
 	// 1c. Apply Reliability Layer if requested (UDP only)
 	if s.Config.Reliable && s.Profile.GetTransport() == interfaces.TransportUDP {
@@ -119,24 +336,91 @@ func (s *SocketServer) Accept() (interfaces.TransportConnection, error) {
 		s.Profile.GetProtocol() == interfaces.ProtocolHello {
 
 		// Wrap connection to handle Per-Packet Decapsulation
-		config := models.SocketConfig{} // Server doesn't usually use config for receiving, but wrapper needs it struct
-		conn = NewEnvelopedConnection(conn, s.Profile, config)
+		// Every packet is its own connection: replay windows live on the server.
+		ec := NewEnvelopedConnection(conn, s.Profile, s.Config)
+		ec.Guard = s.replayGuard
+		conn = ec
+
+	} else if s.Profile.GetProtocol() == interfaces.ProtocolSecure {
+		// Case B: Secure (Noise handshake, or datagram sealing on UDP)
+		var channel protocols.SecureChannel
+		var err error
+		if s.Profile.GetTransport() == interfaces.TransportUDP {
+			channel, err = protocols.NewDatagramChannel(s.Config, false, s.replayGuard)
+		} else {
+			channel, err = protocols.SecureHandshake(conn, s.Config, false)
+		}
+		if err != nil {
+			s.metrics.HandshakeFailed()
+			s.logger().Debug("secure handshake failed", slog.Any("remote", conn.RemoteAddr()), slog.Any("error", err))
+			_ = conn.Close()
+			return acceptResult{err: err}
+		}
+		conn = NewSecureConnection(conn, channel)
 
 	} else if s.Profile.GetProtocol() != "" && s.Profile.GetProtocol() != interfaces.ProtocolNone {
-		// Case B: Connection-Oriented (TCP) + Hello
+		// Case C: Connection-Oriented (TCP) + Hello
 		// Perform Standard Handshake (Wait for Client to send Hello)
-		proto := protocols.NewHelloProtocol()
+		proto := &protocols.HelloProtocol{Tracer: s.Config.Tracer}
 
 		// Note: The handshake itself will respect the Deadline set in 1b because it uses Read/Write on the conn.
-		helloMsg, err := proto.WaitInitiation(conn)
+		helloMsg, err := proto.WaitInitiationContext(ctx, conn)
 		if err != nil {
+			s.metrics.HandshakeFailed()
+			s.logger().Debug("hello handshake failed", slog.Any("remote", conn.RemoteAddr()), slog.Any("error", err))
 			_ = conn.Close()
-			return nil, err
+			return acceptResult{err: err}
+		}
+
+		// Authentication (keyring, client certificate), then Admission on the proven identity
+		verdict := proto.Authenticate(conn, helloMsg, s.Profile, s.Config)
+		if verdict == nil && s.Config.BindCertIdentity {
+			verdict = bindCertIdentity(helloMsg, CertIdentityOf(conn), s.Config.CertNameMap)
+		}
+		if verdict == nil {
+			verdict = s.admit(helloMsg, conn.RemoteAddr())
+		}
+
+		// Answer with our identity and the verdict (clients predating the reply get none)
+		if err := proto.Respond(conn, helloMsg, s.Profile, s.Config, verdict); err != nil {
+			s.metrics.HandshakeFailed()
+			_ = conn.Close()
+			return acceptResult{err: err}
+		}
+		if verdict != nil {
+			s.metrics.HandshakeFailed()
+			rejected = verdict
+			span.SetAttributes(tracing.Bool("rejected", true))
+			name, _ := helloMsg.FromName()
+			s.logger().Warn("peer rejected",
+				slog.String("peer", name),
+				slog.Any("remote", conn.RemoteAddr()),
+				slog.Any("reason", verdict))
+			_ = conn.Close()
+			return acceptResult{} // Dropped: Accept never sees refused peers
 		}
 
 		// Wrap with identity
-		conn = NewHandshakeConnection(conn, helloMsg)
+		hc := NewHandshakeConnection(conn, helloMsg)
+		hc.Authenticated = len(s.Config.AuthKeys) > 0 || s.Config.BindCertIdentity
+		conn = hc
+	}
+	return acceptResult{tracked: tracked, conn: conn}
+}
+
+// idleTimeout returns the idle timeout of accepted connections: Config.Deadline when
+// set (even to 0), the profile's connect timeout otherwise.
+func (s *SocketServer) idleTimeout() time.Duration {
+	if s.Config.Deadline >= 0 {
+		return s.Config.Deadline
 	}
+	return time.Duration(s.Profile.GetConnectTimeout()) * time.Millisecond
+}
+
+// finish wraps a handshaked connection with the heartbeat layer as Accept hands it out.
+func (s *SocketServer) finish(res acceptResult) interfaces.TransportConnection {
+	conn := res.conn
+	idleTimeout := s.idleTimeout()
 
 	// 3. Heartbeat Optimization & Safety Ratio
 	heartbeatInterval := s.Config.HeartbeatInterval
@@ -145,36 +429,88 @@ func (s *SocketServer) Accept() (interfaces.TransportConnection, error) {
 	} else if idleTimeout > 0 && float64(heartbeatInterval)*2.5 > float64(idleTimeout) {
 		// If user provided an unsafe heartbeat (too close to deadline), adjust it
 		newHeartbeat := time.Duration(float64(idleTimeout) / 2.5)
-		if s.Logger != nil {
-			s.Logger.Warning(fmt.Sprintf("User HeartbeatInterval (%v) is too close to IdleTimeout (%v). Adjusting to safety ratio: %v",
-				heartbeatInterval, idleTimeout, newHeartbeat))
-		}
+		s.logger().Warn("heartbeat interval too close to the idle timeout, adjusted to the safety ratio",
+			slog.Duration("heartbeat_interval", heartbeatInterval),
+			slog.Duration("idle_timeout", idleTimeout),
+			slog.Duration("adjusted_interval", newHeartbeat))
 		heartbeatInterval = newHeartbeat
 	}
 
-	// Threshold Check (Network: 300ms, Local: 150ms, SHM: 50ms)
+	// Threshold Check (Network: 300ms, Local: 150ms, SHM and mem: 50ms)
 	threshold := 300 * time.Millisecond // Default (Networking)
 	addr := s.Profile.GetAddress()
 	isLocal := strings.Contains(addr, "127.0.0.1") || strings.Contains(addr, "localhost")
 	isShm := s.Profile.GetTransport() == interfaces.TransportShm
+	isMem := s.Profile.GetTransport() == interfaces.TransportMem
 
 	transportName := "networking"
 	if isShm {
 		threshold = 50 * time.Millisecond
 		transportName = "shared memory"
+	} else if isMem {
+		threshold = 50 * time.Millisecond
+		transportName = "in-memory"
 	} else if isLocal {
 		threshold = 150 * time.Millisecond
 		transportName = "local"
 	}
 
 	if idleTimeout > 0 && idleTimeout < threshold {
-		if s.Logger != nil {
-			s.Logger.Info(fmt.Sprintf("Heartbeat disabled: IdleTimeout (%v) is below the threshold for %s transport.", idleTimeout, transportName))
+		s.logger().Info("heartbeat disabled: idle timeout below the threshold of the transport",
+			slog.Duration("idle_timeout", idleTimeout),
+			slog.Duration("threshold", threshold),
+			slog.String("transport_kind", transportName))
+		heartbeatInterval = 0
+	}
+
+	hb := NewHeartbeatConnection(conn, heartbeatInterval)
+	if s.Config.HeartbeatPingPong {
+		if err := hb.EnablePingPong(s.Config.MaxMissedPongs); err != nil {
+			s.logger().Warn("ping/pong heartbeat unavailable, falling back to one-way heartbeats",
+				slog.Any("remote", conn.RemoteAddr()), slog.Any("error", err))
 		}
-		return NewHeartbeatConnection(conn, 0), nil
 	}
+	instrument(hb, s.metrics)
+	hb.SetTracer(s.Config.Tracer)
+	hb.profile = s.Profile
+	s.logger().Debug("connection accepted",
+		slog.Any("remote", conn.RemoteAddr()),
+		peerAttr(hb),
+		slog.Duration("idle_timeout", idleTimeout),
+		slog.Duration("heartbeat_interval", heartbeatInterval))
+	return hb
+}
+
+// -----------------------------------------------------------------------------
 
-	return NewHeartbeatConnection(conn, heartbeatInterval), nil
+// Stats returns a snapshot of the activity recorded for this server and the
+// connections it accepted (see metrics.Stats).
+func (s *SocketServer) Stats() metrics.Stats {
+	return s.metrics.Stats()
+}
+
+// -----------------------------------------------------------------------------
+
+// SetAdmissionPolicy installs the policy deciding which peers may connect (nil = admit all).
+// It is evaluated after the Hello handshake of connection-oriented profiles; refused
+// peers are sent the reason (when their client supports the Hello reply), logged and
+// closed without ever being returned by Accept.
+func (s *SocketServer) SetAdmissionPolicy(policy interfaces.AdmissionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+// admit evaluates the admission policy, if any.
+func (s *SocketServer) admit(hello *schemas.HelloMsg, remote net.Addr) error {
+	s.mu.RLock()
+	policy := s.policy
+	s.mu.RUnlock()
+
+	if policy == nil {
+		return nil
+	}
+	return policy.Admit(hello, remote)
 }
 
 // -----------------------------------------------------------------------------
@@ -198,6 +534,10 @@ func (s *SocketServer) Close() error {
 	s.mu.Lock()
 	ln := s.listener
 	s.listener = nil
+	if s.queue != nil {
+		close(s.queue.done)
+		s.queue = nil
+	}
 	s.mu.Unlock()
 
 	if ln != nil {
@@ -205,6 +545,7 @@ func (s *SocketServer) Close() error {
 
 		// Wait for active connections to finish
 		s.wg.Wait()
+		s.metrics.Unregister()
 
 		return err
 	}
@@ -216,17 +557,29 @@ type trackingConnection struct {
 	interfaces.TransportConnection
 	onClose func()
 	once    sync.Once
+	closed  atomic.Bool
+}
+
+// ConnInfo describes the wrapped connection.
+func (c *trackingConnection) ConnInfo() interfaces.ConnInfo {
+	return c.TransportConnection.ConnInfo().WithLayer("tracking")
 }
 
 func (c *trackingConnection) Close() error {
 	var err error
 	c.once.Do(func() {
+		c.closed.Store(true)
 		err = c.TransportConnection.Close()
 		c.onClose()
 	})
 	return err
 }
 
+// Unwrap returns the wrapped connection.
+func (c *trackingConnection) Unwrap() interfaces.TransportConnection {
+	return c.TransportConnection
+}
+
 // -----------------------------------------------------------------------------
 
 // Bind logger to safe-socket
@@ -234,6 +587,16 @@ func (s *SocketServer) SetLogger(logger interfaces.Logger) {
 	s.Logger = logger
 }
 
+// SetLogHandler routes the server's structured log events to h (see SocketConfig.LogHandler).
+func (s *SocketServer) SetLogHandler(h slog.Handler) {
+	s.Config.LogHandler = h
+}
+
+// logger returns the structured logger of the server (see socketLogger).
+func (s *SocketServer) logger() *slog.Logger {
+	return socketLogger(s.Config.LogHandler, s.Logger, s.Profile, "server")
+}
+
 // -----------------------------------------------------------------------------
 // Client Methods (Not Supported for Server)
 // -----------------------------------------------------------------------------
@@ -0,0 +1,30 @@
+package facade
+
+import (
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// tlsOptions maps the TLS settings of a SocketConfig onto the transport options.
+func tlsOptions(c models.SocketConfig) transports.TLSOptions {
+	return transports.TLSOptions{
+		Config:             c.TLSConfig,
+		CertProvider:       c.CertProvider,
+		CertPEM:            c.CertPEM,
+		KeyPEM:             c.KeyPEM,
+		CertFile:           c.CertFile,
+		KeyFile:            c.KeyFile,
+		CAPEM:              c.CAPEM,
+		CAFile:             c.CAFile,
+		ReloadInterval:     c.TLSReloadInterval,
+		MinVersion:         c.TLSMinVersion,
+		MaxVersion:         c.TLSMaxVersion,
+		CipherSuites:       c.TLSCipherSuites,
+		CurvePreferences:   c.TLSCurvePreferences,
+		NextProtos:         c.ALPNProtocols,
+		ClientAuth:         c.TLSClientAuth,
+		PinnedSPKI:         c.PinnedSPKI,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+}
@@ -0,0 +1,27 @@
+package facade
+
+import (
+	"context"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// WriteContext writes p to a connection returned by Accept within a send span (see
+// HeartbeatConnection.WriteContext). Other connections are written to untraced.
+func WriteContext(ctx context.Context, conn interfaces.TransportConnection, p []byte) (int, error) {
+	if hb, ok := conn.(*HeartbeatConnection); ok {
+		return hb.WriteContext(ctx, p)
+	}
+	return conn.Write(p)
+}
+
+// ReadMessageContext reads one message from a connection returned by Accept within a
+// receive span (see HeartbeatConnection.ReadMessageContext). Other connections are
+// read untraced, returning ctx as is.
+func ReadMessageContext(ctx context.Context, conn interfaces.TransportConnection) (context.Context, []byte, error) {
+	if hb, ok := conn.(*HeartbeatConnection); ok {
+		return hb.ReadMessageContext(ctx)
+	}
+	msg, err := conn.ReadMessage()
+	return ctx, msg, err
+}
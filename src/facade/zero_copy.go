@@ -0,0 +1,28 @@
+package facade
+
+import (
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// ZeroCopyOf returns the zero-copy API of an SHM connection (wrapped or not), or nil
+// on other transports. *-secure connections return nil too: their ring only carries
+// ciphertext, so bypassing the encryption layer is never allowed.
+func ZeroCopyOf(conn interfaces.TransportConnection) transports.ZeroCopyConnection {
+	for conn != nil {
+		switch c := conn.(type) {
+		case *SecureConnection:
+			return nil
+		case transports.ZeroCopyConnection:
+			return c
+		}
+		u, ok := conn.(interface {
+			Unwrap() interfaces.TransportConnection
+		})
+		if !ok {
+			return nil
+		}
+		conn = u.Unwrap()
+	}
+	return nil
+}
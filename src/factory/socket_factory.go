@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Bastien-Antigravity/safe-socket/src/chaos"
 	"github.com/Bastien-Antigravity/safe-socket/src/facade"
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
 	"github.com/Bastien-Antigravity/safe-socket/src/models"
@@ -18,6 +19,8 @@ const (
 	DefaultLocalHandshakeTimeout = 200 // 0.2s
 	// DefaultShmHandshakeTimeout is used for Shared Memory (SHM)
 	DefaultShmHandshakeTimeout = 100 // 100ms
+	// DefaultMemHandshakeTimeout is used for the in-process mem transport
+	DefaultMemHandshakeTimeout = 100 // 100ms
 )
 
 // -----------------------------------------------------------------------------
@@ -44,7 +47,7 @@ func Create(profileName, address, publicIP string, socketType string, autoConnec
 // CreateWithConfig is the extended library entry point allowing full configuration.
 //
 // Parameters:
-//   - profileName: e.g. "tcp-hello"
+//   - profileName: e.g. "tcp-hello", or "tcp-hello+chaos" to inject faults (see chaos)
 //   - address: destination address
 //   - config: models.SocketConfig (allows setting Deadlines, PublicIP, etc.)
 //   - socketType: "client" or "server"
@@ -55,6 +58,14 @@ func CreateWithConfig(profileName, address string, config models.SocketConfig, s
 		return nil, err
 	}
 
+	// 0. Chaos testing (syntax: "profile+chaos[:identity]")
+	if name, ok := cutChaosSuffix(profileName); ok {
+		profileName = name
+		if config.Chaos == nil {
+			config.Chaos = chaos.Default
+		}
+	}
+
 	// 1. Determine Identity and Profile Key
 	profileKey := profileName
 	if parts := strings.Split(profileName, ":"); len(parts) > 1 {
@@ -70,6 +81,8 @@ func CreateWithConfig(profileName, address string, config models.SocketConfig, s
 
 		if strings.HasPrefix(profileKey, "shm") {
 			timeout = DefaultShmHandshakeTimeout
+		} else if strings.HasPrefix(profileKey, "mem") {
+			timeout = DefaultMemHandshakeTimeout
 		} else if isLocal {
 			timeout = DefaultLocalHandshakeTimeout
 		} else {
@@ -94,6 +107,19 @@ func CreateWithConfig(profileName, address string, config models.SocketConfig, s
 	return CreateSocket(p, config, socketType)
 }
 
+// cutChaosSuffix removes the "+chaos" suffix of a profile key, reporting whether it was there.
+func cutChaosSuffix(profileName string) (string, bool) {
+	key, identity, hasIdentity := strings.Cut(profileName, ":")
+	key, ok := strings.CutSuffix(key, "+chaos")
+	if !ok {
+		return profileName, false
+	}
+	if hasIdentity {
+		key += ":" + identity
+	}
+	return key, true
+}
+
 func createProfile(profileName, address string, st interfaces.SocketType, timeout int) (interfaces.SocketProfile, error) {
 	// 1. Support Compound Names (syntax: "profile:identity")
 	// If no colon is present, identity defaults to an internal fallback in the switch.
@@ -129,6 +155,15 @@ func createProfile(profileName, address string, st interfaces.SocketType, timeou
 		}
 		return profiles.NewTcpServerProfile(identity, address, timeout), nil
 
+	case "tcp-secure":
+		if identity == "" {
+			identity = "TcpSecure-Generic"
+		}
+		if st == interfaces.SocketTypeClient {
+			return profiles.NewTcpSecureClientProfile(identity, address, timeout), nil
+		}
+		return profiles.NewTcpSecureServerProfile(identity, address, timeout), nil
+
 	// TLS Support (Uses TCP transport with TLS config)
 	case "tls-hello":
 		if identity == "" {
@@ -163,12 +198,37 @@ func createProfile(profileName, address string, st interfaces.SocketType, timeou
 			identity = "UdpHello-Generic"
 		}
 		return profiles.NewUdpHelloProfile(identity, address, timeout), nil
+	case "udp-secure":
+		if identity == "" {
+			identity = "UdpSecure-Generic"
+		}
+		return profiles.NewUdpSecureProfile(identity, address, timeout), nil
 
 	// SHM Support
 	case "shm":
 		return profiles.NewShmProfile(address, timeout), nil // address is path
 	case "shm-hello":
 		return profiles.NewShmHelloProfile(address, timeout), nil
+	case "shm-secure":
+		return profiles.NewShmSecureProfile(address, timeout), nil
+	case "shm-broadcast":
+		return profiles.NewShmBroadcastProfile(address, timeout), nil
+
+	// In-process Support (tests; address is a listener name)
+	case "mem":
+		if identity == "" {
+			identity = "MemRaw-Generic"
+		}
+		return profiles.NewMemProfile(identity, address, timeout), nil
+	case "mem-hello":
+		if identity == "" {
+			if st == interfaces.SocketTypeClient {
+				identity = "MemClient-Generic"
+			} else {
+				identity = "MemServer-Generic"
+			}
+		}
+		return profiles.NewMemHelloProfile(identity, address, timeout), nil
 	default:
 		return nil, fmt.Errorf("unknown profile: %s", profileKey)
 	}
@@ -211,10 +271,19 @@ func CreateSocket(p interfaces.SocketProfile, config models.SocketConfig, socket
 		return nil, err
 	}
 
-	if st == interfaces.SocketTypeClient {
-		return facade.NewSocketClient(p, config), nil
+	var socket interfaces.Socket
+	switch {
+	case p.GetTransport() == interfaces.TransportShmBroadcast:
+		socket = facade.NewBroadcastSocket(p, config, st)
+	case st == interfaces.SocketTypeClient:
+		socket = facade.NewSocketClient(p, config)
+	default:
+		socket = facade.NewSocketServer(p, config)
 	}
-	return facade.NewSocketServer(p, config), nil
+
+	// Listed by the debug handler while the application holds it
+	facade.Track(socket)
+	return socket, nil
 }
 
 func parseSocketType(t string) (interfaces.SocketType, error) {
@@ -4,6 +4,8 @@ import (
 	"os"
 	"testing"
 
+	"github.com/Bastien-Antigravity/safe-socket/src/chaos"
+	"github.com/Bastien-Antigravity/safe-socket/src/facade"
 	"github.com/Bastien-Antigravity/safe-socket/src/models"
 )
 
@@ -48,3 +50,25 @@ func TestRegression_IgnoreEnvOverrides(t *testing.T) {
 	// For now, this test serves as a compilation check that the logic is gone
 	// (if I had left references, they might have surfaced here if I tried to use them).
 }
+
+func TestChaosSuffix(t *testing.T) {
+	// 1. The suffix selects chaos.Default, and keeps the identity
+	s, err := CreateWithConfig("tcp-hello+chaos:worker", "127.0.0.1:0", models.SocketConfig{}, "client", false)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	c := s.(*facade.SocketClient)
+	if c.Config.Chaos != chaos.Default || c.Profile.GetName() != "worker" {
+		t.Errorf("expected chaos.Default and identity 'worker', got %p and %q", c.Config.Chaos, c.Profile.GetName())
+	}
+
+	// 2. An injector of the config takes precedence
+	inj := chaos.New(chaos.Config{}, 1)
+	s, err = CreateWithConfig("tcp+chaos", "127.0.0.1:0", models.SocketConfig{Chaos: inj}, "server", false)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	if got := s.(*facade.SocketServer).Config.Chaos; got != inj {
+		t.Errorf("expected the configured injector, got %p", got)
+	}
+}
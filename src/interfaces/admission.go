@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"net"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// -----------------------------------------------------------------------------
+// AdmissionPolicy decides whether a peer that sent its HelloMsg may connect.
+// It is evaluated by the server right after WaitInitiation.
+type AdmissionPolicy interface {
+	// -------------------------------------------------------------------------
+	// Admit returns nil to accept the peer, or an error whose message is sent back
+	// to the client as the rejection reason.
+	Admit(hello *schemas.HelloMsg, remote net.Addr) error
+}
+
+// -----------------------------------------------------------------------------
+// AdmissionFunc adapts a plain function to the AdmissionPolicy interface.
+type AdmissionFunc func(hello *schemas.HelloMsg, remote net.Addr) error
+
+// Admit calls f(hello, remote).
+func (f AdmissionFunc) Admit(hello *schemas.HelloMsg, remote net.Addr) error {
+	return f(hello, remote)
+}
@@ -0,0 +1,48 @@
+package interfaces
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// ConnInfo describes a connection. The transport fills in what it knows, then every
+// wrapper of the chain adds its own part (see TransportConnection.ConnInfo).
+type ConnInfo struct {
+	// Layers lists the wrapper chain, outermost first, e.g.
+	// ["heartbeat", "handshake", "tracking", "framed-tcp"].
+	Layers []string
+
+	Transport TransportType
+	Protocol  ProtocolType // Set by the facade layer (heartbeat)
+	Profile   string       // Local profile name (SocketProfile.GetName)
+
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+
+	Identity      *schemas.HelloMsg    // Peer Hello identity (hello profiles)
+	Authenticated bool                 // Identity proven by the keyring or the client certificate
+	PeerKey       []byte               // Authenticated static key of the peer (*-secure profiles)
+	TLS           *tls.ConnectionState // TLS session (tls profiles)
+	FrameVersion  uint8                // Negotiated frame header version
+
+	HeartbeatInterval time.Duration // 0 = heartbeats disabled
+	IdleTimeout       time.Duration // 0 = no idle timeout
+
+	OpenedAt     time.Time // Transport connected (client) or accepted (server)
+	LastActivity time.Time // Last message or heartbeat sent, or message received
+
+	// Application traffic, heartbeats and control frames excluded
+	BytesSent        uint64
+	BytesReceived    uint64
+	MessagesSent     uint64
+	MessagesReceived uint64
+}
+
+// WithLayer returns info with layer prepended to its wrapper chain.
+func (info ConnInfo) WithLayer(layer string) ConnInfo {
+	info.Layers = append([]string{layer}, info.Layers...)
+	return info
+}
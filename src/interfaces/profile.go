@@ -4,18 +4,21 @@ package interfaces
 type TransportType string
 
 const (
-	TransportFramedTCP TransportType = "FramedTCP"
-	TransportTLS       TransportType = "TLS"
-	TransportShm       TransportType = "SharedMemory"
-	TransportUDP       TransportType = "UDP"
+	TransportFramedTCP    TransportType = "FramedTCP"
+	TransportTLS          TransportType = "TLS"
+	TransportShm          TransportType = "SharedMemory"
+	TransportShmBroadcast TransportType = "SharedMemoryBroadcast" // One publisher, many subscribers
+	TransportUDP          TransportType = "UDP"
+	TransportMem          TransportType = "Memory" // In-process, for tests
 )
 
 // ProtocolType defines the application-level handshake or startup protocol.
 type ProtocolType string
 
 const (
-	ProtocolNone  ProtocolType = "none"
-	ProtocolHello ProtocolType = "hello"
+	ProtocolNone   ProtocolType = "none"
+	ProtocolHello  ProtocolType = "hello"
+	ProtocolSecure ProtocolType = "secure" // Noise handshake + encrypted frames
 )
 
 // SocketProfile defines the behavior for a connection strategy.
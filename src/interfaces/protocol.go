@@ -10,9 +10,22 @@ import (
 type Protocol interface {
 	// -------------------------------------------------------------------------
 	// Initiate executes the handshake sequence or protocol logic (Client).
-	Initiate(conn TransportConnection, profile SocketProfile, config models.SocketConfig) error
+	// It returns the server identity, or nil when the server does not reply (legacy).
+	Initiate(conn TransportConnection, profile SocketProfile, config models.SocketConfig) (*schemas.HelloMsg, error)
 
 	// -------------------------------------------------------------------------
-	// WaitInitiation waits for and processes the handshake sequence (Server).
+	// WaitInitiation waits for the client's handshake message (Server).
+	// It does not answer it: call Respond once the client has been admitted or refused.
 	WaitInitiation(conn TransportConnection) (*schemas.HelloMsg, error)
+
+	// -------------------------------------------------------------------------
+	// Authenticate makes the client prove the identity it claimed (Server), when the
+	// config requires it. A non-nil error means the client must be rejected.
+	Authenticate(conn TransportConnection, client *schemas.HelloMsg, profile SocketProfile, config models.SocketConfig) error
+
+	// -------------------------------------------------------------------------
+	// Respond answers the client with the server identity (profile, config) and the
+	// admission verdict: nil accepts the client, any other error rejects it with
+	// its message as reason.
+	Respond(conn TransportConnection, client *schemas.HelloMsg, profile SocketProfile, config models.SocketConfig, verdict error) error
 }
@@ -28,6 +28,10 @@ type TransportConnection interface {
 	SetReadDeadline(t time.Time) error
 	SetWriteDeadline(t time.Time) error
 	SetIdleTimeout(d time.Duration) error
+
+	// ConnInfo describes the connection. Wrappers call it on the connection they wrap
+	// and add their own layer (see ConnInfo).
+	ConnInfo() ConnInfo
 }
 
 // TransportListener defines a listener that waits for incoming connections.
@@ -36,3 +40,125 @@ type TransportListener interface {
 	Close() error
 	Addr() net.Addr
 }
+
+// -----------------------------------------------------------------------------
+
+// FrameType identifies the kind of a frame in the versioned (v1) frame header.
+type FrameType uint8
+
+const (
+	// FrameData carries application payload (the only type a legacy v0 peer knows).
+	FrameData FrameType = 0
+	// FrameHeartbeat is an empty keep-alive frame, discarded by the reader.
+	FrameHeartbeat FrameType = 1
+	// FramePing carries an opaque payload that the peer must echo back in a FramePong.
+	FramePing FrameType = 2
+	// FramePong echoes the payload of a received FramePing.
+	FramePong FrameType = 3
+	// FrameGoodbye announces a graceful close: [Code:2] [Reason UTF-8...].
+	FrameGoodbye FrameType = 4
+	// FrameGoodbyeAck acknowledges a received FrameGoodbye.
+	FrameGoodbyeAck FrameType = 5
+)
+
+// ControlHandler is invoked from the read path for every control frame received.
+// It must not block: it runs while the reader holds the transport.
+type ControlHandler func(frameType FrameType, payload []byte)
+
+// ControlConnection is implemented by transports able to speak the versioned frame
+// header and therefore carry typed control frames (ping/pong...) alongside data frames.
+// Control frames never surface through Read/ReadMessage; they are dispatched to the
+// registered ControlHandler instead.
+//
+// Connections start in the legacy length-only format (version 0), where control frames
+// cannot be expressed; the version is raised once both peers agreed on it (Hello handshake).
+//
+// PumpControl dispatches the control frames already received ahead of the next data
+// frame, without blocking: it lets a connection nobody reads process its pongs. It
+// does nothing while a reader holds the transport (the reader dispatches them).
+type ControlConnection interface {
+	WriteControl(frameType FrameType, payload []byte) error
+	SetControlHandler(h ControlHandler)
+	SetFrameVersion(v uint8) error
+	FrameVersion() uint8
+	PumpControl() error
+}
+
+// TraceContextConnection is implemented by transports able to carry the sender's trace
+// context in the header of data frames (frame version 1 and later). Propagation is off
+// until both peers agreed on it (Hello handshake): until then WriteTraced sends the
+// payload alone, and readers never see the context of a frame (Read and ReadMessage
+// always strip it).
+type TraceContextConnection interface {
+	EnableTraceContext() error
+	TraceContextEnabled() bool
+	WriteTraced(p, tc []byte) (int, error)
+	ReadMessageTraced() (msg, tc []byte, err error)
+}
+
+// -----------------------------------------------------------------------------
+
+// FrameDirection tells whether a frame was sent or received.
+type FrameDirection uint8
+
+const (
+	FrameOutbound FrameDirection = 0
+	FrameInbound  FrameDirection = 1
+)
+
+// Frame is one frame as it went over the wire: Body is everything after the header
+// (trace context prefix included, see Flags).
+type Frame struct {
+	Direction FrameDirection
+	Version   uint8 // Frame header version in use when the frame was written or read
+	Type      FrameType
+	Flags     uint8
+	Body      []byte
+}
+
+// FrameObserver sees the frames of a connection. It runs on the read and write paths
+// and must neither block nor retain Body past the call.
+type FrameObserver func(f Frame)
+
+// FrameTap is implemented by transports able to report every frame they write or
+// read, control frames and heartbeats included (used by traffic capture).
+type FrameTap interface {
+	SetFrameObserver(o FrameObserver)
+}
+
+// -----------------------------------------------------------------------------
+
+// CloseCode tells the peer why a connection is being closed gracefully.
+// Codes from 4000 upwards are free for application use.
+type CloseCode uint16
+
+const (
+	// CloseNormal is a regular end of session.
+	CloseNormal CloseCode = 0
+	// CloseGoingAway means the endpoint is shutting down or restarting.
+	CloseGoingAway CloseCode = 1
+	// CloseProtocolError means the peer sent something the endpoint could not process.
+	CloseProtocolError CloseCode = 2
+	// ClosePolicyViolation means the peer was refused by a local policy.
+	ClosePolicyViolation CloseCode = 3
+	// CloseIdleTimeout means the connection was idle for too long.
+	CloseIdleTimeout CloseCode = 4
+)
+
+// GracefulCloser is implemented by transports that notify the peer when closing
+// (goodbye frame on TCP/TLS, status words on SHM), so that the peer can tell a
+// deliberate close from a crash or a network partition. TCP/TLS only send the goodbye
+// once frame version 1 was negotiated (Hello profiles): plain tcp and tls connections
+// keep legacy frames, which cannot carry it, and their peer sees a bare EOF.
+type GracefulCloser interface {
+	// SetCloseReason sets the code and reason announced by the next Close.
+	SetCloseReason(code CloseCode, reason string)
+	// SetCloseAckTimeout makes Close wait up to d for the peer's acknowledgement (0 = don't wait).
+	SetCloseAckTimeout(d time.Duration)
+}
+
+// Aborter is implemented by transports able to drop a connection the way a crash or a
+// network failure would: without announcing it to the peer (used by chaos testing).
+type Aborter interface {
+	Abort() error
+}
@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// LevelCritical is the slog level routed to Logger.Critical.
+const LevelCritical = slog.LevelError + 4
+
+// LoggerHandler adapts an interfaces.Logger to slog: each record becomes one line,
+// the message followed by its attributes as key=value pairs, e.g.
+//
+//	open failed, retrying attempt=2 error="connection refused" address=127.0.0.1:80
+type LoggerHandler struct {
+	logger interfaces.Logger
+	level  slog.Leveler
+	attrs  string // Preformatted attributes of WithAttrs
+	group  string // Key prefix of WithGroup ("a.b.")
+}
+
+// NewLoggerHandler routes slog records at Info level and above to logger (see
+// WithLevel to include Debug records).
+func NewLoggerHandler(logger interfaces.Logger) *LoggerHandler {
+	return &LoggerHandler{logger: logger, level: slog.LevelInfo}
+}
+
+// WithLevel returns a copy dropping records below level.
+func (h *LoggerHandler) WithLevel(level slog.Leveler) *LoggerHandler {
+	c := *h
+	c.level = level
+	return &c
+}
+
+// -----------------------------------------------------------------------------
+
+func (h *LoggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *LoggerHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.group, a)
+		return true
+	})
+	b.WriteString(h.attrs)
+	msg := b.String()
+
+	switch {
+	case r.Level < slog.LevelInfo:
+		h.logger.Debug(msg)
+	case r.Level < slog.LevelWarn:
+		h.logger.Info(msg)
+	case r.Level < slog.LevelError:
+		h.logger.Warning(msg)
+	case r.Level < LevelCritical:
+		h.logger.Error(msg)
+	default:
+		h.logger.Critical(msg)
+	}
+	return nil
+}
+
+func (h *LoggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		appendAttr(&b, h.group, a)
+	}
+	c := *h
+	c.attrs = b.String() + h.attrs // Record attributes come first, the socket's last
+	return &c
+}
+
+func (h *LoggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.group = h.group + name + "."
+	return &c
+}
+
+// -----------------------------------------------------------------------------
+
+// appendAttr writes " key=value", flattening groups into dotted keys.
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, prefix, ga)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " =\"") {
+		value = fmt.Sprintf("%q", value)
+	}
+	b.WriteString(" ")
+	b.WriteString(prefix)
+	b.WriteString(a.Key)
+	b.WriteString("=")
+	b.WriteString(value)
+}
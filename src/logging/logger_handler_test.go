@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+// recordingLogger keeps every line with its level.
+type recordingLogger struct {
+	lines []string
+}
+
+func (r *recordingLogger) Debug(msg string)    { r.lines = append(r.lines, "DEBUG "+msg) }
+func (r *recordingLogger) Info(msg string)     { r.lines = append(r.lines, "INFO "+msg) }
+func (r *recordingLogger) Warning(msg string)  { r.lines = append(r.lines, "WARNING "+msg) }
+func (r *recordingLogger) Error(msg string)    { r.lines = append(r.lines, "ERROR "+msg) }
+func (r *recordingLogger) Critical(msg string) { r.lines = append(r.lines, "CRITICAL "+msg) }
+func (r *recordingLogger) Close()              {}
+
+func TestLoggerHandler(t *testing.T) {
+	rec := &recordingLogger{}
+	logger := slog.New(NewLoggerHandler(rec).WithLevel(slog.LevelDebug)).With("address", "127.0.0.1:80")
+
+	logger.Debug("probe")
+	logger.Warn("open failed", "attempt", 2, "error", "connection refused")
+	logger.WithGroup("tls").Error("handshake", slog.Group("peer", "cn", "server"))
+	logger.Log(context.Background(), LevelCritical, "down")
+
+	want := []string{
+		"DEBUG probe address=127.0.0.1:80",
+		`WARNING open failed attempt=2 error="connection refused" address=127.0.0.1:80`,
+		"ERROR handshake tls.peer.cn=server address=127.0.0.1:80",
+		"CRITICAL down address=127.0.0.1:80",
+	}
+	if len(rec.lines) != len(want) {
+		t.Fatalf("expected %d lines, got %q", len(want), rec.lines)
+	}
+	for i := range want {
+		if rec.lines[i] != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], rec.lines[i])
+		}
+	}
+
+	rec.lines = nil
+	slog.New(NewLoggerHandler(rec)).Debug("dropped")
+	if len(rec.lines) != 0 {
+		t.Errorf("expected Debug to be filtered by default, got %q", rec.lines)
+	}
+	slog.New(NewLoggerHandler(rec).WithLevel(slog.LevelWarn)).Info("dropped")
+	if len(rec.lines) != 0 {
+		t.Errorf("expected Info to be filtered, got %q", rec.lines)
+	}
+}
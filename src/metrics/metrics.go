@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Labels identify the series a socket is exported under. Each socket records its
+// own counters; sockets with identical labels are summed by Registry.Stats.
+type Labels struct {
+	Profile   string // Profile name (SocketProfile.GetName)
+	Transport string // e.g. "FramedTCP", "SharedMemory"
+	Protocol  string // e.g. "hello", "none"
+	Role      string // "client" or "server"
+	Address   string // Network address or SHM path
+}
+
+// Stats is a snapshot of the activity recorded for one socket.
+type Stats struct {
+	Labels
+
+	BytesSent         uint64 // Application payload bytes written
+	BytesReceived     uint64 // Application payload bytes read
+	FramesSent        uint64 // Application messages written
+	FramesReceived    uint64 // Application messages read
+	HeartbeatsSent    uint64 // Heartbeats (or pings) sent by the heartbeat layer
+	HeartbeatsSkipped uint64 // Heartbeat frames received and discarded by readers
+	HandshakeFailures uint64 // Failed or refused handshakes
+	OpenRetries       uint64 // Reconnection attempts made by SocketClient.Open
+	Retransmits       uint64 // Packets resent by the UDP reliability layer
+	ConnectionsOpened uint64 // Connections opened (client) or accepted (server)
+	ConnectionsOpen   int64  // Connections currently open
+	ShmRingOutBytes   int64  // Bytes waiting in the outbound SHM rings (at the last write)
+	ShmRingInBytes    int64  // Bytes waiting in the inbound SHM rings (at the last read)
+}
+
+// -----------------------------------------------------------------------------
+
+// Socket records the activity of one socket. Every method is a no-op on a nil
+// *Socket, so that instrumented layers need no checks.
+type Socket struct {
+	labels   Labels
+	registry *Registry
+
+	bytesSent         atomic.Uint64
+	bytesReceived     atomic.Uint64
+	framesSent        atomic.Uint64
+	framesReceived    atomic.Uint64
+	heartbeatsSent    atomic.Uint64
+	heartbeatsSkipped atomic.Uint64
+	handshakeFailures atomic.Uint64
+	openRetries       atomic.Uint64
+	retransmits       atomic.Uint64
+	connectionsOpened atomic.Uint64
+	connectionsOpen   atomic.Int64
+	shmRingOut        atomic.Int64
+	shmRingIn         atomic.Int64
+}
+
+// Instrumented is implemented by connection layers recording into a Socket.
+type Instrumented interface {
+	SetMetrics(m *Socket)
+}
+
+func (s *Socket) Sent(n int) {
+	if s != nil {
+		s.framesSent.Add(1)
+		s.bytesSent.Add(uint64(n))
+	}
+}
+
+func (s *Socket) Received(n int) {
+	if s != nil {
+		s.framesReceived.Add(1)
+		s.bytesReceived.Add(uint64(n))
+	}
+}
+
+func (s *Socket) HeartbeatSent() {
+	if s != nil {
+		s.heartbeatsSent.Add(1)
+	}
+}
+
+func (s *Socket) HeartbeatSkipped() {
+	if s != nil {
+		s.heartbeatsSkipped.Add(1)
+	}
+}
+
+func (s *Socket) HandshakeFailed() {
+	if s != nil {
+		s.handshakeFailures.Add(1)
+	}
+}
+
+func (s *Socket) OpenRetried() {
+	if s != nil {
+		s.openRetries.Add(1)
+	}
+}
+
+func (s *Socket) Retransmitted(n int) {
+	if s != nil {
+		s.retransmits.Add(uint64(n))
+	}
+}
+
+func (s *Socket) ConnOpened() {
+	if s != nil {
+		s.connectionsOpened.Add(1)
+		s.connectionsOpen.Add(1)
+	}
+}
+
+func (s *Socket) ConnClosed() {
+	if s != nil {
+		s.connectionsOpen.Add(-1)
+	}
+}
+
+// AddShmRing adjusts the SHM ring occupancy by the change observed on one connection.
+func (s *Socket) AddShmRing(outDelta, inDelta int64) {
+	if s != nil {
+		s.shmRingOut.Add(outDelta)
+		s.shmRingIn.Add(inDelta)
+	}
+}
+
+// Register exports the socket in its registry (a no-op when already registered).
+func (s *Socket) Register() {
+	if s != nil && s.registry != nil {
+		s.registry.mu.Lock()
+		s.registry.sockets[s] = struct{}{}
+		s.registry.mu.Unlock()
+	}
+}
+
+// Unregister removes the socket from its registry, e.g. once the socket is closed.
+// The socket keeps recording, and Stats still reports it.
+func (s *Socket) Unregister() {
+	if s != nil && s.registry != nil {
+		s.registry.mu.Lock()
+		delete(s.registry.sockets, s)
+		s.registry.mu.Unlock()
+	}
+}
+
+// Stats returns a snapshot of the recorded activity (zero on a nil *Socket).
+func (s *Socket) Stats() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	return Stats{
+		Labels:            s.labels,
+		BytesSent:         s.bytesSent.Load(),
+		BytesReceived:     s.bytesReceived.Load(),
+		FramesSent:        s.framesSent.Load(),
+		FramesReceived:    s.framesReceived.Load(),
+		HeartbeatsSent:    s.heartbeatsSent.Load(),
+		HeartbeatsSkipped: s.heartbeatsSkipped.Load(),
+		HandshakeFailures: s.handshakeFailures.Load(),
+		OpenRetries:       s.openRetries.Load(),
+		Retransmits:       s.retransmits.Load(),
+		ConnectionsOpened: s.connectionsOpened.Load(),
+		ConnectionsOpen:   s.connectionsOpen.Load(),
+		ShmRingOutBytes:   s.shmRingOut.Load(),
+		ShmRingInBytes:    s.shmRingIn.Load(),
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Registry holds the sockets exposed together (see WriteText and ServeHTTP).
+type Registry struct {
+	mu      sync.Mutex
+	sockets map[*Socket]struct{}
+}
+
+// DefaultRegistry is used by sockets whose SocketConfig.Metrics is nil.
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{sockets: make(map[*Socket]struct{})}
+}
+
+// Socket returns a new socket registered under the given labels.
+func (r *Registry) Socket(l Labels) *Socket {
+	s := r.NewSocket(l)
+	s.Register()
+	return s
+}
+
+// NewSocket returns a new socket of the registry, exported once Register is called.
+func (r *Registry) NewSocket(l Labels) *Socket {
+	return &Socket{labels: l, registry: r}
+}
+
+// Stats returns a snapshot of every registered series, ordered by labels. Sockets
+// sharing their labels are summed into one series.
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	series := make(map[Labels]*Stats, len(r.sockets))
+	for s := range r.sockets {
+		st := s.Stats()
+		if sum, ok := series[st.Labels]; ok {
+			sum.add(&st)
+		} else {
+			series[st.Labels] = &st
+		}
+	}
+	r.mu.Unlock()
+
+	stats := make([]Stats, 0, len(series))
+	for _, st := range series {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i].Labels, stats[j].Labels
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		if a.Transport != b.Transport {
+			return a.Transport < b.Transport
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.Address < b.Address
+	})
+	return stats
+}
+
+// add sums the counters of o into s.
+func (s *Stats) add(o *Stats) {
+	s.BytesSent += o.BytesSent
+	s.BytesReceived += o.BytesReceived
+	s.FramesSent += o.FramesSent
+	s.FramesReceived += o.FramesReceived
+	s.HeartbeatsSent += o.HeartbeatsSent
+	s.HeartbeatsSkipped += o.HeartbeatsSkipped
+	s.HandshakeFailures += o.HandshakeFailures
+	s.OpenRetries += o.OpenRetries
+	s.Retransmits += o.Retransmits
+	s.ConnectionsOpened += o.ConnectionsOpened
+	s.ConnectionsOpen += o.ConnectionsOpen
+	s.ShmRingOutBytes += o.ShmRingOutBytes
+	s.ShmRingInBytes += o.ShmRingInBytes
+}
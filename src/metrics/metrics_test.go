@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("NilSocket", func(t *testing.T) {
+		var s *Socket
+		s.Sent(10)
+		s.ConnOpened()
+		if st := s.Stats(); st != (Stats{}) {
+			t.Errorf("expected zero stats, got %+v", st)
+		}
+	})
+
+	t.Run("PerSocket", func(t *testing.T) {
+		r := NewRegistry()
+		labels := Labels{Profile: "worker", Transport: "FramedTCP", Protocol: "hello", Role: "client", Address: "127.0.0.1:1"}
+		alice, bob := r.Socket(labels), r.Socket(labels)
+		alice.Sent(5)
+		bob.Sent(7)
+
+		if st := bob.Stats(); st.FramesSent != 1 || st.BytesSent != 7 {
+			t.Errorf("expected 1 frame / 7 bytes for one socket, got %d / %d", st.FramesSent, st.BytesSent)
+		}
+		stats := r.Stats()
+		if len(stats) != 1 {
+			t.Fatalf("expected one series, got %d", len(stats))
+		}
+		if stats[0].FramesSent != 2 || stats[0].BytesSent != 12 {
+			t.Errorf("expected 2 frames / 12 bytes, got %d / %d", stats[0].FramesSent, stats[0].BytesSent)
+		}
+
+		alice.Unregister()
+		bob.Unregister()
+		if stats := r.Stats(); len(stats) != 0 {
+			t.Errorf("expected no series once unregistered, got %+v", stats)
+		}
+		if st := bob.Stats(); st.BytesSent != 7 {
+			t.Errorf("expected an unregistered socket to keep its stats, got %d bytes", st.BytesSent)
+		}
+	})
+
+	t.Run("PrometheusText", func(t *testing.T) {
+		r := NewRegistry()
+		s := r.Socket(Labels{Transport: "SharedMemory", Role: "server", Address: `odd "path"`})
+		s.Received(1 << 30)
+		s.AddShmRing(64, 0)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		body := rec.Body.String()
+
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		for _, want := range []string{
+			"# TYPE safesocket_bytes_received_total counter\n",
+			`safesocket_bytes_received_total{profile="",transport="SharedMemory",protocol="",role="server",address="odd \"path\""} 1073741824` + "\n",
+			"# TYPE safesocket_shm_ring_out_bytes gauge\n",
+			`safesocket_shm_ring_out_bytes{profile="",transport="SharedMemory",protocol="",role="server",address="odd \"path\""} 64` + "\n",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("missing %q in:\n%s", want, body)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Prometheus Text Exposition (format 0.0.4)
+//
+// Every Stats field is a metric family, with one sample per series labelled by
+// profile, transport, protocol, role and address. Closed sockets leave the
+// registry: their series disappear, or drop (a counter reset) when another socket
+// shares the labels. Per-transport totals are a query away, e.g.
+// sum by (transport, protocol) (rate(safesocket_bytes_sent_total[1m])).
+
+// family describes one exported Stats field.
+type family struct {
+	name  string
+	kind  string // "counter" or "gauge"
+	help  string
+	value func(s *Stats) float64
+}
+
+var families = []family{
+	{"safesocket_bytes_sent_total", "counter", "Application payload bytes written.", func(s *Stats) float64 { return float64(s.BytesSent) }},
+	{"safesocket_bytes_received_total", "counter", "Application payload bytes read.", func(s *Stats) float64 { return float64(s.BytesReceived) }},
+	{"safesocket_frames_sent_total", "counter", "Application messages written.", func(s *Stats) float64 { return float64(s.FramesSent) }},
+	{"safesocket_frames_received_total", "counter", "Application messages read.", func(s *Stats) float64 { return float64(s.FramesReceived) }},
+	{"safesocket_heartbeats_sent_total", "counter", "Heartbeats or pings sent by the heartbeat layer.", func(s *Stats) float64 { return float64(s.HeartbeatsSent) }},
+	{"safesocket_heartbeats_skipped_total", "counter", "Heartbeat frames received and discarded by readers.", func(s *Stats) float64 { return float64(s.HeartbeatsSkipped) }},
+	{"safesocket_handshake_failures_total", "counter", "Failed or refused handshakes.", func(s *Stats) float64 { return float64(s.HandshakeFailures) }},
+	{"safesocket_open_retries_total", "counter", "Reconnection attempts made by Open.", func(s *Stats) float64 { return float64(s.OpenRetries) }},
+	{"safesocket_retransmits_total", "counter", "Packets resent by the UDP reliability layer.", func(s *Stats) float64 { return float64(s.Retransmits) }},
+	{"safesocket_connections_opened_total", "counter", "Connections opened or accepted.", func(s *Stats) float64 { return float64(s.ConnectionsOpened) }},
+	{"safesocket_connections_open", "gauge", "Connections currently open.", func(s *Stats) float64 { return float64(s.ConnectionsOpen) }},
+	{"safesocket_shm_ring_out_bytes", "gauge", "Bytes waiting in the outbound SHM rings.", func(s *Stats) float64 { return float64(s.ShmRingOutBytes) }},
+	{"safesocket_shm_ring_in_bytes", "gauge", "Bytes waiting in the inbound SHM rings.", func(s *Stats) float64 { return float64(s.ShmRingInBytes) }},
+}
+
+// WriteText writes every series of the registry in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	stats := r.Stats()
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for i := range stats {
+			fmt.Fprintf(bw, "%s{%s} %s\n", f.name, labelPairs(stats[i].Labels), strconv.FormatFloat(f.value(&stats[i]), 'f', -1, 64))
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP exposes the registry to a Prometheus scraper.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteText(w)
+}
+
+// Handler returns the http.Handler of the default registry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// -----------------------------------------------------------------------------
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelPairs(l Labels) string {
+	return fmt.Sprintf(`profile="%s",transport="%s",protocol="%s",role="%s",address="%s"`,
+		labelEscaper.Replace(l.Profile), labelEscaper.Replace(l.Transport), labelEscaper.Replace(l.Protocol), labelEscaper.Replace(l.Role),
+		labelEscaper.Replace(l.Address))
+}
@@ -1,7 +1,15 @@
 package models
 
 import (
+	"crypto/tls"
+	"log/slog"
+	"os"
 	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/capture"
+	"github.com/Bastien-Antigravity/safe-socket/src/chaos"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
 )
 
 // SocketConfig holds runtime configuration for socket creation.
@@ -20,9 +28,34 @@ type SocketConfig struct {
 	// If 0, a default (e.g. 10s) may be used depending on the facade.
 	HeartbeatInterval time.Duration
 
-	// HandshakeTimeout is the time allowed for the initial protocol handshake.
+	// HeartbeatPingPong replaces one-way heartbeats with timestamped ping frames that
+	// the peer echoes back, enabling RTT measurement and detection of peers that never read.
+	// Requires a Hello profile negotiating frame version 1 (TCP, TLS and SHM transports only).
+	HeartbeatPingPong bool
+
+	// MaxMissedPongs is the number of consecutive unanswered pings after which the
+	// connection is closed in ping/pong mode. If 0, facade.DefaultMaxMissedPongs (3) is used.
+	MaxMissedPongs int
+
+	// LegacyFraming disables frame header negotiation during the Hello handshake.
+	// Set it on clients that must talk to servers predating versioned frame headers:
+	// such servers never answer the Hello, so Open would otherwise fail with
+	// protocols.ErrNoHelloReply once the handshake timeout expires.
+	LegacyFraming bool
+
+	// CloseAckTimeout is how long Close waits for the peer to acknowledge the goodbye
+	// announcing a graceful close (TCP/TLS with negotiated frames, SHM). If 0, Close
+	// sends the goodbye and returns immediately.
+	CloseAckTimeout time.Duration
+
+	// HandshakeTimeout is the time allowed for the initial protocol handshake
+	// (0 = the profile's connect timeout, or 5s).
 	HandshakeTimeout time.Duration
 
+	// MaxPendingHandshakes caps the connections a server handshakes at once (0 = 64).
+	// Further connections wait in the listener backlog until a handshake finishes.
+	MaxPendingHandshakes int
+
 	// MaxRetries is the number of times to attempt reconnection if Open() fails.
 	// Set to -1 for infinite retries.
 	MaxRetries int
@@ -34,10 +67,125 @@ type SocketConfig struct {
 	// When enabled, packets will include sequence numbers and expect ACKs.
 	Reliable bool
 
+	// AuthKeys is the keyring of pre-shared secrets (key ID -> secret) used by the Hello
+	// challenge-response. A server with a non-empty keyring challenges every client and
+	// accepts a proof made with any key of the ring, which allows rotating keys: add the
+	// new key on servers, move clients to it, then drop the old one.
+	AuthKeys map[string][]byte
+
+	// AuthKeyID selects the key a client answers challenges with. It may be left empty
+	// when the keyring holds a single key.
+	AuthKeyID string
+
+	// EnvelopeMaxAge is the clock skew tolerated on authenticated udp-hello envelopes
+	// (AuthKeys set): older or future-dated packets are dropped. If 0, 30s is used.
+	EnvelopeMaxAge time.Duration
+
+	// SecureStaticKey is this node's X25519 private key (32 bytes) for the *-secure
+	// profiles (see protocols.GenerateSecureKey).
+	SecureStaticKey []byte
+
+	// SecurePeerKeys lists the trusted X25519 public keys of peers. Clients must set the
+	// server key (udp-secure sends to the first one); a server with an empty list accepts
+	// any client key, otherwise only the listed ones.
+	SecurePeerKeys [][]byte
+
+	// ShmMode is the permission of SHM segments created by this node (0 = 0600).
+	ShmMode os.FileMode
+
+	// ShmOwner and ShmGroup (name or numeric id) are applied to new SHM segments.
+	// Existing segments are only opened if they belong to the current user or ShmOwner.
+	ShmOwner string
+	ShmGroup string
+
+	// ShmDir is where bare SHM names are placed ("" = /dev/shm when it exists).
+	ShmDir string
+
+	// ShmMemfd backs SHM segments with an anonymous memfd handed from the server to the
+	// client over a unix socket, leaving nothing on disk (Linux only; set on both ends).
+	ShmMemfd bool
+
+	// ShmBroadcastSize is the ring size of shm-broadcast publishers (0 = 32 MB). Messages
+	// are limited to half of it.
+	ShmBroadcastSize int
+
+	// BroadcastAutoResync makes lapped shm-broadcast subscribers jump to the latest
+	// message by themselves: the overrun error is still returned once.
+	BroadcastAutoResync bool
+
 	// TLS Configuration
 	CertFile           string
 	KeyFile            string
 	CAFile             string
 	ServerName         string
 	InsecureSkipVerify bool
+
+	// CertPEM, KeyPEM and CAPEM supply TLS material in memory (e.g. fetched from a
+	// vault). They take precedence over CertFile, KeyFile and CAFile.
+	CertPEM []byte
+	KeyPEM  []byte
+	CAPEM   []byte
+
+	// TLSConfig is the base TLS configuration (cloned). The fields above are applied on top.
+	TLSConfig *tls.Config
+
+	// CertProvider is asked for the certificate at every TLS handshake (server certificate,
+	// or client certificate for mTLS), which lets rotated secrets apply without restarting.
+	// It takes precedence over the PEM and file settings.
+	CertProvider func() (*tls.Certificate, error)
+
+	// TLS policy, applied on both ends (0/nil = TLSConfig or Go defaults).
+	// CipherSuites only restricts TLS 1.0-1.2: TLS 1.3 suites are not configurable in Go.
+	TLSMinVersion       uint16 // e.g. tls.VersionTLS12
+	TLSMaxVersion       uint16
+	TLSCipherSuites     []uint16
+	TLSCurvePreferences []tls.CurveID
+	ALPNProtocols       []string
+
+	// TLSClientAuth is the server's client certificate policy, e.g. tls.VerifyClientCertIfGiven
+	// (request) or tls.RequireAndVerifyClientCert (require). The default (tls.NoClientCert)
+	// requires a verified certificate when a CA bundle is set and asks for none otherwise.
+	TLSClientAuth tls.ClientAuthType
+
+	// PinnedSPKI makes clients accept only servers whose verified chain carries one of these
+	// public keys, as base64(SHA-256(SubjectPublicKeyInfo)) (see transports.SPKIPin). Pinning
+	// also applies with InsecureSkipVerify, which allows trusting a self-signed server by
+	// key: the server's own certificate must carry the pinned key then.
+	PinnedSPKI []string
+
+	// TLSReloadInterval makes tls servers poll CertFile, KeyFile and CAFile for changes and
+	// apply rotated certificates and CA bundles to new connections (0 = load once).
+	TLSReloadInterval time.Duration
+
+	// BindCertIdentity makes a tls-hello server (mTLS via CAFile) reject clients whose
+	// Hello fromName is not vouched for by their verified certificate: it must equal
+	// the CN, a DNS SAN or a URI SAN, or match a glob that CertNameMap maps them to.
+	BindCertIdentity bool
+
+	// CertNameMap maps a certificate identity (CN, DNS SAN or URI SAN such as
+	// "spiffe://corp/ns/prod/sa/worker") to the Hello name globs it may claim.
+	CertNameMap map[string][]string
+
+	// LogHandler receives the socket's structured log events (nil = Logger set with
+	// SetLogger, or nothing at all: the library never logs to stdout by default).
+	LogHandler slog.Handler
+
+	// Metrics is the registry recording this socket's activity (nil = metrics.DefaultRegistry,
+	// exposed by metrics.Handler()).
+	Metrics *metrics.Registry
+
+	// Tracer records spans around connect, accept, handshake and the context-aware
+	// send/receive methods (nil = no tracing). A tracer implementing tracing.Propagator
+	// also sends its trace context along with each message, to peers supporting it.
+	Tracer tracing.Tracer
+
+	// Capture records every frame of the socket's connections, handshakes, control
+	// frames and heartbeats included, into a capture file (nil = no capture). One
+	// Writer may be shared by several sockets; closing it is up to the caller.
+	Capture *capture.Writer
+
+	// Chaos injects the faults it draws into every connection of the socket, for chaos
+	// testing (nil = none; a "+chaos" profile suffix uses chaos.Default). Not applied
+	// to shm-broadcast.
+	Chaos *chaos.Injector
 }
@@ -0,0 +1,42 @@
+package profiles
+
+import "github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+
+// -----------------------------------------------------------------------------
+// Memory Profiles (in-process, for tests)
+// -----------------------------------------------------------------------------
+
+// MemProfile implements the SocketProfile interface for the in-process mem transport,
+// where the address is the name of a listener of the same process.
+type MemProfile struct {
+	Name           string
+	Address        string
+	ConnectTimeout int
+	Protocol       interfaces.ProtocolType
+}
+
+func (p *MemProfile) GetName() string                        { return p.Name }
+func (p *MemProfile) GetAddress() string                     { return p.Address }
+func (p *MemProfile) GetTransport() interfaces.TransportType { return interfaces.TransportMem }
+func (p *MemProfile) GetConnectTimeout() int                 { return p.ConnectTimeout }
+func (p *MemProfile) GetProtocol() interfaces.ProtocolType   { return p.Protocol }
+
+// -----------------------------------------------------------------------------
+
+func NewMemProfile(name, address string, timeout int) *MemProfile {
+	return &MemProfile{
+		Name:           name,
+		Address:        address,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolNone,
+	}
+}
+
+func NewMemHelloProfile(name, address string, timeout int) *MemProfile {
+	return &MemProfile{
+		Name:           name,
+		Address:        address,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolHello,
+	}
+}
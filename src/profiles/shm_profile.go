@@ -57,3 +57,32 @@ func NewShmHelloProfile(path string, timeout int) *ShmProfile {
 		Protocol:       interfaces.ProtocolHello,
 	}
 }
+
+func NewShmSecureProfile(path string, timeout int) *ShmProfile {
+	return &ShmProfile{
+		Name:           path,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolSecure,
+	}
+}
+
+// -----------------------------------------------------------------------------
+// SHM Broadcast Profile
+// -----------------------------------------------------------------------------
+
+// ShmBroadcastProfile is a one-to-many SHM ring: the server publishes, clients subscribe.
+type ShmBroadcastProfile struct {
+	ShmProfile
+}
+
+func (p *ShmBroadcastProfile) GetTransport() interfaces.TransportType {
+	return interfaces.TransportShmBroadcast
+}
+
+func NewShmBroadcastProfile(path string, timeout int) *ShmBroadcastProfile {
+	return &ShmBroadcastProfile{ShmProfile{
+		Name:           path,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolNone,
+	}}
+}
@@ -85,3 +85,13 @@ func (p *TcpHelloClientProfile) GetProtocol() interfaces.ProtocolType {
 func (p *TcpHelloClientProfile) GetConnectTimeout() int {
 	return p.ConnectTimeout
 }
+
+// NewTcpSecureClientProfile creates a new instance of a TCP profile with the secure protocol.
+func NewTcpSecureClientProfile(name, address string, timeout int) *TcpHelloClientProfile {
+	return &TcpHelloClientProfile{
+		Name:           name,
+		Address:        address,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolSecure,
+	}
+}
@@ -85,3 +85,13 @@ func (p *TcpHelloServerProfile) GetProtocol() interfaces.ProtocolType {
 func (p *TcpHelloServerProfile) GetConnectTimeout() int {
 	return p.ConnectTimeout
 }
+
+// NewTcpSecureServerProfile creates a new instance of a TCP server profile with the secure protocol.
+func NewTcpSecureServerProfile(name, address string, timeout int) *TcpHelloServerProfile {
+	return &TcpHelloServerProfile{
+		Name:           name,
+		Address:        address,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolSecure,
+	}
+}
@@ -38,3 +38,12 @@ func NewUdpHelloProfile(name, address string, timeout int) *UdpProfile {
 		Protocol:       interfaces.ProtocolHello,
 	}
 }
+
+func NewUdpSecureProfile(name, address string, timeout int) *UdpProfile {
+	return &UdpProfile{
+		Name:           name,
+		Address:        address,
+		ConnectTimeout: timeout,
+		Protocol:       interfaces.ProtocolSecure,
+	}
+}
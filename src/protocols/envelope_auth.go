@@ -0,0 +1,267 @@
+package protocols
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"capnproto.org/go/capnp/v3"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// Authenticated Envelopes (udp-hello + keyring)
+//
+// With SocketConfig.AuthKeys set, every PacketEnvelope also carries the sender's clock,
+// a random epoch drawn once per sending socket, a counter monotonic within that epoch,
+// the key ID and
+//
+//	mac = HMAC-SHA256(key, envelopeContext | len|senderID | timestamp | epoch | counter | len|keyId | payload)
+//
+// The receiver drops packets that are unsigned or forged, older (or further in the
+// future) than the allowed clock skew, or whose counter was already seen / fell
+// behind the sliding replay window of that (sender, epoch). Sender IDs are not unique
+// (every client of a profile may share one), so windows are never keyed on the ID alone.
+// A guard holds at most maxTrackedSenders windows: when full, a new sender epoch
+// evicts the least recently seen one, and no packet older than the evicted window's
+// newest can open a window again.
+
+const (
+	envelopeContext = "safe-socket/envelope/v2"
+
+	// DefaultEnvelopeMaxAge is the clock skew tolerated when EnvelopeMaxAge is 0.
+	DefaultEnvelopeMaxAge = 30 * time.Second
+
+	// ReplayWindowSize is the number of counters tracked behind the highest one seen.
+	ReplayWindowSize = 64
+
+	// maxTrackedSenders bounds the windows of a guard: past it, idle windows are
+	// pruned, then the least recently seen one is evicted.
+	maxTrackedSenders = 4096
+)
+
+var (
+	ErrEnvelopeUnsigned = errors.New("envelope is not authenticated")
+	ErrEnvelopeForged   = errors.New("envelope authentication failed")
+	ErrEnvelopeStale    = errors.New("envelope timestamp outside the allowed window")
+	ErrEnvelopeReplayed = errors.New("envelope replayed")
+)
+
+// envelopeSession numbers the envelopes (or udp-secure datagrams) of one sending socket.
+type envelopeSession struct {
+	once    sync.Once
+	epoch   uint64
+	counter atomic.Uint64
+}
+
+// next returns the session epoch, drawn on first use, and the next counter.
+func (s *envelopeSession) next() (epoch, counter uint64) {
+	s.once.Do(func() {
+		var b [8]byte
+		_, _ = rand.Read(b[:])
+		s.epoch = binary.BigEndian.Uint64(b[:])
+	})
+	return s.epoch, s.counter.Add(1)
+}
+
+// -----------------------------------------------------------------------------
+
+// ReplayGuard tracks the sliding replay window of every sender epoch. One guard must be
+// shared by all the connections receiving from the same senders (e.g. a UDP server).
+type ReplayGuard struct {
+	mu      sync.Mutex
+	maxAge  time.Duration
+	senders map[replayKey]*replayWindow
+	floor   int64 // Newest timestamp of the evicted windows: older unknown senders are replays
+}
+
+type replayKey struct {
+	sender string
+	epoch  uint64
+}
+
+type replayWindow struct {
+	highest  uint64
+	bitmap   uint64 // bit i set = counter (highest - i) seen
+	newest   int64  // Newest timestamp accepted
+	lastSeen time.Time
+}
+
+// NewReplayGuard creates a guard accepting timestamps within maxAge of the local clock
+// (DefaultEnvelopeMaxAge if <= 0).
+func NewReplayGuard(maxAge time.Duration) *ReplayGuard {
+	if maxAge <= 0 {
+		maxAge = DefaultEnvelopeMaxAge
+	}
+	return &ReplayGuard{maxAge: maxAge, senders: make(map[replayKey]*replayWindow)}
+}
+
+// check validates the timestamp and records the counter in the window of (sender, epoch),
+// rejecting replays.
+func (g *ReplayGuard) check(sender string, epoch uint64, timestamp int64, counter uint64) error {
+	now := time.Now()
+	if age := now.Sub(time.Unix(0, timestamp)); age > g.maxAge || age < -g.maxAge {
+		return ErrEnvelopeStale
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := replayKey{sender, epoch}
+	w, ok := g.senders[key]
+	if !ok {
+		if timestamp <= g.floor {
+			return ErrEnvelopeReplayed // Its window may have been evicted
+		}
+		if len(g.senders) >= maxTrackedSenders {
+			g.prune(now)
+		}
+		if len(g.senders) >= maxTrackedSenders {
+			g.evict()
+		}
+		g.senders[key] = &replayWindow{highest: counter, bitmap: 1, newest: timestamp, lastSeen: now}
+		return nil
+	}
+
+	switch {
+	case counter > w.highest:
+		shift := counter - w.highest
+		if shift >= ReplayWindowSize {
+			w.bitmap = 0
+		} else {
+			w.bitmap <<= shift
+		}
+		w.bitmap |= 1
+		w.highest = counter
+	case w.highest-counter >= ReplayWindowSize:
+		return ErrEnvelopeReplayed // Too old to tell: treat as replay
+	default:
+		bit := uint64(1) << (w.highest - counter)
+		if w.bitmap&bit != 0 {
+			return ErrEnvelopeReplayed
+		}
+		w.bitmap |= bit
+	}
+	w.newest = max(w.newest, timestamp)
+	w.lastSeen = now
+	return nil
+}
+
+// prune forgets the windows idle for longer than twice the max age. A packet can be
+// up to maxAge in the future when seen, then stays fresh for maxAge more: only past
+// that are all the packets of the window stale.
+func (g *ReplayGuard) prune(now time.Time) {
+	for key, w := range g.senders {
+		if now.Sub(w.lastSeen) > 2*g.maxAge {
+			delete(g.senders, key)
+		}
+	}
+}
+
+// evict forgets the least recently seen window, raising the floor to its newest
+// timestamp: its packets cannot open a new window after that.
+func (g *ReplayGuard) evict() {
+	var oldest replayKey
+	var ow *replayWindow
+	for key, w := range g.senders {
+		if ow == nil || w.lastSeen.Before(ow.lastSeen) {
+			oldest, ow = key, w
+		}
+	}
+	if ow != nil {
+		g.floor = max(g.floor, ow.newest)
+		delete(g.senders, oldest)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// sealEnvelope signs an envelope whose senderID and payload are already set.
+func sealEnvelope(envelope schemas.PacketEnvelope, config models.SocketConfig, session *envelopeSession) error {
+	keyID, key, err := selectKey(config)
+	if err != nil {
+		return err
+	}
+	epoch, counter := session.next()
+	envelope.SetTimestamp(time.Now().UnixNano())
+	envelope.SetEpoch(epoch)
+	envelope.SetCounter(counter)
+	_ = envelope.SetKeyId(keyID)
+	return envelope.SetMac(envelopeMAC(key, envelope))
+}
+
+// envelopeMAC computes the MAC over every field of the envelope but the MAC itself.
+func envelopeMAC(key []byte, envelope schemas.PacketEnvelope) []byte {
+	senderID, _ := envelope.SenderID()
+	keyID, _ := envelope.KeyId()
+	payload, _ := envelope.Payload()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(envelopeContext))
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(senderID)))
+	mac.Write(buf[:4])
+	mac.Write([]byte(senderID))
+	binary.BigEndian.PutUint64(buf[:], uint64(envelope.Timestamp()))
+	mac.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], envelope.Epoch())
+	mac.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], envelope.Counter())
+	mac.Write(buf[:])
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(keyID)))
+	mac.Write(buf[:4])
+	mac.Write([]byte(keyID))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// -----------------------------------------------------------------------------
+
+// DecapsulateAuthenticated unwraps a signed PacketEnvelope, verifying its MAC against
+// the keyring, then its freshness and counter against the replay guard.
+func (p *HelloProtocol) DecapsulateAuthenticated(packet []byte, config models.SocketConfig, guard *ReplayGuard) ([]byte, *schemas.HelloMsg, error) {
+	msg, err := capnp.Unmarshal(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+	envelope, err := schemas.ReadRootPacketEnvelope(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 1. Authenticity
+	if !envelope.HasMac() {
+		return nil, nil, ErrEnvelopeUnsigned
+	}
+	keyID, _ := envelope.KeyId()
+	key, ok := config.AuthKeys[keyID]
+	received, _ := envelope.Mac()
+	if !ok || !hmac.Equal(received, envelopeMAC(key, envelope)) {
+		return nil, nil, ErrEnvelopeForged
+	}
+
+	// 2. Freshness & Replay (only once the fields are known to be genuine)
+	senderID, _ := envelope.SenderID()
+	if err := guard.check(senderID, envelope.Epoch(), envelope.Timestamp(), envelope.Counter()); err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := envelope.Payload()
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, senderIdentity(senderID), nil
+}
+
+// senderIdentity builds the partial HelloMsg exposed for an envelope sender.
+func senderIdentity(senderID string) *schemas.HelloMsg {
+	_, metaSeg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	helloMsg, _ := schemas.NewRootHelloMsg(metaSeg)
+	_ = helloMsg.SetFromName(senderID)
+	return &helloMsg
+}
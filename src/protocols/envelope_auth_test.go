@@ -0,0 +1,47 @@
+package protocols
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReplayGuardBounds(t *testing.T) {
+	t.Run("FutureSkewOutlivesPrune", func(t *testing.T) {
+		// A packet stamped maxAge ahead stays fresh for 2*maxAge: pruning its window
+		// after maxAge of idleness would let it through again
+		maxAge := 50 * time.Millisecond
+		g := NewReplayGuard(maxAge)
+		ts := time.Now().Add(40 * time.Millisecond).UnixNano()
+		if err := g.check("sender", 1, ts, 1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		g.mu.Lock()
+		g.prune(time.Now())
+		g.mu.Unlock()
+		if err := g.check("sender", 1, ts, 1); !errors.Is(err, ErrEnvelopeReplayed) {
+			t.Errorf("expected ErrEnvelopeReplayed, got %v", err)
+		}
+	})
+
+	t.Run("HardCap", func(t *testing.T) {
+		g := NewReplayGuard(time.Minute)
+		base := time.Now().UnixNano()
+		for i := 0; i <= maxTrackedSenders; i++ {
+			if err := g.check("sender", uint64(i), base+int64(i), 1); err != nil {
+				t.Fatalf("sender epoch %d: %v", i, err)
+			}
+		}
+		if n := len(g.senders); n > maxTrackedSenders {
+			t.Errorf("expected at most %d windows, got %d", maxTrackedSenders, n)
+		}
+		// The evicted (least recently seen) window cannot be replayed
+		if err := g.check("sender", 0, base, 1); !errors.Is(err, ErrEnvelopeReplayed) {
+			t.Errorf("expected ErrEnvelopeReplayed, got %v", err)
+		}
+		if err := g.check("sender", maxTrackedSenders+1, time.Now().UnixNano(), 1); err != nil {
+			t.Errorf("expected a new sender epoch to pass, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package protocols
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// ErrHandshakeRejected matches (errors.Is) any HandshakeRejectedError.
+var ErrHandshakeRejected = errors.New("handshake rejected by server")
+
+// -----------------------------------------------------------------------------
+
+// HandshakeError reports a Hello handshake that failed before a verdict was received
+// (I/O error, malformed reply, impossible negotiation...).
+type HandshakeError struct {
+	Op  string // Step that failed: "write hello", "read reply", "negotiate"...
+	Err error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("hello handshake: %s: %v", e.Op, e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// -----------------------------------------------------------------------------
+
+// HandshakeRejectedError is returned on the client when the server refused the connection.
+type HandshakeRejectedError struct {
+	Reason string
+	Server *schemas.HelloMsg // Identity of the refusing server
+}
+
+func (e *HandshakeRejectedError) Error() string {
+	if e.Reason == "" {
+		return ErrHandshakeRejected.Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrHandshakeRejected, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrHandshakeRejected) match any HandshakeRejectedError.
+func (e *HandshakeRejectedError) Is(target error) bool {
+	return target == ErrHandshakeRejected
+}
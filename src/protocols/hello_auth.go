@@ -0,0 +1,169 @@
+package protocols
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"capnproto.org/go/capnp/v3"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+)
+
+// Hello Challenge-Response
+//
+// When the server has a keyring (SocketConfig.AuthKeys), the client's HelloMsg only
+// claims an identity. The server answers with a challenge HelloMsg (status=challenge,
+// random nonce) and the client must send back {keyId, proof} where
+//
+//	proof = HMAC-SHA256(key, authContext | nonce | len|fromName | len|fromHost | len|fromPublicIP)
+//
+// The nonce is fresh for every connection, so a captured proof cannot be replayed.
+// Only clients offering a frame version (i.e. expecting replies) can be challenged.
+
+const (
+	authContext = "safe-socket/hello-auth/v1"
+	nonceSize   = 32
+)
+
+var (
+	// ErrAuthRequired is returned when a server challenges a client that has no key.
+	ErrAuthRequired = errors.New("server requires authentication but no key is configured")
+	// ErrAuthFailed is the verdict sent to clients whose proof does not verify.
+	ErrAuthFailed = errors.New("authentication failed")
+)
+
+// -----------------------------------------------------------------------------
+
+// Authenticate runs the server side of the challenge-response (Server). It is a no-op
+// without a keyring. On failure the caller should reject the client with the error.
+func (p *HelloProtocol) Authenticate(conn interfaces.TransportConnection, client *schemas.HelloMsg, profile interfaces.SocketProfile, config models.SocketConfig) error {
+	if len(config.AuthKeys) == 0 {
+		return nil
+	}
+	if client.FrameVersion() == 0 {
+		// Legacy clients never read a reply, hence cannot answer a challenge
+		return ErrAuthFailed
+	}
+
+	// 1. Send Challenge
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	msg, challenge, err := newHello(conn, profile, config, 0)
+	if err != nil {
+		return err
+	}
+	challenge.SetStatus(schemas.HelloStatus_challenge)
+	_ = challenge.SetNonce(nonce)
+	data, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+
+	// 2. Verify Answer
+	answer, err := readHello(conn)
+	if err != nil {
+		return err
+	}
+	keyID, _ := answer.KeyId()
+	proof, _ := answer.Proof()
+	key, ok := config.AuthKeys[keyID]
+	if !ok || !hmac.Equal(proof, computeProof(key, nonce, client)) {
+		return ErrAuthFailed
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// answerChallenge sends the client's proof for a challenge HelloMsg (Client).
+func answerChallenge(conn interfaces.TransportConnection, challenge *schemas.HelloMsg, sent []byte, config models.SocketConfig) error {
+	keyID, key, err := selectKey(config)
+	if err != nil {
+		return err
+	}
+	nonce, err := challenge.Nonce()
+	if err != nil || len(nonce) != nonceSize {
+		return errors.New("malformed challenge")
+	}
+
+	// The proof covers the identity exactly as the server received it
+	sentMsg, err := capnp.Unmarshal(sent)
+	if err != nil {
+		return err
+	}
+	ownHello, err := schemas.ReadRootHelloMsg(sentMsg)
+	if err != nil {
+		return err
+	}
+
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return err
+	}
+	answer, err := schemas.NewRootHelloMsg(seg)
+	if err != nil {
+		return err
+	}
+	_ = answer.SetKeyId(keyID)
+	_ = answer.SetProof(computeProof(key, nonce, &ownHello))
+	data, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(data)
+	return err
+}
+
+// selectKey returns the key a client signs with: AuthKeyID, or the only key of the ring.
+func selectKey(config models.SocketConfig) (string, []byte, error) {
+	if config.AuthKeyID != "" {
+		key, ok := config.AuthKeys[config.AuthKeyID]
+		if !ok {
+			return "", nil, fmt.Errorf("auth key %q not found in keyring", config.AuthKeyID)
+		}
+		return config.AuthKeyID, key, nil
+	}
+	switch len(config.AuthKeys) {
+	case 0:
+		return "", nil, ErrAuthRequired
+	case 1:
+		for id, key := range config.AuthKeys {
+			return id, key, nil
+		}
+	}
+	ids := make([]string, 0, len(config.AuthKeys))
+	for id := range config.AuthKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return "", nil, fmt.Errorf("keyring holds %d keys %v: set AuthKeyID", len(ids), ids)
+}
+
+// computeProof binds the nonce to the identity claimed in the client's HelloMsg.
+func computeProof(key, nonce []byte, hello *schemas.HelloMsg) []byte {
+	name, _ := hello.FromName()
+	host, _ := hello.FromHost()
+	publicIP, _ := hello.FromPublicIP()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(authContext))
+	mac.Write(nonce)
+	for _, field := range []string{name, host, publicIP} {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(field)))
+		mac.Write(size[:])
+		mac.Write([]byte(field))
+	}
+	return mac.Sum(nil)
+}
@@ -1,16 +1,50 @@
 package protocols
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"capnproto.org/go/capnp/v3"
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
 	"github.com/Bastien-Antigravity/safe-socket/src/models"
 	"github.com/Bastien-Antigravity/safe-socket/src/schemas"
+	"github.com/Bastien-Antigravity/safe-socket/src/tracing"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
 )
 
+// DefaultHandshakeTimeout bounds a handshake when neither the config nor the profile
+// sets a timeout.
+const DefaultHandshakeTimeout = 5 * time.Second
+
+// ErrNoHelloReply is returned (wrapped in a *HandshakeError) when the server did not
+// answer the Hello within the handshake timeout, as servers predating frame version
+// negotiation never do: such servers need LegacyFraming on the client.
+var ErrNoHelloReply = errors.New("no Hello reply from the server (set LegacyFraming for servers predating frame negotiation)")
+
+// HandshakeTimeout returns the time allowed for a handshake: config.HandshakeTimeout,
+// else the profile's connect timeout, else DefaultHandshakeTimeout.
+func HandshakeTimeout(profile interfaces.SocketProfile, config models.SocketConfig) time.Duration {
+	if config.HandshakeTimeout > 0 {
+		return config.HandshakeTimeout
+	}
+	if ms := profile.GetConnectTimeout(); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return DefaultHandshakeTimeout
+}
+
+// -----------------------------------------------------------------------------
+
 // HelloProtocol implements the "Initiate" logic.
-type HelloProtocol struct{}
+type HelloProtocol struct {
+	// Tracer records a tracing.SpanHandshake span per handshake (nil = none).
+	Tracer tracing.Tracer
+
+	envelopes envelopeSession // Epoch & counter of signed envelopes
+}
 
 // -----------------------------------------------------------------------------
 
@@ -21,7 +55,171 @@ func NewHelloProtocol() interfaces.Protocol {
 // -----------------------------------------------------------------------------
 
 // Initiate executes the handshake sequence or protocol logic (Client).
-func (p *HelloProtocol) Initiate(conn interfaces.TransportConnection, profile interfaces.SocketProfile, config models.SocketConfig) error {
+// When the transport supports typed frames, the client offers CurrentFrameVersion and
+// waits for the server's HelloMsg carrying the verdict and the agreed version, then
+// switches to it. It returns the server identity (nil with LegacyFraming, as legacy
+// servers never reply). A refusal is returned as a *HandshakeRejectedError; no reply
+// within HandshakeTimeout as ErrNoHelloReply.
+func (p *HelloProtocol) Initiate(conn interfaces.TransportConnection, profile interfaces.SocketProfile, config models.SocketConfig) (*schemas.HelloMsg, error) {
+	return p.InitiateContext(context.Background(), conn, profile, config)
+}
+
+// InitiateContext is Initiate, with the handshake span recorded as a child of ctx.
+func (p *HelloProtocol) InitiateContext(ctx context.Context, conn interfaces.TransportConnection, profile interfaces.SocketProfile, config models.SocketConfig) (server *schemas.HelloMsg, err error) {
+	_, span := tracing.Start(p.Tracer, ctx, tracing.SpanHandshake, tracing.String("role", "client"))
+	defer func() {
+		if server != nil {
+			name, _ := server.FromName()
+			span.SetAttributes(tracing.String("peer", name), tracing.Int("frame_version", int(server.FrameVersion())))
+		}
+		span.End(err)
+	}()
+	return p.initiate(conn, profile, config)
+}
+
+func (p *HelloProtocol) initiate(conn interfaces.TransportConnection, profile interfaces.SocketProfile, config models.SocketConfig) (*schemas.HelloMsg, error) {
+	// 1. Offer the highest frame version we speak (0 = legacy, no reply expected),
+	// and trace contexts when the transport can carry them
+	control := transports.ControlOf(conn)
+	traced := transports.TraceContextOf(conn)
+	var offer uint8
+	if control != nil && !config.LegacyFraming {
+		offer = transports.CurrentFrameVersion
+	}
+
+	msg, helloMsg, err := newHello(conn, profile, config, offer)
+	if err != nil {
+		return nil, &HandshakeError{Op: "build hello", Err: err}
+	}
+	helloMsg.SetTraceContext(offer != transports.FrameVersionLegacy && traced != nil)
+	data, err := msg.Marshal()
+	if err != nil {
+		return nil, &HandshakeError{Op: "build hello", Err: err}
+	}
+
+	// 2. Write Data
+	if _, err = conn.Write(data); err != nil {
+		return nil, &HandshakeError{Op: "write hello", Err: err}
+	}
+	if offer == transports.FrameVersionLegacy {
+		return nil, nil
+	}
+
+	// 3. Read the server's reply, within the handshake timeout (transports with an idle
+	// timeout may bound each read sooner)
+	_ = conn.SetReadDeadline(time.Now().Add(HandshakeTimeout(profile, config)))
+	defer func() { _ = conn.SetReadDeadline(time.Time{}) }()
+	reply, err := readHello(conn)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", ErrNoHelloReply, err)
+		}
+		return nil, &HandshakeError{Op: "read reply", Err: err}
+	}
+
+	// 3b. Prove our identity if the server challenges it (see hello_auth.go)
+	if reply.Status() == schemas.HelloStatus_challenge {
+		if err := answerChallenge(conn, reply, data, config); err != nil {
+			return nil, &HandshakeError{Op: "authenticate", Err: err}
+		}
+		if reply, err = readHello(conn); err != nil {
+			return nil, &HandshakeError{Op: "read reply", Err: err}
+		}
+	}
+	if reply.Status() == schemas.HelloStatus_rejected {
+		reason, _ := reply.Reason()
+		return nil, &HandshakeRejectedError{Reason: reason, Server: reply}
+	}
+
+	// 4. Switch to the agreed frame version
+	agreed := reply.FrameVersion()
+	if agreed > offer {
+		return nil, &HandshakeError{Op: "negotiate", Err: fmt.Errorf("server chose frame version %d, offered %d", agreed, offer)}
+	}
+	if err := control.SetFrameVersion(agreed); err != nil {
+		return nil, &HandshakeError{Op: "negotiate", Err: err}
+	}
+	if reply.TraceContext() && traced != nil {
+		if err := traced.EnableTraceContext(); err != nil {
+			return nil, &HandshakeError{Op: "negotiate", Err: err}
+		}
+	}
+	return reply, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// WaitInitiation waits for a HelloMsg from the client and unmarshals it.
+func (p *HelloProtocol) WaitInitiation(conn interfaces.TransportConnection) (*schemas.HelloMsg, error) {
+	return p.WaitInitiationContext(context.Background(), conn)
+}
+
+// WaitInitiationContext is WaitInitiation, with the handshake span recorded as a child of ctx.
+func (p *HelloProtocol) WaitInitiationContext(ctx context.Context, conn interfaces.TransportConnection) (client *schemas.HelloMsg, err error) {
+	_, span := tracing.Start(p.Tracer, ctx, tracing.SpanHandshake, tracing.String("role", "server"))
+	defer func() {
+		if client != nil {
+			name, _ := client.FromName()
+			span.SetAttributes(tracing.String("peer", name), tracing.Int("frame_version", int(client.FrameVersion())))
+		}
+		span.End(err)
+	}()
+	return readHello(conn)
+}
+
+// -----------------------------------------------------------------------------
+
+// Respond answers a client HelloMsg with the server's own HelloMsg and verdict.
+// On acceptance both sides switch to the highest frame version they (and the transport)
+// support. Legacy clients (frame version 0) expect no reply and get none: a rejected
+// legacy client only learns about it when the connection is closed.
+func (p *HelloProtocol) Respond(conn interfaces.TransportConnection, client *schemas.HelloMsg, profile interfaces.SocketProfile, config models.SocketConfig, verdict error) error {
+	offer := client.FrameVersion()
+	if offer == transports.FrameVersionLegacy {
+		return nil
+	}
+
+	// Agree on the highest version both sides (and the transport) support
+	control := transports.ControlOf(conn)
+	agreed := min(offer, transports.CurrentFrameVersion)
+	if control == nil {
+		agreed = transports.FrameVersionLegacy
+	}
+	traced := transports.TraceContextOf(conn)
+	carryTrace := client.TraceContext() && agreed != transports.FrameVersionLegacy && traced != nil
+
+	msg, helloMsg, err := newHello(conn, profile, config, agreed)
+	if err != nil {
+		return err
+	}
+	helloMsg.SetTraceContext(carryTrace && verdict == nil)
+	if verdict != nil {
+		helloMsg.SetStatus(schemas.HelloStatus_rejected)
+		_ = helloMsg.SetReason(verdict.Error())
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	if verdict == nil && control != nil {
+		if err := control.SetFrameVersion(agreed); err != nil {
+			return err
+		}
+		if carryTrace {
+			return traced.EnableTraceContext()
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// newHello builds a HelloMsg describing the local side of conn, ready to be completed.
+func newHello(conn interfaces.TransportConnection, profile interfaces.SocketProfile, config models.SocketConfig, frameVersion uint8) (*capnp.Message, schemas.HelloMsg, error) {
 	hostname, _ := os.Hostname()
 
 	// PublicIP is optional for the Hello Protocol
@@ -33,12 +231,12 @@ func (p *HelloProtocol) Initiate(conn interfaces.TransportConnection, profile in
 	// Cap'n Proto Message Construction
 	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
 	if err != nil {
-		return err
+		return nil, schemas.HelloMsg{}, err
 	}
 
 	helloMsg, err := schemas.NewRootHelloMsg(seg)
 	if err != nil {
-		return err
+		return nil, schemas.HelloMsg{}, err
 	}
 
 	// Dynamic Address Resolution from Transport
@@ -50,22 +248,15 @@ func (p *HelloProtocol) Initiate(conn interfaces.TransportConnection, profile in
 	_ = helloMsg.SetFromAddress(localAddr) // Actual bound address
 	_ = helloMsg.SetToAddress(remoteAddr)  // Target address
 	_ = helloMsg.SetFromPublicIP(publicIP)
+	helloMsg.SetFrameVersion(frameVersion)
 
-	// Marshal to bytes
-	data, err := msg.Marshal()
-	if err != nil {
-		return err
-	}
-
-	// 1. Write Data
-	_, err = conn.Write(data)
-	return err
+	return msg, helloMsg, nil
 }
 
 // -----------------------------------------------------------------------------
 
-// WaitInitiation waits for a HelloMsg from the client and unmarshals it.
-func (p *HelloProtocol) WaitInitiation(conn interfaces.TransportConnection) (*schemas.HelloMsg, error) {
+// readHello reads one frame from conn and unmarshals it as a HelloMsg.
+func readHello(conn interfaces.TransportConnection) (*schemas.HelloMsg, error) {
 	// 1. Prepare Buffer
 	// Use a larger buffer (4KB) to avoid io.ErrShortBuffer from framed transport
 	// if the message contains long strings (host, address, etc).
@@ -78,10 +269,7 @@ func (p *HelloProtocol) WaitInitiation(conn interfaces.TransportConnection) (*sc
 	}
 
 	// 3. Unmarshal
-	// Cap'n Proto requires the full buffer or a stream.
 	// With FramedTCP, we have the full message in buf[:n].
-	// However, we need to handle proper unmarshalling from bytes.
-
 	msg, err := capnp.Unmarshal(buf[:n])
 	if err != nil {
 		return nil, err
@@ -115,6 +303,13 @@ func (p *HelloProtocol) Encapsulate(data []byte, profile interfaces.SocketProfil
 	_ = envelope.SetSenderID(profile.GetName())
 	_ = envelope.SetPayload(data)
 
+	// Sign when a keyring is configured (see envelope_auth.go)
+	if len(config.AuthKeys) > 0 {
+		if err := sealEnvelope(envelope, config, &p.envelopes); err != nil {
+			return nil, err
+		}
+	}
+
 	return msg.Marshal()
 }
 
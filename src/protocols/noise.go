@@ -0,0 +1,351 @@
+package protocols
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Noise Protocol Framework (https://noiseprotocol.org/noise.html), revision 34.
+//
+// Only what the secure profiles need is implemented: the 25519 DH, AESGCM cipher and
+// SHA256 hash functions (standard library only), and the XX (interactive, mutual
+// authentication) and X (one-way, one message per datagram) handshake patterns.
+
+const (
+	noiseDHLen   = 32
+	noiseTagSize = 16
+
+	noisePrologue = "safe-socket/secure/v1"
+)
+
+var (
+	// ErrSecureDecrypt is returned when a handshake message or frame fails authentication.
+	ErrSecureDecrypt = errors.New("secure frame authentication failed")
+
+	errNoiseShortMessage = errors.New("noise message too short")
+	errNoiseNonceLimit   = errors.New("noise nonce exhausted")
+)
+
+// noiseToken is a handshake pattern token.
+type noiseToken int
+
+const (
+	tokenE noiseToken = iota
+	tokenS
+	tokenEE
+	tokenES
+	tokenSE
+	tokenSS
+)
+
+// noisePattern lists the tokens of each message, alternating initiator -> responder.
+type noisePattern struct {
+	name     string
+	preS     bool // Responder static key known beforehand ("<- s")
+	messages [][]noiseToken
+}
+
+var (
+	noiseXX = noisePattern{
+		name: "Noise_XX_25519_AESGCM_SHA256",
+		messages: [][]noiseToken{
+			{tokenE},
+			{tokenE, tokenEE, tokenS, tokenES},
+			{tokenS, tokenSE},
+		},
+	}
+	noiseX = noisePattern{
+		name:     "Noise_X_25519_AESGCM_SHA256",
+		preS:     true,
+		messages: [][]noiseToken{{tokenE, tokenES, tokenS, tokenSS}},
+	}
+)
+
+// -----------------------------------------------------------------------------
+// CipherState
+// -----------------------------------------------------------------------------
+
+type noiseCipher struct {
+	aead cipher.AEAD // nil until a key is set
+	n    uint64
+}
+
+func newNoiseCipher(key []byte) *noiseCipher {
+	block, _ := aes.NewCipher(key[:32]) // 32-byte key: cannot fail
+	aead, _ := cipher.NewGCM(block)
+	return &noiseCipher{aead: aead}
+}
+
+// nonce encodes n as 32 zero bits followed by a big-endian uint64 (AESGCM rule).
+func (c *noiseCipher) nonce() ([]byte, error) {
+	if c.n == math.MaxUint64 {
+		return nil, errNoiseNonceLimit
+	}
+	var nonce [12]byte
+	binary.BigEndian.PutUint64(nonce[4:], c.n)
+	return nonce[:], nil
+}
+
+func (c *noiseCipher) encrypt(ad, plaintext []byte) ([]byte, error) {
+	if c.aead == nil {
+		return plaintext, nil
+	}
+	nonce, err := c.nonce()
+	if err != nil {
+		return nil, err
+	}
+	c.n++
+	return c.aead.Seal(nil, nonce, plaintext, ad), nil
+}
+
+func (c *noiseCipher) decrypt(ad, ciphertext []byte) ([]byte, error) {
+	if c.aead == nil {
+		return ciphertext, nil
+	}
+	nonce, err := c.nonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrSecureDecrypt
+	}
+	c.n++
+	return plaintext, nil
+}
+
+// -----------------------------------------------------------------------------
+// SymmetricState
+// -----------------------------------------------------------------------------
+
+type noiseSymmetric struct {
+	cs noiseCipher
+	ck [32]byte
+	h  [32]byte
+}
+
+func (s *noiseSymmetric) init(protocolName string) {
+	if len(protocolName) <= sha256.Size {
+		copy(s.h[:], protocolName)
+	} else {
+		s.h = sha256.Sum256([]byte(protocolName))
+	}
+	s.ck = s.h
+}
+
+func (s *noiseSymmetric) mixHash(data []byte) {
+	hash := sha256.New()
+	hash.Write(s.h[:])
+	hash.Write(data)
+	hash.Sum(s.h[:0])
+}
+
+func (s *noiseSymmetric) mixKey(ikm []byte) {
+	ck, key := noiseHKDF(s.ck[:], ikm)
+	s.ck = ck
+	s.cs = *newNoiseCipher(key[:])
+}
+
+func (s *noiseSymmetric) encryptAndHash(plaintext []byte) ([]byte, error) {
+	ciphertext, err := s.cs.encrypt(s.h[:], plaintext)
+	if err != nil {
+		return nil, err
+	}
+	s.mixHash(ciphertext)
+	return ciphertext, nil
+}
+
+func (s *noiseSymmetric) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	plaintext, err := s.cs.decrypt(s.h[:], ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	s.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+// split derives the two transport ciphers (initiator->responder, responder->initiator).
+func (s *noiseSymmetric) split() (*noiseCipher, *noiseCipher) {
+	k1, k2 := noiseHKDF(s.ck[:], nil)
+	return newNoiseCipher(k1[:]), newNoiseCipher(k2[:])
+}
+
+// noiseHKDF is the two-output HKDF of the Noise specification.
+func noiseHKDF(chainingKey, ikm []byte) (out1, out2 [32]byte) {
+	mac := hmac.New(sha256.New, chainingKey)
+	mac.Write(ikm)
+	tempKey := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, tempKey)
+	mac.Write([]byte{0x01})
+	mac.Sum(out1[:0])
+
+	mac.Reset()
+	mac.Write(out1[:])
+	mac.Write([]byte{0x02})
+	mac.Sum(out2[:0])
+	return out1, out2
+}
+
+// -----------------------------------------------------------------------------
+// HandshakeState
+// -----------------------------------------------------------------------------
+
+type noiseHandshake struct {
+	pattern   noisePattern
+	initiator bool
+	step      int
+	sym       noiseSymmetric
+	s         *ecdh.PrivateKey // Local static
+	e         *ecdh.PrivateKey // Local ephemeral
+	rs        *ecdh.PublicKey  // Remote static
+	re        *ecdh.PublicKey  // Remote ephemeral
+}
+
+// newNoiseHandshake starts a handshake. rs is the responder static key for patterns
+// with a pre-message (initiator side), nil otherwise.
+func newNoiseHandshake(pattern noisePattern, initiator bool, prologue []byte, s *ecdh.PrivateKey, rs *ecdh.PublicKey) *noiseHandshake {
+	hs := &noiseHandshake{pattern: pattern, initiator: initiator, s: s, rs: rs}
+	hs.sym.init(pattern.name)
+	hs.sym.mixHash(prologue)
+	if pattern.preS {
+		if initiator {
+			hs.sym.mixHash(rs.Bytes())
+		} else {
+			hs.sym.mixHash(s.PublicKey().Bytes())
+		}
+	}
+	return hs
+}
+
+// done reports whether every message of the pattern was processed.
+func (hs *noiseHandshake) done() bool {
+	return hs.step == len(hs.pattern.messages)
+}
+
+func (hs *noiseHandshake) dh(local *ecdh.PrivateKey, remote *ecdh.PublicKey) error {
+	secret, err := local.ECDH(remote)
+	if err != nil {
+		return err // Low-order point
+	}
+	hs.sym.mixKey(secret)
+	return nil
+}
+
+// mixDH performs the DH of a two-letter token from this side's point of view.
+func (hs *noiseHandshake) mixDH(token noiseToken) error {
+	switch token {
+	case tokenEE:
+		return hs.dh(hs.e, hs.re)
+	case tokenSS:
+		return hs.dh(hs.s, hs.rs)
+	case tokenES: // initiator ephemeral, responder static
+		if hs.initiator {
+			return hs.dh(hs.e, hs.rs)
+		}
+		return hs.dh(hs.s, hs.re)
+	default: // tokenSE: initiator static, responder ephemeral
+		if hs.initiator {
+			return hs.dh(hs.s, hs.re)
+		}
+		return hs.dh(hs.e, hs.rs)
+	}
+}
+
+// writeMessage produces the next handshake message carrying payload.
+func (hs *noiseHandshake) writeMessage(payload []byte) ([]byte, error) {
+	var msg []byte
+	for _, token := range hs.pattern.messages[hs.step] {
+		switch token {
+		case tokenE:
+			if hs.e == nil { // Only preset by known-answer tests
+				e, err := ecdh.X25519().GenerateKey(rand.Reader)
+				if err != nil {
+					return nil, err
+				}
+				hs.e = e
+			}
+			msg = append(msg, hs.e.PublicKey().Bytes()...)
+			hs.sym.mixHash(hs.e.PublicKey().Bytes())
+		case tokenS:
+			ct, err := hs.sym.encryptAndHash(hs.s.PublicKey().Bytes())
+			if err != nil {
+				return nil, err
+			}
+			msg = append(msg, ct...)
+		default:
+			if err := hs.mixDH(token); err != nil {
+				return nil, err
+			}
+		}
+	}
+	ct, err := hs.sym.encryptAndHash(payload)
+	if err != nil {
+		return nil, err
+	}
+	hs.step++
+	return append(msg, ct...), nil
+}
+
+// readMessage processes the next handshake message and returns its payload.
+func (hs *noiseHandshake) readMessage(msg []byte) ([]byte, error) {
+	for _, token := range hs.pattern.messages[hs.step] {
+		switch token {
+		case tokenE:
+			if len(msg) < noiseDHLen {
+				return nil, errNoiseShortMessage
+			}
+			re, err := ecdh.X25519().NewPublicKey(msg[:noiseDHLen])
+			if err != nil {
+				return nil, err
+			}
+			hs.re = re
+			hs.sym.mixHash(msg[:noiseDHLen])
+			msg = msg[noiseDHLen:]
+		case tokenS:
+			size := noiseDHLen
+			if hs.sym.cs.aead != nil {
+				size += noiseTagSize
+			}
+			if len(msg) < size {
+				return nil, errNoiseShortMessage
+			}
+			raw, err := hs.sym.decryptAndHash(msg[:size])
+			if err != nil {
+				return nil, err
+			}
+			rs, err := ecdh.X25519().NewPublicKey(raw)
+			if err != nil {
+				return nil, err
+			}
+			hs.rs = rs
+			msg = msg[size:]
+		default:
+			if err := hs.mixDH(token); err != nil {
+				return nil, err
+			}
+		}
+	}
+	payload, err := hs.sym.decryptAndHash(msg)
+	if err != nil {
+		return nil, err
+	}
+	hs.step++
+	return payload, nil
+}
+
+// ciphers returns the (send, receive) transport ciphers once the handshake is done.
+func (hs *noiseHandshake) ciphers() (send, recv *noiseCipher) {
+	c1, c2 := hs.sym.split()
+	if hs.initiator {
+		return c1, c2
+	}
+	return c2, c1
+}
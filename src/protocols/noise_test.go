@@ -0,0 +1,175 @@
+package protocols
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// Known-answer vectors of github.com/flynn/noise (vectors.txt, cacophony format). Every
+// vector uses the same static and ephemeral keys; its messages are the handshake ones,
+// then transport ones alternating initiator -> responder and responder -> initiator.
+const (
+	vectorInitStatic    = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	vectorRespStatic    = "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+	vectorInitEphemeral = "202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f"
+	vectorRespEphemeral = "4142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f60"
+)
+
+var noiseVectors = []struct {
+	pattern  noisePattern
+	prologue string
+	messages [][2]string // Payload, ciphertext (hex)
+}{
+	{
+		pattern:  noiseXX,
+		prologue: "",
+		messages: [][2]string{
+			{"746573745f6d73675f30", "358072d6365880d1aeea329adf9121383851ed21a28e3b75e965d0d2cd166254746573745f6d73675f30"},
+			{"746573745f6d73675f31", "64b101b1d0be5a8704bd078f9895001fc03e8e9f9522f188dd128d9846d484665393019dbd6f438795da206db0886610b26108e424142c2e9b5fd1f7ea70cde8c9f29dcec8d3ab554f4a5330657867fe4917917195c8cf360e08d6dc5f71baf875ec6e3bfc7afda4c9c2"},
+			{"746573745f6d73675f32", "e610eadc4b00c17708bf223f29a66f02342fbedf6c0044736544b9271821ae40232c55cd96d1350af861f6a04978f7d5e070c07602c6b84d25a331242a71c50ae31dd4c164267fd48bd2"},
+			{"79656c6c6f777375626d6172696e65", "9ea1da1ec3bfecfffab213e537ed1791bfa887dd9c631351b3f63d6315ab9a"},
+			{"7375626d6172696e6579656c6c6f77", "217c5111fad7afde33bd28abaff3def88a57ab50515115d23a10f28621f842"},
+		},
+	},
+	{
+		pattern:  noiseXX,
+		prologue: "notsecret",
+		messages: [][2]string{
+			{"746573745f6d73675f30", "358072d6365880d1aeea329adf9121383851ed21a28e3b75e965d0d2cd166254746573745f6d73675f30"},
+			{"746573745f6d73675f31", "64b101b1d0be5a8704bd078f9895001fc03e8e9f9522f188dd128d9846d484665393019dbd6f438795da206db0886610b26108e424142c2e9b5fd1f7ea70cde847f6866f15c3cd3f864f7ed682f1711a4917917195c8cf360e080035dfa88af5c6e9b820278e6016f7d7"},
+			{"746573745f6d73675f32", "e610eadc4b00c17708bf223f29a66f02342fbedf6c0044736544b9271821ae403bbe475185a4a265a50e1d43bdaeee7fe070c07602c6b84d25a3b4064af5be30115a052069038f5002a3"},
+			{"79656c6c6f777375626d6172696e65", "9ea1da1ec3bfecfffab213e537ed1791bfa887dd9c631351b3f63d6315ab9a"},
+			{"7375626d6172696e6579656c6c6f77", "217c5111fad7afde33bd28abaff3def88a57ab50515115d23a10f28621f842"},
+		},
+	},
+	{
+		pattern:  noiseX,
+		prologue: "",
+		messages: [][2]string{
+			{"746573745f6d73675f30", "358072d6365880d1aeea329adf9121383851ed21a28e3b75e965d0d2cd16625427b9e233a46e236bc3b949c842a23bd75b3d6d717dbf3aa4a3cfaa59a42e6a50f3540c9b1fdc8ca68fb6b8f9081e9b28ea4c05b652dbf8aff85830c628e63acd02c85425c685c650b07e"},
+			{"79656c6c6f777375626d6172696e65", "601398a290497a3ecf22851d05f53b34fa1fc4a47a0371df1f5c540a1ecf61"},
+			{"7375626d6172696e6579656c6c6f77", "04edfc327b91e91bb67f5a069e5afbf154ebcf196baf843dce5d22f58f04e7"},
+		},
+	},
+	{
+		pattern:  noiseX,
+		prologue: "notsecret",
+		messages: [][2]string{
+			{"746573745f6d73675f30", "358072d6365880d1aeea329adf9121383851ed21a28e3b75e965d0d2cd16625427b9e233a46e236bc3b949c842a23bd75b3d6d717dbf3aa4a3cfaa59a42e6a50e9a53f4b77ba9c212a5ca41f911c0991ea4c05b652dbf8aff858319c0516c6e9079711b89e419c5825b1"},
+			{"79656c6c6f777375626d6172696e65", "601398a290497a3ecf22851d05f53b34fa1fc4a47a0371df1f5c540a1ecf61"},
+			{"7375626d6172696e6579656c6c6f77", "04edfc327b91e91bb67f5a069e5afbf154ebcf196baf843dce5d22f58f04e7"},
+		},
+	},
+}
+
+func vectorKey(t *testing.T, hexKey string) *ecdh.PrivateKey {
+	t.Helper()
+	key, err := ecdh.X25519().NewPrivateKey(vectorHex(t, hexKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func vectorHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// -----------------------------------------------------------------------------
+
+func TestNoiseVectors(t *testing.T) {
+	for _, v := range noiseVectors {
+		t.Run(v.pattern.name+"/"+v.prologue, func(t *testing.T) {
+			is, rs := vectorKey(t, vectorInitStatic), vectorKey(t, vectorRespStatic)
+			var peer *ecdh.PublicKey
+			if v.pattern.preS {
+				peer = rs.PublicKey()
+			}
+			initiator := newNoiseHandshake(v.pattern, true, []byte(v.prologue), is, peer)
+			responder := newNoiseHandshake(v.pattern, false, []byte(v.prologue), rs, nil)
+			initiator.e = vectorKey(t, vectorInitEphemeral)
+			responder.e = vectorKey(t, vectorRespEphemeral)
+
+			handshake := len(v.pattern.messages)
+			var initSend, initRecv, respSend, respRecv *noiseCipher
+			for i, m := range v.messages {
+				payload, want := vectorHex(t, m[0]), vectorHex(t, m[1])
+
+				if i < handshake {
+					writer, reader := initiator, responder
+					if i%2 == 1 {
+						writer, reader = responder, initiator
+					}
+					got, err := writer.writeMessage(payload)
+					if err != nil {
+						t.Fatalf("message %d: write: %v", i, err)
+					}
+					if !bytes.Equal(got, want) {
+						t.Fatalf("message %d: expected %x, got %x", i, want, got)
+					}
+					read, err := reader.readMessage(got)
+					if err != nil {
+						t.Fatalf("message %d: read: %v", i, err)
+					}
+					if !bytes.Equal(read, payload) {
+						t.Fatalf("message %d: expected payload %x, got %x", i, payload, read)
+					}
+					continue
+				}
+
+				if initSend == nil {
+					if !initiator.done() || !responder.done() {
+						t.Fatal("handshake not done after its last message")
+					}
+					initSend, initRecv = initiator.ciphers()
+					respSend, respRecv = responder.ciphers()
+				}
+				enc, dec := initSend, respRecv
+				if (i-handshake)%2 == 1 {
+					enc, dec = respSend, initRecv
+				}
+				got, err := enc.encrypt(nil, payload)
+				if err != nil {
+					t.Fatalf("message %d: encrypt: %v", i, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("message %d: expected %x, got %x", i, want, got)
+				}
+				read, err := dec.decrypt(nil, got)
+				if err != nil || !bytes.Equal(read, payload) {
+					t.Fatalf("message %d: expected payload %x, got %x (%v)", i, payload, read, err)
+				}
+			}
+
+			if !bytes.Equal(responder.rs.Bytes(), is.PublicKey().Bytes()) {
+				t.Error("responder did not learn the initiator static key")
+			}
+			if !bytes.Equal(initiator.rs.Bytes(), rs.PublicKey().Bytes()) {
+				t.Error("initiator does not hold the responder static key")
+			}
+		})
+	}
+}
+
+func TestNoiseTampered(t *testing.T) {
+	rs := vectorKey(t, vectorRespStatic)
+	initiator := newNoiseHandshake(noiseX, true, nil, vectorKey(t, vectorInitStatic), rs.PublicKey())
+	msg, err := initiator.writeMessage([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg[len(msg)-1] ^= 1
+
+	responder := newNoiseHandshake(noiseX, false, nil, rs, nil)
+	if _, err := responder.readMessage(msg); !errors.Is(err, ErrSecureDecrypt) {
+		t.Errorf("expected ErrSecureDecrypt, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package protocols
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/models"
+)
+
+// Secure Profiles (tcp-secure, shm-secure, udp-secure)
+//
+// Connection-oriented transports run a Noise XX handshake right after connecting:
+//
+//	-> e
+//	<- e, ee, s, es
+//	-> s, se
+//
+// which authenticates both static keys, then every data frame is AES-256-GCM encrypted
+// with a per-direction key and implicit counter nonce. Heartbeat (empty) frames are not.
+//
+// UDP has no connection to handshake on: each datagram is a complete one-way Noise X
+// message (-> e, es, s, ss) to the recipient's static key, whose payload starts with
+// the random session epoch of the sending channel, the sender clock and a counter
+// monotonic within that session, checked against a ReplayGuard keyed on (static key, epoch).
+
+var (
+	ErrSecureKeyRequired  = errors.New("secure profiles require SecureStaticKey (32-byte X25519 private key)")
+	ErrSecurePeerRequired = errors.New("secure clients require SecurePeerKeys (the server public key)")
+	ErrPeerKeyUntrusted   = errors.New("peer static key is not trusted")
+	ErrSecurePeerUnknown  = errors.New("secure datagram peer unknown: nothing received yet")
+)
+
+// datagramHeaderSize is the [epoch:8][timestamp:8][counter:8] prefix of udp-secure payloads.
+const datagramHeaderSize = 24
+
+// -----------------------------------------------------------------------------
+
+// GenerateSecureKey returns a new X25519 static key pair for SecureStaticKey / SecurePeerKeys.
+func GenerateSecureKey() (private, public []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.Bytes(), key.PublicKey().Bytes(), nil
+}
+
+// SecurePublicKey returns the public key to share for a SecureStaticKey.
+func SecurePublicKey(private []byte) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return nil, ErrSecureKeyRequired
+	}
+	return key.PublicKey().Bytes(), nil
+}
+
+func staticKey(config models.SocketConfig) (*ecdh.PrivateKey, error) {
+	key, err := ecdh.X25519().NewPrivateKey(config.SecureStaticKey)
+	if err != nil {
+		return nil, ErrSecureKeyRequired
+	}
+	return key, nil
+}
+
+// trusted reports whether the remote static key is allowed (any key if the list is empty).
+func trusted(config models.SocketConfig, remote []byte) bool {
+	if len(config.SecurePeerKeys) == 0 {
+		return true
+	}
+	for _, key := range config.SecurePeerKeys {
+		if bytes.Equal(key, remote) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// SecureChannel encrypts the frames of an established secure connection.
+type SecureChannel interface {
+	// Seal encrypts one outgoing frame or datagram.
+	Seal(plaintext []byte) ([]byte, error)
+	// Open authenticates and decrypts one incoming frame or datagram.
+	Open(ciphertext []byte) ([]byte, error)
+	// PeerKey returns the authenticated static public key of the peer.
+	PeerKey() []byte
+}
+
+// -----------------------------------------------------------------------------
+
+// secureSession is the channel of a completed XX handshake.
+type secureSession struct {
+	send *noiseCipher
+	recv *noiseCipher
+	peer []byte
+}
+
+func (s *secureSession) Seal(plaintext []byte) ([]byte, error) { return s.send.encrypt(nil, plaintext) }
+func (s *secureSession) Open(ciphertext []byte) ([]byte, error) {
+	return s.recv.decrypt(nil, ciphertext)
+}
+func (s *secureSession) PeerKey() []byte { return s.peer }
+
+// SecureHandshake runs the Noise XX handshake over a connection-oriented transport.
+// The initiator (client) requires SecurePeerKeys; a responder with an empty list accepts
+// any client key. Callers must serialize Seal and the matching write (counter nonces).
+func SecureHandshake(conn interfaces.TransportConnection, config models.SocketConfig, initiator bool) (SecureChannel, error) {
+	s, err := staticKey(config)
+	if err != nil {
+		return nil, err
+	}
+	if initiator && len(config.SecurePeerKeys) == 0 {
+		return nil, ErrSecurePeerRequired
+	}
+
+	hs := newNoiseHandshake(noiseXX, initiator, []byte(noisePrologue), s, nil)
+	for !hs.done() {
+		writing := (hs.step%2 == 0) == initiator
+		if writing {
+			msg, err := hs.writeMessage(nil)
+			if err != nil {
+				return nil, fmt.Errorf("secure handshake: write message %d: %w", hs.step, err)
+			}
+			if _, err := conn.Write(msg); err != nil {
+				return nil, fmt.Errorf("secure handshake: write message %d: %w", hs.step, err)
+			}
+			continue
+		}
+
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("secure handshake: read message %d: %w", hs.step+1, err)
+		}
+		if _, err := hs.readMessage(msg); err != nil {
+			return nil, fmt.Errorf("secure handshake: read message %d: %w", hs.step+1, err)
+		}
+		// The peer static key is known as soon as it was read: refuse it early
+		if hs.rs != nil && !trusted(config, hs.rs.Bytes()) {
+			return nil, ErrPeerKeyUntrusted
+		}
+	}
+
+	send, recv := hs.ciphers()
+	return &secureSession{send: send, recv: recv, peer: hs.rs.Bytes()}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// DatagramChannel seals each datagram as a one-way Noise X message. Datagrams are
+// addressed to the configured server key (client) or to the sender of the last
+// datagram opened (server).
+type DatagramChannel struct {
+	config  models.SocketConfig
+	s       *ecdh.PrivateKey
+	guard   *ReplayGuard
+	session envelopeSession // Epoch & counter of the datagrams sealed
+
+	mu   sync.Mutex
+	peer *ecdh.PublicKey
+}
+
+// NewDatagramChannel creates the channel of a udp-secure socket. A client addresses
+// SecurePeerKeys[0]. Receivers of the same senders must share the guard.
+func NewDatagramChannel(config models.SocketConfig, initiator bool, guard *ReplayGuard) (*DatagramChannel, error) {
+	s, err := staticKey(config)
+	if err != nil {
+		return nil, err
+	}
+	c := &DatagramChannel{config: config, s: s, guard: guard}
+	if initiator {
+		if len(config.SecurePeerKeys) == 0 {
+			return nil, ErrSecurePeerRequired
+		}
+		if c.peer, err = ecdh.X25519().NewPublicKey(config.SecurePeerKeys[0]); err != nil {
+			return nil, fmt.Errorf("invalid server key: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *DatagramChannel) Seal(plaintext []byte) ([]byte, error) {
+	c.mu.Lock()
+	peer := c.peer
+	c.mu.Unlock()
+	if peer == nil {
+		return nil, ErrSecurePeerUnknown
+	}
+
+	epoch, counter := c.session.next()
+	payload := make([]byte, datagramHeaderSize+len(plaintext))
+	binary.BigEndian.PutUint64(payload[0:], epoch)
+	binary.BigEndian.PutUint64(payload[8:], uint64(time.Now().UnixNano()))
+	binary.BigEndian.PutUint64(payload[16:], counter)
+	copy(payload[datagramHeaderSize:], plaintext)
+
+	return newNoiseHandshake(noiseX, true, []byte(noisePrologue), c.s, peer).writeMessage(payload)
+}
+
+func (c *DatagramChannel) Open(ciphertext []byte) ([]byte, error) {
+	hs := newNoiseHandshake(noiseX, false, []byte(noisePrologue), c.s, nil)
+	payload, err := hs.readMessage(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	sender := hs.rs.Bytes()
+	if !trusted(c.config, sender) {
+		return nil, ErrPeerKeyUntrusted
+	}
+	if len(payload) < datagramHeaderSize {
+		return nil, errNoiseShortMessage
+	}
+
+	epoch := binary.BigEndian.Uint64(payload[0:])
+	timestamp := int64(binary.BigEndian.Uint64(payload[8:]))
+	counter := binary.BigEndian.Uint64(payload[16:])
+	if err := c.guard.check(hex.EncodeToString(sender), epoch, timestamp, counter); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.peer = hs.rs
+	c.mu.Unlock()
+	return payload[datagramHeaderSize:], nil
+}
+
+func (c *DatagramChannel) PeerKey() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peer == nil {
+		return nil
+	}
+	return c.peer.Bytes()
+}
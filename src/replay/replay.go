@@ -0,0 +1,218 @@
+// Package replay feeds a captured session (see the capture package) back into a live
+// peer, to reproduce offline what went over the wire.
+//
+// Play impersonates one end of the session over a raw transport connection: it writes
+// the frames that end sent, with their original header version and pacing, and waits
+// for each data message the other end sent in the capture before going on. Replies
+// are compared with the captured ones, but never required to match: handshakes carry
+// timestamps and nonces. Sessions protected by a challenge or a key exchange
+// (keyring-authenticated Hello, *-secure profiles) therefore cannot be replayed past
+// their handshake.
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/capture"
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// DefaultReadTimeout is how long Play waits for each message expected from the peer.
+const DefaultReadTimeout = 5 * time.Second
+
+// ErrNoSession is returned by Load when the file holds no such session.
+var ErrNoSession = errors.New("replay: no such session in the capture")
+
+// Session is one captured connection.
+type Session struct {
+	ID      uint32
+	Info    capture.Session
+	Records []capture.Record // Frames only, in capture order
+}
+
+// Options tune Play.
+type Options struct {
+	// Speed scales the pauses between frames: 1 = as captured, 2 = twice as fast,
+	// 0 = no pauses at all.
+	Speed float64
+
+	// ReadTimeout bounds the wait for each message expected from the peer
+	// (0 = DefaultReadTimeout).
+	ReadTimeout time.Duration
+}
+
+// Report sums up a replay.
+type Report struct {
+	Sent       int // Frames written
+	Received   int // Messages read from the peer
+	Mismatched int // Messages read that differ from the captured ones
+}
+
+// -----------------------------------------------------------------------------
+
+// Sessions lists the sessions of a capture, in the order they were opened.
+func Sessions(records []capture.Record) ([]*Session, error) {
+	var sessions []*Session
+	byID := make(map[uint32]*Session)
+	for _, r := range records {
+		switch r.Kind {
+		case capture.KindOpen:
+			info, err := capture.SessionOf(r)
+			if err != nil {
+				return nil, err
+			}
+			s := &Session{ID: r.Session, Info: info}
+			byID[r.Session] = s
+			sessions = append(sessions, s)
+		case capture.KindOutbound, capture.KindInbound:
+			if s := byID[r.Session]; s != nil {
+				s.Records = append(s.Records, r)
+			}
+		}
+	}
+	return sessions, nil
+}
+
+// Load reads the capture file at path and returns session id (0 = the first one).
+func Load(path string, id uint32) (*Session, error) {
+	records, err := capture.ReadAll(path)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := Sessions(records)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		if id == 0 || s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, ErrNoSession
+}
+
+// -----------------------------------------------------------------------------
+
+// sentBy reports whether r was written by the given end ("client" or "server").
+func (s *Session) sentBy(r capture.Record, role string) bool {
+	return (r.Kind == capture.KindOutbound) == (s.Info.Role == role)
+}
+
+// Play impersonates the given end of the session ("client" to replay into a server,
+// "server" to replay into a client) over conn, a raw transport connection. It stops
+// at the first error, returning what was done so far.
+func (s *Session) Play(conn interfaces.TransportConnection, role string, opts Options) (Report, error) {
+	var report Report
+	if role != "client" && role != "server" {
+		return report, fmt.Errorf("replay: unknown role %q", role)
+	}
+	timeout := opts.ReadTimeout
+	if timeout == 0 {
+		timeout = DefaultReadTimeout
+	}
+
+	var origin time.Time
+	start := time.Now()
+	for i, r := range s.Records {
+		// 1. Pacing
+		if origin.IsZero() {
+			origin = r.Time
+		}
+		if opts.Speed > 0 {
+			due := start.Add(time.Duration(float64(r.Time.Sub(origin)) / opts.Speed))
+			time.Sleep(time.Until(due))
+		}
+
+		// 2. Follow the header format of the capture
+		if err := follow(conn, r); err != nil {
+			return report, fmt.Errorf("replay: frame %d: %w", i, err)
+		}
+
+		// 3. Our frames are written, the peer's data messages awaited
+		if s.sentBy(r, role) {
+			sent, err := send(conn, r)
+			if err != nil {
+				return report, fmt.Errorf("replay: frame %d: %w", i, err)
+			}
+			if sent {
+				report.Sent++
+			}
+			continue
+		}
+		if interfaces.FrameType(r.Type) != interfaces.FrameData || len(r.Body) == 0 {
+			continue // Heartbeats and control frames of the peer are not deterministic
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return report, fmt.Errorf("replay: waiting for frame %d: %w", i, err)
+		}
+		report.Received++
+		if _, payload, _ := split(r); !bytes.Equal(msg, payload) {
+			report.Mismatched++
+		}
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	return report, nil
+}
+
+// follow switches conn to the frame version and trace context mode of r.
+func follow(conn interfaces.TransportConnection, r capture.Record) error {
+	if cc := transports.ControlOf(conn); cc != nil && cc.FrameVersion() != r.Version {
+		if err := cc.SetFrameVersion(r.Version); err != nil {
+			return err
+		}
+	}
+	if r.Flags&transports.FlagTraceContext != 0 {
+		if tc := transports.TraceContextOf(conn); tc != nil && !tc.TraceContextEnabled() {
+			return tc.EnableTraceContext()
+		}
+	}
+	return nil
+}
+
+// send writes r as captured. Goodbye acks are left to the transport, which answers
+// the live peer's goodbye itself.
+func send(conn interfaces.TransportConnection, r capture.Record) (bool, error) {
+	switch frameType := interfaces.FrameType(r.Type); frameType {
+	case interfaces.FrameData:
+		tc, payload, err := split(r)
+		if err != nil {
+			return false, err
+		}
+		if tcc := transports.TraceContextOf(conn); tc != nil && tcc != nil {
+			_, err = tcc.WriteTraced(payload, tc)
+		} else {
+			_, err = conn.Write(payload)
+		}
+		return err == nil, err
+	case interfaces.FrameHeartbeat:
+		_, err := conn.Write(nil)
+		return err == nil, err
+	case interfaces.FrameGoodbyeAck:
+		return false, nil
+	default:
+		cc := transports.ControlOf(conn)
+		if cc == nil {
+			return false, transports.ErrControlUnsupported
+		}
+		err := cc.WriteControl(frameType, r.Body)
+		return err == nil, err
+	}
+}
+
+// split separates the trace context from the payload of a captured data frame.
+func split(r capture.Record) (tc, payload []byte, err error) {
+	if r.Flags&transports.FlagTraceContext == 0 {
+		return nil, r.Body, nil
+	}
+	if len(r.Body) == 0 || int(r.Body[0])+1 > len(r.Body) {
+		return nil, nil, transports.ErrBadTraceContext
+	}
+	n := int(r.Body[0]) + 1
+	return r.Body[1:n], r.Body[n:], nil
+}
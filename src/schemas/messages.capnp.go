@@ -14,12 +14,12 @@ type HelloMsg capnp.Struct
 const HelloMsg_TypeID = 0xda3c8f277bce810b
 
 func NewHelloMsg(s *capnp.Segment) (HelloMsg, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 9})
 	return HelloMsg(st), err
 }
 
 func NewRootHelloMsg(s *capnp.Segment) (HelloMsg, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 9})
 	return HelloMsg(st), err
 }
 
@@ -166,12 +166,126 @@ func (s HelloMsg) SetFromPublicIP(v string) error {
 	return capnp.Struct(s).SetText(4, v)
 }
 
+// -----------------------------------------------------
+// Field @5: frameVersion
+// -----------------------------------------------------
+
+func (s HelloMsg) FrameVersion() uint8 {
+	return capnp.Struct(s).Uint8(0)
+}
+
+func (s HelloMsg) SetFrameVersion(v uint8) {
+	capnp.Struct(s).SetUint8(0, v)
+}
+
+// -----------------------------------------------------
+// Field @6: status
+// -----------------------------------------------------
+
+func (s HelloMsg) Status() HelloStatus {
+	return HelloStatus(capnp.Struct(s).Uint16(2))
+}
+
+func (s HelloMsg) SetStatus(v HelloStatus) {
+	capnp.Struct(s).SetUint16(2, uint16(v))
+}
+
+// -----------------------------------------------------
+// Field @7: reason
+// -----------------------------------------------------
+
+func (s HelloMsg) Reason() (string, error) {
+	p, err := capnp.Struct(s).Ptr(5)
+	return p.Text(), err
+}
+
+func (s HelloMsg) HasReason() bool {
+	return capnp.Struct(s).HasPtr(5)
+}
+
+func (s HelloMsg) ReasonBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(5)
+	return p.TextBytes(), err
+}
+
+func (s HelloMsg) SetReason(v string) error {
+	return capnp.Struct(s).SetText(5, v)
+}
+
+// -----------------------------------------------------
+// Field @8: nonce
+// -----------------------------------------------------
+
+func (s HelloMsg) Nonce() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(6)
+	return []byte(p.Data()), err
+}
+
+func (s HelloMsg) HasNonce() bool {
+	return capnp.Struct(s).HasPtr(6)
+}
+
+func (s HelloMsg) SetNonce(v []byte) error {
+	return capnp.Struct(s).SetData(6, v)
+}
+
+// -----------------------------------------------------
+// Field @9: keyId
+// -----------------------------------------------------
+
+func (s HelloMsg) KeyId() (string, error) {
+	p, err := capnp.Struct(s).Ptr(7)
+	return p.Text(), err
+}
+
+func (s HelloMsg) HasKeyId() bool {
+	return capnp.Struct(s).HasPtr(7)
+}
+
+func (s HelloMsg) KeyIdBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(7)
+	return p.TextBytes(), err
+}
+
+func (s HelloMsg) SetKeyId(v string) error {
+	return capnp.Struct(s).SetText(7, v)
+}
+
+// -----------------------------------------------------
+// Field @10: proof
+// -----------------------------------------------------
+
+func (s HelloMsg) Proof() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(8)
+	return []byte(p.Data()), err
+}
+
+func (s HelloMsg) HasProof() bool {
+	return capnp.Struct(s).HasPtr(8)
+}
+
+func (s HelloMsg) SetProof(v []byte) error {
+	return capnp.Struct(s).SetData(8, v)
+}
+
+// -----------------------------------------------------
+// Field @11: traceContext
+// -----------------------------------------------------
+
+func (s HelloMsg) TraceContext() bool {
+	return capnp.Struct(s).Bit(8)
+}
+
+func (s HelloMsg) SetTraceContext(v bool) {
+	capnp.Struct(s).SetBit(8, v)
+}
+
 // HelloMsg_List is a list of HelloMsg.
 type HelloMsg_List = capnp.StructList[HelloMsg]
 
 // NewHelloMsg creates a new list of HelloMsg.
 func NewHelloMsg_List(s *capnp.Segment, sz int32) (HelloMsg_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 9}, sz)
 	return capnp.StructList[HelloMsg](l), err
 }
 
@@ -183,18 +297,67 @@ func (f HelloMsg_Future) Struct() (HelloMsg, error) {
 	return HelloMsg(p.Struct()), err
 }
 
+type HelloStatus uint16
+
+// HelloStatus_TypeID is the unique identifier for the type HelloStatus.
+const HelloStatus_TypeID = 0xfd01a180664b4abd
+
+// Values of HelloStatus.
+const (
+	HelloStatus_accepted  HelloStatus = 0
+	HelloStatus_rejected  HelloStatus = 1
+	HelloStatus_challenge HelloStatus = 2
+)
+
+// String returns the enum's constant name.
+func (c HelloStatus) String() string {
+	switch c {
+	case HelloStatus_accepted:
+		return "accepted"
+	case HelloStatus_rejected:
+		return "rejected"
+	case HelloStatus_challenge:
+		return "challenge"
+
+	default:
+		return ""
+	}
+}
+
+// HelloStatusFromString returns the enum value with a name,
+// or the zero value if there's no such value.
+func HelloStatusFromString(c string) HelloStatus {
+	switch c {
+	case "accepted":
+		return HelloStatus_accepted
+	case "rejected":
+		return HelloStatus_rejected
+	case "challenge":
+		return HelloStatus_challenge
+
+	default:
+		return 0
+	}
+}
+
+type HelloStatus_List = capnp.EnumList[HelloStatus]
+
+func NewHelloStatus_List(s *capnp.Segment, sz int32) (HelloStatus_List, error) {
+	return capnp.NewEnumList[HelloStatus](s, sz)
+}
+
 type PacketEnvelope capnp.Struct
 
 // PacketEnvelope_TypeID is the unique identifier for the type PacketEnvelope.
 const PacketEnvelope_TypeID = 0xcc0f564ba623e267
 
 func NewPacketEnvelope(s *capnp.Segment) (PacketEnvelope, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 4})
 	return PacketEnvelope(st), err
 }
 
 func NewRootPacketEnvelope(s *capnp.Segment) (PacketEnvelope, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 4})
 	return PacketEnvelope(st), err
 }
 
@@ -261,12 +424,67 @@ func (s PacketEnvelope) SetPayload(v []byte) error {
 	return capnp.Struct(s).SetData(1, v)
 }
 
+func (s PacketEnvelope) Timestamp() int64 {
+	return int64(capnp.Struct(s).Uint64(0))
+}
+
+func (s PacketEnvelope) SetTimestamp(v int64) {
+	capnp.Struct(s).SetUint64(0, uint64(v))
+}
+
+func (s PacketEnvelope) Counter() uint64 {
+	return capnp.Struct(s).Uint64(8)
+}
+
+func (s PacketEnvelope) SetCounter(v uint64) {
+	capnp.Struct(s).SetUint64(8, v)
+}
+
+func (s PacketEnvelope) KeyId() (string, error) {
+	p, err := capnp.Struct(s).Ptr(2)
+	return p.Text(), err
+}
+
+func (s PacketEnvelope) HasKeyId() bool {
+	return capnp.Struct(s).HasPtr(2)
+}
+
+func (s PacketEnvelope) KeyIdBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(2)
+	return p.TextBytes(), err
+}
+
+func (s PacketEnvelope) SetKeyId(v string) error {
+	return capnp.Struct(s).SetText(2, v)
+}
+
+func (s PacketEnvelope) Mac() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(3)
+	return []byte(p.Data()), err
+}
+
+func (s PacketEnvelope) HasMac() bool {
+	return capnp.Struct(s).HasPtr(3)
+}
+
+func (s PacketEnvelope) SetMac(v []byte) error {
+	return capnp.Struct(s).SetData(3, v)
+}
+
+func (s PacketEnvelope) Epoch() uint64 {
+	return capnp.Struct(s).Uint64(16)
+}
+
+func (s PacketEnvelope) SetEpoch(v uint64) {
+	capnp.Struct(s).SetUint64(16, v)
+}
+
 // PacketEnvelope_List is a list of PacketEnvelope.
 type PacketEnvelope_List = capnp.StructList[PacketEnvelope]
 
 // NewPacketEnvelope creates a new list of PacketEnvelope.
 func NewPacketEnvelope_List(s *capnp.Segment, sz int32) (PacketEnvelope_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 24, PointerCount: 4}, sz)
 	return capnp.StructList[PacketEnvelope](l), err
 }
 
@@ -278,27 +496,47 @@ func (f PacketEnvelope_Future) Struct() (PacketEnvelope, error) {
 	return PacketEnvelope(p.Struct()), err
 }
 
-const schema_cf4762d38e91a0b1 = "x\xdal\x8f\xbdJ#Q\x18\x86\xdf\xf7\x9c\xc9\xce\xc2" +
-	"&$C\x02\xbb\xb0\xddn\xb1?\xa8!h!b!" +
-	"\xa2h\x14!\xc7\xc2\xca\xc2\x93\x99C\x12\x9c?s\xe2" +
-	"\x1f\x16\xe2\x0d(\xde\x81\x95\xbdx\x1b\xa2\x85\x9d\xa5\x8d" +
-	"\xb5W02BB\x10\xcb\xef\xe5\x81\xe7\xf9*\xb7\x0b" +
-	"N\xa3\xf4]@\xa8\x1f\x85/Y\xe7\xf9\xf7\xf5\xfaV" +
-	"\xf9\x0e\xdeOf7W\x97\xe7\x8f\xed\x95\x07\x14\x84\x0b" +
-	"4^7Y-\xd0\x05\xaa\xe4!\x98};\xbb?\xf9" +
-	"s1\xff\xf4\x81u\\`Z\xf3\x17\xab{\xefp\xc4" +
-	"\x17Lf\xb6\xef\xd7\xad\xdf5N\xa4m=2\xd6\xea" +
-	"\x8e\xb1S\xbeN\xe3t\xae\xa5\xfd]3X\x8e\x0fL" +
-	"\x98\xa44-R}\x95\x0e\xe0\x10\xf0\xfe\xad\x01\xea\xaf" +
-	"\xa4\x9a\x11\xf4\xc8\x1a\xf3\xb1\xb1\x08\xa8\x09I5+\x98" +
-	"Y\x13\x07\xa6\xdf\\\x02\xc0\"\x04\x8b\xe0i\xaa\x8f\xc3" +
-	"D\x07,A\xb0\x04\x8e\xf4\xf2\x13\xfd\xaa\x09\xc3\xc4\xdd" +
-	"\xb0\x9d\\\\\x19\x89\xf5\x7f@mK\xaa\xee\x98\xd8\xe4" +
-	"\xe3\x8e\xa4\x0a\x05=!j\x14\x80\xd7\xcbk\x02I\x95" +
-	"\x0azR\xd6(\x01/\xca\xbbCIu$X\x8eu" +
-	"d\x86m\xe5nb\x07\xa3P\x1d\x04}c\xed\xf0\xce" +
-	"\xd2\xfdv\xd8\xf3\x9b\xad\xb1g\xde\x02\x00\x00\xff\xffY" +
-	"\xfe\\g"
+const schema_cf4762d38e91a0b1 = "x\xda\x8c\xd2Mk$E\x1c\x06\xf0\xe7\xa9\xee\x9e\xce" +
+	"\xac\x13\xc6\xa6[P\x16\x09\xac\x8a\x97\xd5\xddl\x14$" +
+	"\xacd\x95\x0d&\x13\xa3S\xd3\x1a#FH\xa7\xa72" +
+	"y\xe9\x97\xa1\xab#\x04\x0f\xd1/\xa0\xe4f\xc0\x83\x81" +
+	"DrPH@\xc1\x83~\x03\xd1\x837\x8f~\x0b\x0f" +
+	"\xd2R\xad\x93L\xc4\xc3\x9e\xba\xf9\xf1T\x15<\xff\xff" +
+	"\xdd\x0f\xf8\xc0\x9e\x9e\x1c\x10B>\xee4\xaa\xc1\x1f\xcf" +
+	"|\xbd\xb4\xd2\xfe\x19\xf2&Eu\xf1\xd5\xe1g\xbfm" +
+	"\xbc\xf1+\x1c\xdb\x05\xa6\x8fz\x04\xa7O\xdf#X=" +
+	"\xf6\xe9/\x1f?\xff\xf9\xfd\xdfM\x90c\xc1\xa6\x0b\xcc" +
+	"\xfc\xc9[\x04g(r\x01V?u\x966?9\xe6" +
+	"_\xf0n\x8e]\x09\xce\x1c\xda\xb3\x04\xfd#\xfbm\xb4" +
+	"*]\xc4wt\xbc\xa5\xec4\xd2wR\xa5u4P" +
+	"\xfa\xc58\x1af\xc3\xd9n\x14\xef\xaar>\xfbH%" +
+	"\xf9\x90J>i\xd9\x80M\xc0;\xea\x00\xf2\x0b\x8b\xf2" +
+	"D\xd0#\x03\x1a<~\x1d\x90_Z\x94g\x82\x14\x01" +
+	"\x05\xe0\x9d\xf6\x00ybQ\x9e\x0bz\x16\x03Z\x80\xf7" +
+	"\xad\x09\x9eY\x94\xdf\x09z\xb6\x08h\x03\xde\xc5=@" +
+	"~cQ\xfe \xe89V@\x07\xf0\xbe\xbf\x05\xc8s" +
+	"\x8b\xf2G\xc1J\xab\xac\xaf\x8a\xc5\x87\x00\xd8\x82`\x0b" +
+	"<\x18F\xfbI\x1e\xf59\x09\xc1I\xb0*\xb7S\xa5" +
+	"\xcb(\x05\x87t \xe8\x80\x07q\xbe\x97\x95\xaa`\x13" +
+	"\x82MpjW\xed/\xf6G7\xb8i\x14_\x9e\x1e" +
+	"\x95a\xfdO\x19\x0b*IrwY\x0f\xe4\xedQ\x0d" +
+	"\xfes\xec\x00\xe1\xb3\xb4\x18\xde\xe5U\x13\xfe\x0b\xb5\xdf" +
+	"6\xfe\x8aq\xf1O\x1b\xfe\xcb\xdc\x00\xc2\x97\x8c?0" +
+	"nYu!\xfe\xab\xec\x01\xe1}\xe3\x0b\xc6m\xbb\xee" +
+	"\xc4\x9f\xe7\x0e\x10>4\xde\xa5 \x9d\xba\x15\x7f\xb9\xe6" +
+	"7\x0d\xaf\x9ax\x83\x01\x1b\x80\xff.g\x81\xb0k|" +
+	"\xcd\xb8\xeb\x04t\x01\xff\xfd\xda\xdf1\xben|\xa2\x11" +
+	"p\x02\xf0?\xe4= \\5\xde7\xdet\x036\x01" +
+	"?\xaa}\xcd\xf8\x96\xf1\x1b\x13\x01o\x00\xbe\xaa}\xdd" +
+	"xB\xc1j\xb3\xc8\xd3\xb7\xa2T\x8d\x0d\xa4\xb6\x85\\" +
+	"\x97\xff\xb5\xd7\xfa\xfd\x02\xae\xd2\xfaR\xcb\xdc\x98\xd2\xa0" +
+	"\xbe\x96\xec\xeem$ho\xc7\x8b\xdd1\x8eR\xb5\xa2" +
+	"\x0a\xb4\xf5v\x9e\xb1\x01\xc1\x068\xa7\xcb\xa8\xdc\xd3l" +
+	"_\xad:\xc868W\xa8H\xe7\xd9\xe8\xf8T\x96g" +
+	"\xb1\x1a\x0d\xf9\xfa\xf8\xa7\x86E\x9eo>\xfa\x02\xcc\x85" +
+	"\xf5\x9b\xb2Uo\xf7\xd3\x1d\x80\xf4\x9e2\x1f\xe1=\xd1" +
+	"\x03\xaa(\x8e\xd5\xb0T}\x00U\xa1vT\xfc\xef\x7f" +
+	"\xbc\x15%\x89\xca\x06\xa0\xfa{\x00\xc7B\xd6G"
 
 func RegisterSchema(reg *schemas.Registry) {
 	reg.Register(&schemas.Schema{
@@ -306,6 +544,7 @@ func RegisterSchema(reg *schemas.Registry) {
 		Nodes: []uint64{
 			0xcc0f564ba623e267,
 			0xda3c8f277bce810b,
+			0xfd01a180664b4abd,
 		},
 		Compressed: true,
 	})
@@ -0,0 +1,84 @@
+package tracing
+
+import "context"
+
+// Span names recorded by the library.
+const (
+	SpanConnect   = "safesocket.connect"   // SocketClient: dial, handshake and wrappers
+	SpanAccept    = "safesocket.accept"    // SocketServer: handshake pipeline of one connection
+	SpanHandshake = "safesocket.handshake" // HelloProtocol: Initiate (client) or WaitInitiation (server)
+	SpanSend      = "safesocket.send"      // One message written with SendContext/WriteContext
+	SpanReceive   = "safesocket.receive"   // One message read with ReceiveContext/ReadMessageContext
+)
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String, Int and Bool build attributes.
+func String(key, value string) Attr    { return Attr{Key: key, Value: value} }
+func Int(key string, value int) Attr   { return Attr{Key: key, Value: value} }
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+// -----------------------------------------------------------------------------
+
+// Tracer starts the spans of a socket. Adapters map it onto a tracing library
+// (e.g. OpenTelemetry): the library never depends on one.
+type Tracer interface {
+	// Start begins a span as a child of the span in ctx, if any, and returns a context
+	// holding the new span.
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// Span is one traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attr)
+	// End finishes the span, recording err when the operation failed.
+	End(err error)
+}
+
+// Propagator is implemented by tracers able to carry a trace across sockets. Its
+// context travels in the header of data frames (at most 255 bytes, e.g. a W3C
+// traceparent) on connections whose peers agreed on it during the Hello handshake.
+type Propagator interface {
+	// Inject serializes the trace context of ctx (nil = nothing to propagate).
+	Inject(ctx context.Context) []byte
+	// Extract returns ctx continuing the trace context received from the peer.
+	Extract(ctx context.Context, tc []byte) context.Context
+}
+
+// -----------------------------------------------------------------------------
+
+// Start begins a span with t, or a no-op span when t is nil.
+func Start(t Tracer, ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	return t.Start(ctx, name, attrs...)
+}
+
+// Inject returns the trace context of ctx when t propagates traces (nil otherwise).
+func Inject(t Tracer, ctx context.Context) []byte {
+	if p, ok := t.(Propagator); ok {
+		return p.Inject(ctx)
+	}
+	return nil
+}
+
+// Extract continues the trace context tc in ctx when t propagates traces.
+func Extract(t Tracer, ctx context.Context, tc []byte) context.Context {
+	if p, ok := t.(Propagator); ok && len(tc) > 0 {
+		return p.Extract(ctx, tc)
+	}
+	return ctx
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attr) {}
+func (noopSpan) End(error)             {}
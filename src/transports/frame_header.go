@@ -0,0 +1,194 @@
+package transports
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// Frame Header Layouts (stream transports: FramedTCP, SHM):
+//
+// Version 0 (legacy, length-only):
+// [0-3] : Length (BigEndian) - 0 means heartbeat
+// [4-.] : Payload
+//
+// Version 1 (typed):
+// [0-3] : Length (BigEndian) - payload length, header excluded
+// [4]   : FrameType (interfaces.FrameData, FrameHeartbeat, FramePing...)
+// [5]   : Flags (FlagTraceContext, see trace_context.go; others ignored when unknown)
+// [6-.] : Payload
+//
+// Every connection starts in version 0 so that peers predating the typed header keep
+// working. The Hello handshake negotiates the version (HelloMsg.frameVersion) and both
+// sides switch with SetFrameVersion before any application frame is exchanged.
+const (
+	FrameVersionLegacy  uint8 = 0
+	FrameVersionTyped   uint8 = 1
+	CurrentFrameVersion       = FrameVersionTyped
+
+	headerSizeV0 = 4
+	headerSizeV1 = 6
+
+	// MaxControlPayloadSize bounds non-data frames, which are always fully buffered.
+	MaxControlPayloadSize = 64 * 1024
+)
+
+var (
+	// ErrControlUnsupported is returned by WriteControl while the connection still
+	// speaks the legacy length-only format.
+	ErrControlUnsupported = errors.New("control frames require frame version 1")
+	// ErrBadControlFrame is returned when a control frame is malformed or oversized.
+	ErrBadControlFrame = errors.New("malformed control frame")
+	// ErrUnknownFrameVersion is returned by SetFrameVersion for unsupported versions.
+	ErrUnknownFrameVersion = errors.New("unknown frame version")
+)
+
+// -----------------------------------------------------------------------------
+
+// frameVersion holds the negotiated header version of a connection.
+type frameVersion struct {
+	v atomic.Uint32
+}
+
+func (f *frameVersion) set(v uint8) error {
+	if v > CurrentFrameVersion {
+		return ErrUnknownFrameVersion
+	}
+	f.v.Store(uint32(v))
+	return nil
+}
+
+func (f *frameVersion) get() uint8 {
+	return uint8(f.v.Load())
+}
+
+func (f *frameVersion) headerSize() int {
+	if f.get() == FrameVersionLegacy {
+		return headerSizeV0
+	}
+	return headerSizeV1
+}
+
+// -----------------------------------------------------------------------------
+
+// encodeHeader builds the frame header for the given version.
+// Version 0 can only express data frames (and empty heartbeats).
+func encodeHeader(version uint8, frameType interfaces.FrameType, flags uint8, length int) []byte {
+	if version == FrameVersionLegacy {
+		header := make([]byte, headerSizeV0)
+		binary.BigEndian.PutUint32(header, uint32(length))
+		return header
+	}
+	header := make([]byte, headerSizeV1)
+	binary.BigEndian.PutUint32(header, uint32(length))
+	header[4] = byte(frameType)
+	header[5] = flags
+	return header
+}
+
+// decodeHeader parses a header of the given version. In version 0 every frame is data.
+func decodeHeader(version uint8, header []byte) (length uint32, frameType interfaces.FrameType, flags uint8) {
+	length = binary.BigEndian.Uint32(header)
+	if version == FrameVersionLegacy {
+		return length, interfaces.FrameData, 0
+	}
+	return length, interfaces.FrameType(header[4]), header[5]
+}
+
+// isSkippable reports whether a frame is a keep-alive that the reader just discards.
+func isSkippable(frameType interfaces.FrameType, length uint32) bool {
+	return frameType == interfaces.FrameHeartbeat || (frameType == interfaces.FrameData && length == 0)
+}
+
+// -----------------------------------------------------------------------------
+
+// controlDispatcher holds the handler receiving control frames. It can be swapped
+// while a reader is blocked, hence the atomic pointer.
+type controlDispatcher struct {
+	handler atomic.Pointer[interfaces.ControlHandler]
+}
+
+func (d *controlDispatcher) set(h interfaces.ControlHandler) {
+	if h == nil {
+		d.handler.Store(nil)
+		return
+	}
+	d.handler.Store(&h)
+}
+
+// dispatch hands a control frame to the handler. Frames are dropped silently when
+// nobody listens, which also covers types introduced by newer peers.
+func (d *controlDispatcher) dispatch(frameType interfaces.FrameType, payload []byte) {
+	if h := d.handler.Load(); h != nil {
+		(*h)(frameType, payload)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// frameTap holds the observer of every frame written or read (traffic capture).
+type frameTap struct {
+	observer atomic.Pointer[interfaces.FrameObserver]
+}
+
+func (t *frameTap) set(o interfaces.FrameObserver) {
+	if o == nil {
+		t.observer.Store(nil)
+		return
+	}
+	t.observer.Store(&o)
+}
+
+// active reports whether an observer is set, letting callers skip building the body.
+func (t *frameTap) active() bool {
+	return t.observer.Load() != nil
+}
+
+// observe reports one frame whose body is prefix followed by p.
+func (t *frameTap) observe(dir interfaces.FrameDirection, version uint8, frameType interfaces.FrameType, flags uint8, prefix, p []byte) {
+	o := t.observer.Load()
+	if o == nil {
+		return
+	}
+	body := p
+	if len(prefix) > 0 {
+		body = append(append(make([]byte, 0, len(prefix)+len(p)), prefix...), p...)
+	}
+	(*o)(interfaces.Frame{Direction: dir, Version: version, Type: frameType, Flags: flags, Body: body})
+}
+
+// -----------------------------------------------------------------------------
+
+// FrameTapOf walks a wrapper chain (via Unwrap) down to the transport able to report
+// its frames. It returns nil when the chain has none (e.g. UDP).
+func FrameTapOf(conn interfaces.TransportConnection) interfaces.FrameTap {
+	ft, _ := unwrapTo[interfaces.FrameTap](conn)
+	return ft
+}
+
+// ControlOf walks a wrapper chain (via Unwrap) down to the first connection able to
+// carry control frames. It returns nil when the chain has none (e.g. UDP).
+func ControlOf(conn interfaces.TransportConnection) interfaces.ControlConnection {
+	cc, _ := unwrapTo[interfaces.ControlConnection](conn)
+	return cc
+}
+
+// unwrapTo returns the first connection of the wrapper chain implementing T.
+func unwrapTo[T any](conn interfaces.TransportConnection) (T, bool) {
+	for conn != nil {
+		if t, ok := conn.(T); ok {
+			return t, true
+		}
+		u, ok := conn.(interface {
+			Unwrap() interfaces.TransportConnection
+		})
+		if !ok {
+			break
+		}
+		conn = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,125 @@
+package transports
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// exerciseFrameVersions checks that v0 refuses control frames but still skips heartbeats,
+// then switches both ends to v1 and checks that a control frame reaches the handler
+// while Read only ever returns the data.
+func exerciseFrameVersions(t *testing.T, writer, reader interfaces.TransportConnection) {
+	wc := writer.(interfaces.ControlConnection)
+	rc := reader.(interfaces.ControlConnection)
+
+	// 1. Legacy framing: no control frames, heartbeats are invisible to Read
+	if err := wc.WriteControl(interfaces.FramePing, []byte("tick")); !errors.Is(err, ErrControlUnsupported) {
+		t.Fatalf("expected ErrControlUnsupported in v0, got %v", err)
+	}
+	_, _ = writer.Write([]byte{})
+	_, _ = writer.Write([]byte("v0"))
+	if msg, err := reader.ReadMessage(); err != nil || string(msg) != "v0" {
+		t.Fatalf("expected 'v0', got %q (%v)", msg, err)
+	}
+
+	// 2. Typed framing
+	if err := wc.SetFrameVersion(FrameVersionTyped); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.SetFrameVersion(FrameVersionTyped); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.SetFrameVersion(99); !errors.Is(err, ErrUnknownFrameVersion) {
+		t.Errorf("expected ErrUnknownFrameVersion, got %v", err)
+	}
+
+	got := make(chan string, 1)
+	rc.SetControlHandler(func(ft interfaces.FrameType, payload []byte) {
+		if ft == interfaces.FramePing {
+			got <- string(payload)
+		}
+	})
+
+	_, _ = writer.Write([]byte{})
+	if err := wc.WriteControl(interfaces.FramePing, []byte("tick")); err != nil {
+		t.Fatalf("WriteControl failed: %v", err)
+	}
+	if _, err := writer.Write([]byte("data")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := reader.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(buf[:n]) != "data" {
+		t.Errorf("expected 'data', got %q", buf[:n])
+	}
+
+	select {
+	case payload := <-got:
+		if payload != "tick" {
+			t.Errorf("expected control payload 'tick', got %q", payload)
+		}
+	default:
+		t.Error("control frame was not dispatched to the handler")
+	}
+}
+
+func TestFrameHeader(t *testing.T) {
+	t.Run("FramedTCP", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, _ := ln.Accept()
+			accepted <- conn
+		}()
+
+		clientConn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewFramedTCPSocket(clientConn, time.Second)
+		server := NewFramedTCPSocket(<-accepted, time.Second)
+		defer func() { _ = client.Close() }()
+		defer func() { _ = server.Close() }()
+
+		exerciseFrameVersions(t, client, server)
+	})
+
+	t.Run("SHM", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "control.shm")
+		defer func() { _ = os.Remove(path) }()
+
+		ln, err := ListenShm(path, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		client, err := ConnectShm(path, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = client.Close() }()
+
+		server, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exerciseFrameVersions(t, client, server)
+	})
+}
@@ -2,10 +2,7 @@ package transports
 
 import (
 	"crypto/tls"
-	"crypto/x509"
-	"fmt"
 	"net"
-	"os"
 	"time"
 
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
@@ -24,32 +21,22 @@ func Connect(address string, timeout time.Duration) (interfaces.TransportConnect
 
 // ConnectTLS dialer helper for TLS-wrapped FramedTCPSocket.
 func ConnectTLS(address string, timeout time.Duration, certFile, keyFile, caFile, serverName string, skipVerify bool) (interfaces.TransportConnection, error) {
-	tlsConfig := &tls.Config{
+	return ConnectTLSWithOptions(address, timeout, TLSOptions{
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		CAFile:             caFile,
 		ServerName:         serverName,
 		InsecureSkipVerify: skipVerify,
-	}
-
-	// 1. Load Client Certificate if provided
-	if certFile != "" && keyFile != "" {
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load client key pair: %w", err)
-		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
-	}
+	})
+}
 
-	// 2. Load CA if provided (for mTLS or custom CA)
-	if caFile != "" {
-		caCert, err := os.ReadFile(caFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read CA file: %w", err)
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tlsConfig.RootCAs = caCertPool
+// ConnectTLSWithOptions dials a TLS-wrapped FramedTCPSocket.
+func ConnectTLSWithOptions(address string, timeout time.Duration, opts TLSOptions) (interfaces.TransportConnection, error) {
+	tlsConfig, err := clientTLSConfig(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// 3. Dial with TLS
 	dialer := &net.Dialer{Timeout: timeout}
 	conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
 	if err != nil {
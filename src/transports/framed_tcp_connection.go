@@ -2,21 +2,37 @@ package transports
 
 import (
 	"bufio"
-	"encoding/binary"
+	"errors"
 	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
 )
 
 // MaxPayloadSize defines the upper limit for incoming frames (default 64MB).
 const MaxPayloadSize = 64 * 1024 * 1024
 
 // FramedTCPSocket implements interfaces.TransportConnection.
-// It uses a 4-byte BigEndian length header for every write.
+// It uses a 4-byte BigEndian length header for every write (plus type/flags bytes
+// once frame version 1 has been negotiated, see frame_header.go).
 type FramedTCPSocket struct {
 	Conn        net.Conn
 	reader      *bufio.Reader
 	idleTimeout time.Duration
+	writeMu     sync.Mutex   // Keeps header+body of a frame contiguous on the wire
+	readMu      sync.Mutex   // Lets Close drain the goodbye ack without racing a reader
+	readLimit   atomic.Int64 // Read deadline set by the application (UnixNano, 0 = none)
+	control     controlDispatcher
+	version     frameVersion
+	close       closeState
+	trace       traceState
+	tap         frameTap
+	metrics     atomic.Pointer[metrics.Socket]
+	openedAt    time.Time
 }
 
 // -----------------------------------------------------------------------------
@@ -26,6 +42,7 @@ func NewFramedTCPSocket(conn net.Conn, timeout time.Duration) *FramedTCPSocket {
 		Conn:        conn,
 		reader:      bufio.NewReader(conn),
 		idleTimeout: timeout,
+		openedAt:    time.Now(),
 	}
 
 	// We no longer set a one-time absolute deadline here.
@@ -37,7 +54,8 @@ func NewFramedTCPSocket(conn net.Conn, timeout time.Duration) *FramedTCPSocket {
 }
 
 func (s *FramedTCPSocket) refreshReadDeadline() {
-	if s.idleTimeout > 0 {
+	// A closing connection keeps the deadline set for the goodbye ack
+	if s.idleTimeout > 0 && !s.close.closing.Load() {
 		_ = s.Conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
 	}
 }
@@ -108,6 +126,7 @@ func (s *FramedTCPSocket) SetWriteBuffer(bytes int) error {
 
 // SetDeadline sets the read and write deadlines associated with the connection.
 func (s *FramedTCPSocket) SetDeadline(t time.Time) error {
+	s.readLimit.Store(deadlineNanos(t))
 	return s.Conn.SetDeadline(t)
 }
 
@@ -115,6 +134,7 @@ func (s *FramedTCPSocket) SetDeadline(t time.Time) error {
 
 // SetReadDeadline sets the deadline for future Read calls.
 func (s *FramedTCPSocket) SetReadDeadline(t time.Time) error {
+	s.readLimit.Store(deadlineNanos(t))
 	return s.Conn.SetReadDeadline(t)
 }
 
@@ -127,53 +147,251 @@ func (s *FramedTCPSocket) SetWriteDeadline(t time.Time) error {
 
 // -----------------------------------------------------------------------------
 
-// Write prepends length and writes data.
+// Write prepends the frame header and writes data.
+// An empty write is sent as a heartbeat frame.
 func (s *FramedTCPSocket) Write(p []byte) (n int, err error) {
+	frameType := interfaces.FrameData
+	if len(p) == 0 {
+		frameType = interfaces.FrameHeartbeat
+	}
+	return s.writeFrame(frameType, 0, nil, p)
+}
+
+// WriteTraced writes p as one data frame carrying the trace context tc, once trace
+// contexts have been negotiated (see trace_context.go). Otherwise tc is dropped.
+func (s *FramedTCPSocket) WriteTraced(p, tc []byte) (n int, err error) {
+	if len(p) == 0 {
+		return s.Write(p)
+	}
+	flags, prefix, err := s.trace.prefix(tc)
+	if err != nil {
+		return 0, err
+	}
+	return s.writeFrame(interfaces.FrameData, flags, prefix, p)
+}
+
+// -----------------------------------------------------------------------------
+
+// WriteControl sends a typed control frame. It requires frame version 1.
+func (s *FramedTCPSocket) WriteControl(frameType interfaces.FrameType, payload []byte) error {
+	if s.version.get() == FrameVersionLegacy {
+		return ErrControlUnsupported
+	}
+	if len(payload) > MaxControlPayloadSize {
+		return ErrBadControlFrame
+	}
+	_, err := s.writeFrame(frameType, 0, nil, payload)
+	return err
+}
+
+// writeFrame writes header and body while holding the write lock, so that frames
+// written from different goroutines (data, heartbeats, pongs) never interleave.
+// The prefix (trace context) is part of the body but not of the returned count.
+func (s *FramedTCPSocket) writeFrame(frameType interfaces.FrameType, flags uint8, prefix, p []byte) (n int, err error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	s.refreshWriteDeadline()
 
-	// 1. Prepare Header (4 bytes length)Endian)
-	header := make([]byte, 4)
-	binary.BigEndian.PutUint32(header, uint32(len(p)))
+	// 1. Prepare Header (4 bytes length BigEndian, + type/flags in v1)
+	version := s.version.get()
+	header := encodeHeader(version, frameType, flags, len(prefix)+len(p))
 
 	// 2. Write Header
-	_, err = s.Conn.Write(header)
+	_, err = s.Conn.Write(append(header, prefix...))
 	if err != nil {
 		return 0, err
 	}
 
 	// 3. Write Data
-	return s.Conn.Write(p)
+	if n, err = s.Conn.Write(p); err == nil {
+		s.tap.observe(interfaces.FrameOutbound, version, frameType, flags, prefix, p)
+	}
+	return n, err
+}
+
+// -----------------------------------------------------------------------------
+
+// SetControlHandler registers the callback receiving inbound control frames.
+func (s *FramedTCPSocket) SetControlHandler(h interfaces.ControlHandler) {
+	s.control.set(h)
+}
+
+// SetFrameVersion switches the header format for every subsequent frame.
+// Only call it at a frame boundary agreed with the peer (end of the handshake).
+func (s *FramedTCPSocket) SetFrameVersion(v uint8) error {
+	return s.version.set(v)
+}
+
+// FrameVersion returns the header version currently spoken on this connection.
+func (s *FramedTCPSocket) FrameVersion() uint8 {
+	return s.version.get()
+}
+
+// EnableTraceContext lets data frames carry trace contexts in both directions.
+// Only call it once the peer agreed on it (end of the handshake).
+func (s *FramedTCPSocket) EnableTraceContext() error {
+	return s.trace.enable(s.version.get())
+}
+
+// TraceContextEnabled reports whether trace contexts were negotiated.
+func (s *FramedTCPSocket) TraceContextEnabled() bool {
+	return s.trace.enabled.Load()
+}
+
+// readControl consumes a control frame body whose header has already been consumed.
+// Goodbye frames end the read path: the peer's goodbye is acknowledged and reported
+// as a *PeerClosedError, the ack of our own goodbye as io.EOF.
+func (s *FramedTCPSocket) readControl(frameType interfaces.FrameType, flags uint8, length uint32) error {
+	if length > MaxControlPayloadSize {
+		return ErrBadControlFrame
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return err
+	}
+	s.tap.observe(interfaces.FrameInbound, s.version.get(), frameType, flags, nil, body)
+
+	switch {
+	case frameType == interfaces.FrameGoodbye:
+		pc := decodeGoodbye(body)
+		s.close.peer.Store(pc)
+		_ = s.WriteControl(interfaces.FrameGoodbyeAck, nil)
+		return pc
+	case frameType == interfaces.FrameGoodbyeAck:
+		s.close.markAcked()
+		return io.EOF
+	case isSkippable(frameType, length):
+		s.metrics.Load().HeartbeatSkipped()
+	default:
+		s.control.dispatch(frameType, body)
+	}
+	return nil
+}
+
+// controlPumpWait bounds the wait of PumpControl for bytes already on their way.
+const controlPumpWait = time.Millisecond
+
+// pumpError hides the timeout ending a PumpControl that ran out of buffered frames.
+func pumpError(err error) error {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return nil
+	}
+	return err
+}
+
+// PumpControl dispatches the control frames (pings, pongs, heartbeats) already
+// received ahead of the next data frame, waiting at most controlPumpWait for bytes
+// the kernel already holds. Data and goodbye frames are left to the reader.
+func (s *FramedTCPSocket) PumpControl() error {
+	if !s.readMu.TryLock() {
+		return nil // A reader is in, and dispatches them
+	}
+	defer s.readMu.Unlock()
+	if s.close.peer.Load() != nil || s.close.closing.Load() {
+		return nil
+	}
+
+	_ = s.Conn.SetReadDeadline(time.Now().Add(controlPumpWait))
+	defer func() {
+		var limit time.Time
+		if nanos := s.readLimit.Load(); nanos > 0 {
+			limit = time.Unix(0, nanos)
+		}
+		_ = s.Conn.SetReadDeadline(limit)
+		s.refreshReadDeadline()
+	}()
+	for {
+		version := s.version.get()
+		headerSize := s.version.headerSize()
+		header, err := s.reader.Peek(headerSize)
+		if err != nil {
+			return pumpError(err)
+		}
+		length, frameType, flags := decodeHeader(version, header)
+		if frameType == interfaces.FrameData && length > 0 ||
+			frameType == interfaces.FrameGoodbye || frameType == interfaces.FrameGoodbyeAck ||
+			length > MaxControlPayloadSize || headerSize+int(length) > s.reader.Size() {
+			return nil
+		}
+		// Only consume a frame once it is buffered whole
+		if _, err := s.reader.Peek(headerSize + int(length)); err != nil {
+			return pumpError(err)
+		}
+		_, _ = s.reader.Discard(headerSize)
+		if err := s.readControl(frameType, flags, length); err != nil {
+			return err
+		}
+	}
+}
+
+// SetMetrics makes the connection record discarded heartbeats into m.
+func (s *FramedTCPSocket) SetMetrics(m *metrics.Socket) {
+	s.metrics.Store(m)
+}
+
+// SetFrameObserver makes the connection report every frame it writes or reads to o
+// (nil = stop reporting).
+func (s *FramedTCPSocket) SetFrameObserver(o interfaces.FrameObserver) {
+	s.tap.set(o)
 }
 
 // -----------------------------------------------------------------------------
 
-// Read expects a 4-byte BigEndian length header, then reads that many bytes.
+// Read expects a frame header (see frame_header.go), then reads that many bytes.
 // SAFE UPDATE: Uses Peek/Discard to ensure header is not lost if buffer is too short.
-// HEARTBEAT UPDATE: Automatically skips frames with length 0.
+// HEARTBEAT UPDATE: Automatically skips heartbeat frames (and 0-length data frames).
+// CONTROL UPDATE: Control frames are dispatched to the ControlHandler, never returned.
 func (s *FramedTCPSocket) Read(p []byte) (n int, err error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	if pc := s.close.peer.Load(); pc != nil {
+		return 0, pc
+	}
 	for {
 		s.refreshReadDeadline()
+		version := s.version.get()
+		headerSize := s.version.headerSize()
+
 		// 1. Peek content check
-		// We need 4 bytes for header.
-		header, err := s.reader.Peek(4)
+		header, err := s.reader.Peek(headerSize)
 		if err != nil {
 			return 0, err
 		}
 
-		// 2. Decode Length
-		length := binary.BigEndian.Uint32(header)
+		// 2. Decode Length (and Type in v1)
+		length, frameType, flags := decodeHeader(version, header)
+
+		// CONTROL / HEARTBEAT: Consume non-data frames and continue.
+		if frameType != interfaces.FrameData || length == 0 {
+			if _, err := s.reader.Discard(headerSize); err != nil {
+				return 0, err
+			}
+			if err := s.readControl(frameType, flags, length); err != nil {
+				return 0, err
+			}
+			continue
+		}
 
 		// OOM PROTECTION: Reject oversized frames before allocation
 		if length > MaxPayloadSize {
 			return 0, io.ErrUnexpectedEOF // Or custom ErrPayloadTooLarge
 		}
 
-		// HEARTBEAT: Length 0 frames are heartbeats. Consume and continue.
-		if length == 0 {
-			if _, err := s.reader.Discard(4); err != nil {
+		// TRACE CONTEXT: Skipped along with the header (see trace_context.go)
+		bodyStart := headerSize
+		if s.trace.traced(flags) {
+			prefix, err := s.reader.Peek(headerSize + 1)
+			if err != nil {
 				return 0, err
 			}
-			continue
+			if skip := uint32(prefix[headerSize]) + 1; skip <= length {
+				headerSize += int(skip)
+				length -= skip
+			} else {
+				return 0, ErrBadTraceContext
+			}
 		}
 
 		// 3. Check Buffer Size BEFORE consuming header
@@ -181,38 +399,76 @@ func (s *FramedTCPSocket) Read(p []byte) (n int, err error) {
 			return 0, io.ErrShortBuffer
 		}
 
+		// Keep the trace context for the frame observer before it is discarded
+		var prefix []byte
+		if headerSize > bodyStart && s.tap.active() {
+			peeked, err := s.reader.Peek(headerSize)
+			if err != nil {
+				return 0, err
+			}
+			prefix = append([]byte(nil), peeked[bodyStart:]...)
+		}
+
 		// 4. Safe to proceed: Consume Header
-		if _, err := s.reader.Discard(4); err != nil {
+		if _, err := s.reader.Discard(headerSize); err != nil {
 			return 0, err
 		}
 
 		// 5. Read Body
-		return io.ReadFull(s.reader, p[:length])
+		n, err = io.ReadFull(s.reader, p[:length])
+		if err == nil {
+			s.tap.observe(interfaces.FrameInbound, version, frameType, flags, prefix, p[:n])
+		}
+		return n, err
 	}
 }
 
 // -----------------------------------------------------------------------------
 
 // ReadMessage implements the dynamic read.
-// HEARTBEAT UPDATE: Automatically skips frames with length 0.
+// HEARTBEAT UPDATE: Automatically skips heartbeat frames (and 0-length data frames).
+// CONTROL UPDATE: Control frames are dispatched to the ControlHandler, never returned.
 func (s *FramedTCPSocket) ReadMessage() ([]byte, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	msg, _, err := s.readMessage()
+	return msg, err
+}
+
+// ReadMessageTraced reads one message along with the trace context sent with it
+// (nil if none, see trace_context.go).
+func (s *FramedTCPSocket) ReadMessageTraced() (msg, tc []byte, err error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	return s.readMessage()
+}
+
+func (s *FramedTCPSocket) readMessage() ([]byte, []byte, error) {
+	if pc := s.close.peer.Load(); pc != nil {
+		return nil, nil, pc
+	}
 	for {
 		s.refreshReadDeadline()
-		// 1. Read Length
-		header := make([]byte, 4)
+		version := s.version.get()
+
+		// 1. Read Header
+		header := make([]byte, s.version.headerSize())
 		if _, err := io.ReadFull(s.reader, header); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		length := binary.BigEndian.Uint32(header)
+		length, frameType, flags := decodeHeader(version, header)
 
-		// OOM PROTECTION: Reject oversized frames before allocation
-		if length > MaxPayloadSize {
-			return nil, io.ErrUnexpectedEOF
+		// CONTROL / HEARTBEAT: Consume non-data frames and continue.
+		if frameType != interfaces.FrameData || length == 0 {
+			if err := s.readControl(frameType, flags, length); err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 
-		// HEARTBEAT: Length 0 frames are heartbeats. Consume and continue.
-		if length == 0 {
-			continue
+		// OOM PROTECTION: Reject oversized frames before allocation
+		if length > MaxPayloadSize {
+			return nil, nil, io.ErrUnexpectedEOF
 		}
 
 		// 2. Allocate exact size
@@ -220,19 +476,90 @@ func (s *FramedTCPSocket) ReadMessage() ([]byte, error) {
 
 		// 3. Read Body
 		if _, err := io.ReadFull(s.reader, buf); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		s.tap.observe(interfaces.FrameInbound, version, frameType, flags, nil, buf)
 
-		return buf, nil
+		// 4. Split the trace context off the payload
+		if s.trace.traced(flags) {
+			tc, payload, err := splitTraceContext(buf)
+			return payload, tc, err
+		}
+		return buf, nil, nil
 	}
 }
 
 // -----------------------------------------------------------------------------
 
+// Close announces a graceful close to the peer (frame version 1 only), optionally
+// waits for its acknowledgement, then closes the underlying connection.
 func (s *FramedTCPSocket) Close() error {
+	if s.close.begin() {
+		s.sendGoodbye()
+	}
+	return s.Conn.Close()
+}
+
+// Abort closes the connection without a goodbye, resetting it when possible: the peer
+// sees a crash rather than a graceful close.
+func (s *FramedTCPSocket) Abort() error {
+	s.close.begin()
+	if tc, ok := s.Conn.(*net.TCPConn); ok {
+		_ = tc.SetLinger(0)
+	}
 	return s.Conn.Close()
 }
 
+// SetCloseReason sets the code and reason announced by the next Close.
+func (s *FramedTCPSocket) SetCloseReason(code interfaces.CloseCode, reason string) {
+	s.close.setReason(code, reason)
+}
+
+// SetCloseAckTimeout makes Close wait up to d for the peer's goodbye ack (0 = don't wait).
+func (s *FramedTCPSocket) SetCloseAckTimeout(d time.Duration) {
+	s.close.ackTimeout.Store(int64(d))
+}
+
+// sendGoodbye writes the goodbye frame, bypassing the idle timeout so Close stays bounded.
+func (s *FramedTCPSocket) sendGoodbye() {
+	if s.version.get() == FrameVersionLegacy || s.close.peer.Load() != nil {
+		return // Legacy peer, or the peer already said goodbye
+	}
+	wait := time.Duration(s.close.ackTimeout.Load())
+	code, reason := s.close.getReason()
+	payload := encodeGoodbye(code, reason)
+	frame := append(encodeHeader(s.version.get(), interfaces.FrameGoodbye, 0, len(payload)), payload...)
+
+	s.writeMu.Lock()
+	_ = s.Conn.SetWriteDeadline(time.Now().Add(max(wait, goodbyeWriteTimeout)))
+	_, err := s.Conn.Write(frame)
+	s.writeMu.Unlock()
+
+	if err == nil && wait > 0 {
+		s.awaitGoodbyeAck(wait)
+	}
+}
+
+// awaitGoodbyeAck waits for the peer's ack. Without an active reader, Close drains the
+// connection itself (remaining data frames are dropped); otherwise that reader sees the ack.
+func (s *FramedTCPSocket) awaitGoodbyeAck(wait time.Duration) {
+	if s.readMu.TryLock() {
+		defer s.readMu.Unlock()
+		_ = s.Conn.SetReadDeadline(time.Now().Add(wait))
+		for !s.close.isAcked() {
+			if _, _, err := s.readMessage(); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	select {
+	case <-s.close.ackChan():
+	case <-time.After(wait):
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 // LocalAddr returns the local network address.
@@ -246,3 +573,25 @@ func (s *FramedTCPSocket) LocalAddr() net.Addr {
 func (s *FramedTCPSocket) RemoteAddr() net.Addr {
 	return s.Conn.RemoteAddr()
 }
+
+// -----------------------------------------------------------------------------
+
+// ConnInfo describes the transport layer ("framed-tcp", "mem", or "tls" with its session).
+func (s *FramedTCPSocket) ConnInfo() interfaces.ConnInfo {
+	info := interfaces.ConnInfo{
+		Layers:       []string{"framed-tcp"},
+		Transport:    interfaces.TransportFramedTCP,
+		LocalAddr:    s.LocalAddr(),
+		RemoteAddr:   s.RemoteAddr(),
+		FrameVersion: s.version.get(),
+		IdleTimeout:  s.idleTimeout,
+		OpenedAt:     s.openedAt,
+	}
+	if state, ok := s.TLSConnectionState(); ok {
+		info.Layers[0], info.Transport, info.TLS = "tls", interfaces.TransportTLS, &state
+	}
+	if _, ok := s.Conn.(*memConn); ok {
+		info.Layers[0], info.Transport = "mem", interfaces.TransportMem
+	}
+	return info
+}
@@ -2,10 +2,7 @@ package transports
 
 import (
 	"crypto/tls"
-	"crypto/x509"
-	"fmt"
 	"net"
-	"os"
 	"time"
 
 	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
@@ -15,6 +12,7 @@ import (
 type FramedTCPListener struct {
 	Listener net.Listener
 	Timeout  time.Duration
+	stop     func() // Ends the TLS material watcher (nil without one)
 }
 
 // -----------------------------------------------------------------------------
@@ -41,6 +39,9 @@ func (l *FramedTCPListener) Accept() (interfaces.TransportConnection, error) {
 
 // Close closes the listener.
 func (l *FramedTCPListener) Close() error {
+	if l.stop != nil {
+		l.stop()
+	}
 	return l.Listener.Close()
 }
 
@@ -65,40 +66,28 @@ func Listen(address string, timeout time.Duration) (interfaces.TransportListener
 	}, nil
 }
 
-// ListenTLS creates a new TLS-enabled FramedTCPListener.
+// ListenTLS creates a new TLS-enabled FramedTCPListener from certificate files.
 func ListenTLS(address string, timeout time.Duration, certFile, keyFile, caFile string) (interfaces.TransportListener, error) {
-	if certFile == "" || keyFile == "" {
-		return nil, fmt.Errorf("TLS listener requires certFile and keyFile")
-	}
+	return ListenTLSWithOptions(address, timeout, TLSOptions{CertFile: certFile, KeyFile: keyFile, CAFile: caFile})
+}
 
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// ListenTLSWithOptions creates a new TLS-enabled FramedTCPListener. A CA bundle enables
+// mTLS; with ReloadInterval set, rotated files apply to new connections until Close.
+func ListenTLSWithOptions(address string, timeout time.Duration, opts TLSOptions) (interfaces.TransportListener, error) {
+	tlsConfig, stop, err := serverTLSConfig(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load server key pair: %w", err)
-	}
-
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-	}
-
-	// Load CA if provided (for mTLS)
-	if caFile != "" {
-		caCert, err := os.ReadFile(caFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read CA file: %w", err)
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tlsConfig.ClientCAs = caCertPool
-		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		return nil, err
 	}
 
 	ln, err := tls.Listen("tcp", address, tlsConfig)
 	if err != nil {
+		stop()
 		return nil, err
 	}
 
 	return &FramedTCPListener{
 		Listener: ln,
 		Timeout:  timeout,
+		stop:     stop,
 	}, nil
 }
@@ -0,0 +1,145 @@
+package transports
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// Graceful Close (Goodbye)
+//
+// Close announces itself to the peer before tearing the connection down:
+//   - TCP/TLS (frame version 1): a FrameGoodbye control frame [Code:2][Reason...],
+//     answered by a FrameGoodbyeAck from the peer's read path.
+//   - SHM: the closing side stores the code in its close word and sets its status
+//     word to StatusClosing; the peer acknowledges by setting its own status to
+//     StatusClosed. The reason text is not carried over SHM.
+//
+// The peer's Read/ReadMessage then fails with a *PeerClosedError (errors.Is
+// ErrPeerClosed) once every frame sent before the goodbye has been delivered.
+
+// goodbyeWriteTimeout bounds the goodbye write so Close never hangs on a stuck peer.
+const goodbyeWriteTimeout = 100 * time.Millisecond
+
+// ErrPeerClosed matches (errors.Is) the error returned by reads after the peer closed gracefully.
+var ErrPeerClosed = errors.New("peer closed the connection")
+
+// -----------------------------------------------------------------------------
+
+// PeerClosedError is returned by Read/ReadMessage once the peer closed gracefully.
+type PeerClosedError struct {
+	Code   interfaces.CloseCode
+	Reason string
+}
+
+func (e *PeerClosedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%v (code %d)", ErrPeerClosed, e.Code)
+	}
+	return fmt.Sprintf("%v (code %d): %s", ErrPeerClosed, e.Code, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrPeerClosed) match any PeerClosedError.
+func (e *PeerClosedError) Is(target error) bool {
+	return target == ErrPeerClosed
+}
+
+// -----------------------------------------------------------------------------
+
+// encodeGoodbye builds a FrameGoodbye payload, truncating the reason to fit a control frame.
+func encodeGoodbye(code interfaces.CloseCode, reason string) []byte {
+	if len(reason) > MaxControlPayloadSize-2 {
+		reason = reason[:MaxControlPayloadSize-2]
+	}
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+	return payload
+}
+
+// decodeGoodbye parses a FrameGoodbye payload. A short payload means CloseNormal.
+func decodeGoodbye(payload []byte) *PeerClosedError {
+	if len(payload) < 2 {
+		return &PeerClosedError{Code: interfaces.CloseNormal}
+	}
+	return &PeerClosedError{
+		Code:   interfaces.CloseCode(binary.BigEndian.Uint16(payload)),
+		Reason: string(payload[2:]),
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// closeState tracks the goodbye exchange of one connection (both directions).
+type closeState struct {
+	mu         sync.Mutex
+	code       interfaces.CloseCode
+	reason     string
+	ackTimeout atomic.Int64
+	closing    atomic.Bool
+	peer       atomic.Pointer[PeerClosedError]
+	ackOnce    sync.Once
+	acked      chan struct{}
+}
+
+func (c *closeState) setReason(code interfaces.CloseCode, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.code, c.reason = code, reason
+}
+
+func (c *closeState) getReason() (interfaces.CloseCode, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.code, c.reason
+}
+
+// begin marks the connection as closing. It returns false if Close already ran.
+func (c *closeState) begin() bool {
+	return !c.closing.Swap(true)
+}
+
+// ackChan returns the channel closed when the peer acknowledged our goodbye.
+func (c *closeState) ackChan() chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.acked == nil {
+		c.acked = make(chan struct{})
+	}
+	return c.acked
+}
+
+func (c *closeState) markAcked() {
+	ch := c.ackChan()
+	c.ackOnce.Do(func() { close(ch) })
+}
+
+func (c *closeState) isAcked() bool {
+	select {
+	case <-c.ackChan():
+		return true
+	default:
+		return false
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// CloserOf walks a wrapper chain (via Unwrap) down to the first connection able to
+// announce a graceful close. It returns nil when the chain has none (e.g. UDP).
+func CloserOf(conn interfaces.TransportConnection) interfaces.GracefulCloser {
+	gc, _ := unwrapTo[interfaces.GracefulCloser](conn)
+	return gc
+}
+
+// AborterOf walks a wrapper chain (via Unwrap) down to the first connection able to
+// close without announcing it. It returns nil when the chain has none.
+func AborterOf(conn interfaces.TransportConnection) interfaces.Aborter {
+	a, _ := unwrapTo[interfaces.Aborter](conn)
+	return a
+}
@@ -0,0 +1,122 @@
+package transports
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// exerciseGoodbye closes the closer with a reason while the peer reads, and checks that
+// data sent before the goodbye is delivered, followed by the typed close error.
+func exerciseGoodbye(t *testing.T, closer, peer interfaces.TransportConnection, wantReason string) {
+	gc := closer.(interfaces.GracefulCloser)
+	gc.SetCloseReason(interfaces.CloseGoingAway, "restarting")
+	gc.SetCloseAckTimeout(time.Second)
+
+	if _, err := closer.Write([]byte("last words")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		msg, err := peer.ReadMessage()
+		if err != nil || string(msg) != "last words" {
+			readErr <- errors.New("data sent before the goodbye was lost")
+			return
+		}
+		_, err = peer.ReadMessage()
+		readErr <- err
+	}()
+
+	start := time.Now()
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Close waited the full ack timeout (%v): goodbye not acknowledged", elapsed)
+	}
+
+	err := <-readErr
+	var pc *PeerClosedError
+	if !errors.Is(err, ErrPeerClosed) || !errors.As(err, &pc) {
+		t.Fatalf("expected PeerClosedError, got %v", err)
+	}
+	if pc.Code != interfaces.CloseGoingAway || pc.Reason != wantReason {
+		t.Errorf("expected code %d reason %q, got code %d reason %q", interfaces.CloseGoingAway, wantReason, pc.Code, pc.Reason)
+	}
+
+	// The error is sticky
+	if _, err := peer.ReadMessage(); !errors.Is(err, ErrPeerClosed) {
+		t.Errorf("expected sticky ErrPeerClosed, got %v", err)
+	}
+}
+
+func TestGoodbye(t *testing.T) {
+	tcpPair := func(t *testing.T) (*FramedTCPSocket, *FramedTCPSocket) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, _ := ln.Accept()
+			accepted <- conn
+		}()
+
+		clientConn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewFramedTCPSocket(clientConn, 2*time.Second), NewFramedTCPSocket(<-accepted, 2*time.Second)
+	}
+
+	t.Run("FramedTCP", func(t *testing.T) {
+		client, server := tcpPair(t)
+		defer func() { _ = server.Close() }()
+		_ = client.SetFrameVersion(FrameVersionTyped)
+		_ = server.SetFrameVersion(FrameVersionTyped)
+
+		exerciseGoodbye(t, client, server, "restarting")
+	})
+
+	t.Run("FramedTCP_Legacy", func(t *testing.T) {
+		client, server := tcpPair(t)
+		defer func() { _ = server.Close() }()
+
+		// Version 0 cannot express a goodbye: the peer sees a plain EOF
+		_ = client.Close()
+		if _, err := server.ReadMessage(); err == nil || errors.Is(err, ErrPeerClosed) {
+			t.Errorf("expected a plain read error on legacy framing, got %v", err)
+		}
+	})
+
+	t.Run("SHM", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "goodbye.shm")
+		defer func() { _ = os.Remove(path) }()
+
+		ln, err := ListenShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		client, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// SHM carries the code only
+		exerciseGoodbye(t, client, server, "")
+	})
+}
@@ -0,0 +1,347 @@
+package transports
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// The mem transport connects a client to a named listener of the same process, for
+// tests: no port, no file. Both ends are FramedTCPSockets over an in-memory stream that
+// behaves like a TCP connection (buffered writes with backpressure, deadlines, EOF
+// after the peer's Close), so framing, control frames, heartbeats, goodbyes and idle
+// timeouts work exactly as over TCP.
+
+const (
+	// memBufferSize is the capacity of each direction of a mem connection, beyond
+	// which writes block like on a full TCP socket.
+	memBufferSize = 4 * 1024 * 1024
+	// memBacklog is the number of connections waiting for Accept before new ones are
+	// refused.
+	memBacklog = 128
+)
+
+var (
+	memMu        sync.Mutex
+	memListeners = map[string]*MemListener{}
+	memClients   atomic.Uint64
+)
+
+// -----------------------------------------------------------------------------
+
+// memAddr is the address of a mem endpoint: the listener name, or the name plus a
+// client number for the dialing end.
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+// -----------------------------------------------------------------------------
+
+// MemListener implements interfaces.TransportListener for the mem transport.
+type MemListener struct {
+	name    string
+	timeout time.Duration
+	backlog chan *memConn
+	done    chan struct{}
+	once    sync.Once
+}
+
+// ListenMem registers an in-memory listener under name, until Close.
+func ListenMem(name string, timeout time.Duration) (interfaces.TransportListener, error) {
+	memMu.Lock()
+	defer memMu.Unlock()
+	if _, ok := memListeners[name]; ok {
+		return nil, &net.OpError{Op: "listen", Net: "mem", Addr: memAddr(name), Err: syscall.EADDRINUSE}
+	}
+	l := &MemListener{
+		name:    name,
+		timeout: timeout,
+		backlog: make(chan *memConn, memBacklog),
+		done:    make(chan struct{}),
+	}
+	memListeners[name] = l
+	return l, nil
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *MemListener) Accept() (interfaces.TransportConnection, error) {
+	select {
+	case conn := <-l.backlog:
+		return NewFramedTCPSocket(conn, l.timeout), nil
+	case <-l.done:
+		return nil, &net.OpError{Op: "accept", Net: "mem", Addr: memAddr(l.name), Err: net.ErrClosed}
+	}
+}
+
+// Close unregisters the listener. Connections not accepted yet are closed.
+func (l *MemListener) Close() error {
+	l.once.Do(func() {
+		memMu.Lock()
+		delete(memListeners, l.name)
+		memMu.Unlock()
+		close(l.done)
+		for {
+			select {
+			case conn := <-l.backlog:
+				_ = conn.Close()
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// Addr returns the listener's name.
+func (l *MemListener) Addr() net.Addr {
+	return memAddr(l.name)
+}
+
+// -----------------------------------------------------------------------------
+
+// ConnectMem connects to the in-memory listener registered under name.
+func ConnectMem(name string, timeout time.Duration) (interfaces.TransportConnection, error) {
+	refused := &net.OpError{Op: "dial", Net: "mem", Addr: memAddr(name), Err: syscall.ECONNREFUSED}
+
+	memMu.Lock()
+	l := memListeners[name]
+	memMu.Unlock()
+	if l == nil {
+		return nil, refused
+	}
+
+	client, server := newMemPipe(memAddr(fmt.Sprintf("%s#%d", name, memClients.Add(1))), memAddr(name))
+	select {
+	case <-l.done:
+		return nil, refused
+	default:
+	}
+	select {
+	case l.backlog <- server:
+		return NewFramedTCPSocket(client, timeout), nil
+	default:
+		return nil, refused // Backlog full
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// memStream is one direction of a mem connection.
+type memStream struct {
+	mu      sync.Mutex
+	buf     []byte
+	eof     bool          // Writer closed: reads return io.EOF once drained
+	broken  bool          // Reader closed: writes fail
+	changed chan struct{} // Closed and replaced on every change
+}
+
+func newMemStream() *memStream {
+	return &memStream{changed: make(chan struct{})}
+}
+
+// notify wakes up the goroutines waiting on the stream. Callers hold mu.
+func (s *memStream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// memConn is one end of an in-memory connection: a net.Conn with TCP semantics.
+type memConn struct {
+	local, remote memAddr
+	in, out       *memStream
+
+	readDeadline, writeDeadline memDeadline
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// newMemPipe returns both ends of a new in-memory connection.
+func newMemPipe(clientAddr, serverAddr memAddr) (*memConn, *memConn) {
+	a, b := newMemStream(), newMemStream()
+	client := &memConn{local: clientAddr, remote: serverAddr, in: a, out: b, closed: make(chan struct{})}
+	server := &memConn{local: serverAddr, remote: clientAddr, in: b, out: a, closed: make(chan struct{})}
+	for _, c := range []*memConn{client, server} {
+		c.readDeadline.cancel = make(chan struct{})
+		c.writeDeadline.cancel = make(chan struct{})
+	}
+	return client, server
+}
+
+func (c *memConn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "mem", Source: c.local, Addr: c.remote, Err: err}
+}
+
+func (c *memConn) Read(p []byte) (int, error) {
+	for {
+		select {
+		case <-c.closed:
+			return 0, c.opError("read", net.ErrClosed)
+		case <-c.readDeadline.wait():
+			return 0, c.opError("read", os.ErrDeadlineExceeded)
+		default:
+		}
+
+		c.in.mu.Lock()
+		if len(c.in.buf) > 0 || len(p) == 0 {
+			n := copy(p, c.in.buf)
+			c.in.buf = c.in.buf[n:]
+			c.in.notify()
+			c.in.mu.Unlock()
+			return n, nil
+		}
+		if c.in.eof {
+			c.in.mu.Unlock()
+			return 0, io.EOF
+		}
+		changed := c.in.changed
+		c.in.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-c.closed:
+		case <-c.readDeadline.wait():
+		}
+	}
+}
+
+func (c *memConn) Write(p []byte) (int, error) {
+	var n int
+	for {
+		select {
+		case <-c.closed:
+			return n, c.opError("write", net.ErrClosed)
+		case <-c.writeDeadline.wait():
+			return n, c.opError("write", os.ErrDeadlineExceeded)
+		default:
+		}
+
+		c.out.mu.Lock()
+		if c.out.broken {
+			c.out.mu.Unlock()
+			return n, c.opError("write", syscall.EPIPE)
+		}
+		if free := memBufferSize - len(c.out.buf); free > 0 {
+			chunk := min(free, len(p)-n)
+			c.out.buf = append(c.out.buf, p[n:n+chunk]...)
+			n += chunk
+			c.out.notify()
+		}
+		if n == len(p) {
+			c.out.mu.Unlock()
+			return n, nil
+		}
+		changed := c.out.changed
+		c.out.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-c.closed:
+		case <-c.writeDeadline.wait():
+		}
+	}
+}
+
+// Close ends both directions: the peer reads what was already written, then io.EOF,
+// and its writes fail.
+func (c *memConn) Close() error {
+	first := false
+	c.closeOnce.Do(func() {
+		first = true
+		close(c.closed)
+		c.out.mu.Lock()
+		c.out.eof = true
+		c.out.notify()
+		c.out.mu.Unlock()
+		c.in.mu.Lock()
+		c.in.broken, c.in.buf = true, nil
+		c.in.notify()
+		c.in.mu.Unlock()
+	})
+	if !first {
+		return c.opError("close", net.ErrClosed)
+	}
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr  { return c.local }
+func (c *memConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *memConn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return nil
+}
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *memConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// memDeadline is a deadline whose channel is closed once it has passed (as in net.Pipe).
+type memDeadline struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+// set arms the deadline at t (zero = none).
+func (d *memDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel // The timer fired: wait for it to close cancel
+	}
+	d.timer = nil
+
+	expired := isClosed(d.cancel)
+	if t.IsZero() {
+		if expired {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+	if dur := time.Until(t); dur > 0 {
+		if expired {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() { close(cancel) })
+		return
+	}
+	if !expired {
+		close(d.cancel)
+	}
+}
+
+// wait returns a channel closed once the deadline has passed.
+func (d *memDeadline) wait() chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,83 @@
+package transports
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMemTransport(t *testing.T) {
+	if _, err := ConnectMem("mem-test", time.Second); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("expected a refused connection, got %v", err)
+	}
+	ln, err := ListenMem("mem-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	if _, err := ListenMem("mem-test", time.Second); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("expected the name to be in use, got %v", err)
+	}
+
+	client, err := ConnectMem("mem-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.LocalAddr().String() != "mem-test" || client.RemoteAddr().String() != "mem-test" ||
+		server.RemoteAddr().String() != client.LocalAddr().String() || client.LocalAddr().Network() != "mem" {
+		t.Errorf("unexpected addresses %v -> %v", client.LocalAddr(), server.RemoteAddr())
+	}
+	if info := server.ConnInfo(); info.Layers[0] != "mem" || info.Transport != "Memory" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	// 1. Framing, and writes that outgrow the buffer wait for the reader
+	big := make([]byte, 2*memBufferSize)
+	go func() { _, _ = client.Write(big) }()
+	if msg, err := server.ReadMessage(); err != nil || len(msg) != len(big) {
+		t.Fatalf("expected %d bytes, got %d (%v)", len(big), len(msg), err)
+	}
+
+	// 2. Deadlines and the idle timeout report timeouts
+	_ = server.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	var ne net.Error
+	if _, err := server.ReadMessage(); !errors.As(err, &ne) || !ne.Timeout() || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	_ = server.SetReadDeadline(time.Time{})
+	_ = client.SetIdleTimeout(20 * time.Millisecond)
+	if _, err := client.ReadMessage(); !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("expected an idle timeout, got %v", err)
+	}
+	_ = client.SetIdleTimeout(0)
+
+	// 3. Data written before Close is still delivered, then EOF
+	if _, err := client.Write([]byte("last")); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close()
+	if msg, err := server.ReadMessage(); err != nil || string(msg) != "last" {
+		t.Errorf("expected 'last', got %q (%v)", msg, err)
+	}
+	if _, err := server.ReadMessage(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	// 4. A closed listener frees its name
+	_ = ln.Close()
+	if _, err := ln.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed, got %v", err)
+	}
+	ln, err = ListenMem("mem-test", time.Second)
+	if err != nil {
+		t.Fatalf("name not released: %v", err)
+	}
+}
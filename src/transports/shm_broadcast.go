@@ -0,0 +1,497 @@
+package transports
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/edsrzf/mmap-go"
+)
+
+// SHM Broadcast Ring (single producer, many consumers)
+//
+// The publisher appends frames to one ring and never waits for readers: the oldest
+// frames are overwritten. Each subscriber follows the ring with its own cursor and
+// detects being lapped (ErrShmOverrun) instead of reading overwritten data.
+//
+// Layout:
+//
+//	[0-127]  : Header (capacity, commit, reserve, sequence, generation, publisher PID/status)
+//	[128-...]: Ring of frames [Length:4][Reserved:4][Sequence:8][Payload], 8-byte aligned
+//
+// A frame never wraps: when it does not fit before the end of the ring, the rest of
+// the ring is skipped with a padding marker. The publisher advances the reserve
+// position before writing and the commit position after; a reader that copied a frame
+// starting at cursor knows it was intact if reserve did not pass cursor + capacity.
+
+// DefaultBroadcastSize is the ring capacity of a broadcast segment when none is set.
+const DefaultBroadcastSize = 32 * 1024 * 1024
+
+const (
+	bcOffsetCapacity   = 0
+	bcOffsetCommit     = 8  // End of the last published frame (monotonic byte position)
+	bcOffsetReserve    = 16 // End of the frame being written
+	bcOffsetSequence   = 24 // Sequence number of the last published frame
+	bcOffsetGeneration = 32 // Bumped by every publisher start
+	bcOffsetPID        = 40 // Publisher's PID word (see ownerWord)
+	bcOffsetStatus     = 48
+	bcOffsetBeat       = 56 // Publisher's liveness beat (see startBeat)
+
+	bcFrameHeaderSize = 16
+	bcPadding         = math.MaxUint32 // Length marker of the skipped end of the ring
+)
+
+var (
+	// ErrShmOverrun matches (errors.Is) the error of a subscriber lapped by the publisher.
+	ErrShmOverrun = errors.New("shm broadcast: subscriber overrun by the publisher")
+
+	ErrShmNoPublisher     = errors.New("shm broadcast: no publisher on this segment")
+	ErrShmBroadcastRole   = errors.New("shm broadcast: operation not supported by this end")
+	ErrShmBroadcastLayout = errors.New("shm broadcast: corrupt frame")
+)
+
+// ShmOverrunError is returned by a subscriber whose unread frames were overwritten.
+type ShmOverrunError struct {
+	// Missed is the number of messages skipped by resyncing to the latest one.
+	Missed uint64
+}
+
+func (e *ShmOverrunError) Error() string {
+	return fmt.Sprintf("%v (%d messages missed)", ErrShmOverrun, e.Missed)
+}
+
+// Is makes errors.Is(err, ErrShmOverrun) match any ShmOverrunError.
+func (e *ShmOverrunError) Is(target error) bool {
+	return target == ErrShmOverrun
+}
+
+// -----------------------------------------------------------------------------
+
+// ShmBroadcast is one end of a broadcast ring: the publisher (Write) or a
+// subscriber (Read/ReadMessage). It implements interfaces.TransportConnection.
+type ShmBroadcast struct {
+	File      *os.File
+	MMap      mmap.MMap
+	Publisher bool
+
+	data       []byte
+	capacity   uint64
+	commit     *uint64
+	reserve    *uint64
+	sequence   *uint64
+	generation uint64
+	handoff    io.Closer // memfd descriptor handoff socket (publisher)
+	beat       *shmBeat  // Liveness beat (publisher)
+
+	writeMu sync.Mutex // Single producer: serialize writers of this process
+
+	readMu        sync.Mutex
+	cursor        uint64
+	lastSeq       uint64
+	autoResync    atomic.Bool
+	readDeadline  atomic.Int64
+	idleTimeout   time.Duration
+	lastPeerCheck atomic.Int64
+
+	closed   atomic.Bool
+	openedAt time.Time
+}
+
+// PublishShmBroadcast creates (or takes over) a broadcast segment of the given ring
+// size (0 = DefaultBroadcastSize). Subscribers of a previous publisher see ErrShmReset.
+func PublishShmBroadcast(path string, size int, opts ShmOptions) (*ShmBroadcast, error) {
+	if size <= 0 {
+		size = DefaultBroadcastSize
+	}
+	size = (size + 7) &^ 7
+
+	var file *os.File
+	var handoff io.Closer
+	var err error
+	if opts.Memfd {
+		file, handoff, err = createMemfd(path, opts)
+	} else {
+		file, err = openShmFile(ResolveShmPath(path, opts.Dir), opts, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	closeAll := func() {
+		if handoff != nil {
+			_ = handoff.Close()
+		}
+		_ = file.Close()
+	}
+
+	// Never shrink: subscribers of a previous publisher may still map the old size
+	info, err := file.Stat()
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	if info.Size() < int64(MetaSize+size) {
+		if err := file.Truncate(int64(MetaSize + size)); err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+	m, err := mmap.Map(file, mmap.RDWR, 0)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	status := atomic.LoadUint64(shmWord(m, bcOffsetStatus))
+	if status == StatusConnected && liveOwner(m, bcOffsetPID) && beating(shmWord(m, bcOffsetBeat)) {
+		_ = m.Unmap()
+		closeAll()
+		return nil, fmt.Errorf("%w: publisher pid %d", ErrShmInUse, ownerPID(atomic.LoadUint64(shmWord(m, bcOffsetPID))))
+	}
+
+	// New generation first: subscribers of the previous publisher stop reading
+	atomic.AddUint64(shmWord(m, bcOffsetGeneration), 1)
+	for _, offset := range []int{bcOffsetCommit, bcOffsetReserve, bcOffsetSequence} {
+		atomic.StoreUint64(shmWord(m, offset), 0)
+	}
+	atomic.StoreUint64(shmWord(m, bcOffsetCapacity), uint64(size))
+	b := newShmBroadcast(file, m, true)
+	b.beat = startBeat(m, bcOffsetBeat)
+	atomic.StoreUint64(shmWord(m, bcOffsetPID), ownerWord())
+	atomic.StoreUint64(shmWord(m, bcOffsetStatus), StatusConnected)
+	b.handoff = handoff
+	return b, nil
+}
+
+// SubscribeShmBroadcast attaches to the broadcast segment of a running publisher.
+// Reading starts with the next message published.
+func SubscribeShmBroadcast(path string, opts ShmOptions) (*ShmBroadcast, error) {
+	var file *os.File
+	var err error
+	if opts.Memfd {
+		file, err = openMemfd(path, opts, shmAttachTimeout)
+	} else {
+		resolved := ResolveShmPath(path, opts.Dir)
+		if _, err := os.Lstat(resolved); errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrShmNoPublisher, resolved)
+		}
+		file, err = openShmFile(resolved, opts, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if info.Size() <= MetaSize {
+		_ = file.Close()
+		return nil, ErrShmNoPublisher
+	}
+	// Subscribers only read: a stray write faults instead of corrupting the ring
+	m, err := mmap.Map(file, mmap.RDONLY, 0)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	capacity := atomic.LoadUint64(shmWord(m, bcOffsetCapacity))
+	if atomic.LoadUint64(shmWord(m, bcOffsetStatus)) != StatusConnected || !publishing(m) ||
+		capacity == 0 || MetaSize+capacity > uint64(len(m)) {
+		_ = m.Unmap()
+		_ = file.Close()
+		return nil, ErrShmNoPublisher
+	}
+
+	b := newShmBroadcast(file, m, false)
+	b.Resync()
+	return b, nil
+}
+
+// publishing reports whether the publisher of the segment is alive and beating.
+func publishing(m mmap.MMap) bool {
+	return alive(atomic.LoadUint64(shmWord(m, bcOffsetPID))) && beating(shmWord(m, bcOffsetBeat))
+}
+
+func newShmBroadcast(f *os.File, m mmap.MMap, publisher bool) *ShmBroadcast {
+	capacity := atomic.LoadUint64(shmWord(m, bcOffsetCapacity))
+	return &ShmBroadcast{
+		File:       f,
+		MMap:       m,
+		Publisher:  publisher,
+		data:       m[MetaSize : MetaSize+capacity],
+		capacity:   capacity,
+		commit:     shmWord(m, bcOffsetCommit),
+		reserve:    shmWord(m, bcOffsetReserve),
+		sequence:   shmWord(m, bcOffsetSequence),
+		generation: atomic.LoadUint64(shmWord(m, bcOffsetGeneration)),
+		openedAt:   time.Now(),
+	}
+}
+
+// MaxMessageSize returns the largest payload the ring accepts.
+func (b *ShmBroadcast) MaxMessageSize() int {
+	return int(b.capacity/2) - bcFrameHeaderSize
+}
+
+// -----------------------------------------------------------------------------
+
+// Write publishes one message (publisher only). It never waits for subscribers.
+func (b *ShmBroadcast) Write(p []byte) (int, error) {
+	if !b.Publisher {
+		return 0, ErrShmBroadcastRole
+	}
+	if len(p) > b.MaxMessageSize() {
+		return 0, io.ErrShortBuffer
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	if b.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if atomic.LoadUint64(shmWord(b.MMap, bcOffsetGeneration)) != b.generation {
+		return 0, ErrShmReset // Another publisher took the segment over
+	}
+
+	need := uint64(bcFrameHeaderSize+len(p)+7) &^ 7
+	pos := atomic.LoadUint64(b.commit)
+	idx := pos % b.capacity
+
+	if idx+need > b.capacity {
+		// Skip the end of the ring so that the frame stays contiguous
+		atomic.StoreUint64(b.reserve, pos+(b.capacity-idx)+need)
+		binary.BigEndian.PutUint32(b.data[idx:], bcPadding)
+		pos += b.capacity - idx
+		idx = 0
+	} else {
+		atomic.StoreUint64(b.reserve, pos+need)
+	}
+
+	seq := atomic.LoadUint64(b.sequence) + 1
+	binary.BigEndian.PutUint32(b.data[idx:], uint32(len(p)))
+	binary.BigEndian.PutUint32(b.data[idx+4:], 0)
+	binary.BigEndian.PutUint64(b.data[idx+8:], seq)
+	copy(b.data[idx+bcFrameHeaderSize:], p)
+
+	atomic.StoreUint64(b.sequence, seq)
+	atomic.StoreUint64(b.commit, pos+need)
+	return len(p), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// SetAutoResync makes an overrun subscriber jump to the latest message by itself:
+// the ShmOverrunError is still returned once, and the next read continues from there.
+func (b *ShmBroadcast) SetAutoResync(enabled bool) {
+	b.autoResync.Store(enabled)
+}
+
+// Resync moves a subscriber to the latest message: the next read returns the next
+// message published. Call it after an ShmOverrunError.
+func (b *ShmBroadcast) Resync() {
+	// Sequence before commit: it never describes a frame beyond the cursor
+	b.lastSeq = atomic.LoadUint64(b.sequence)
+	b.cursor = atomic.LoadUint64(b.commit)
+}
+
+// Read reads one message into p (subscriber only).
+func (b *ShmBroadcast) Read(p []byte) (int, error) {
+	msg, err := b.ReadMessage()
+	if err != nil {
+		return 0, err
+	}
+	if len(msg) > len(p) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(p, msg), nil
+}
+
+// ReadMessage returns the next message (subscriber only), or an *ShmOverrunError if
+// the publisher overwrote it before it was read.
+func (b *ShmBroadcast) ReadMessage() ([]byte, error) {
+	if b.Publisher {
+		return nil, ErrShmBroadcastRole
+	}
+	b.readMu.Lock()
+	defer b.readMu.Unlock()
+
+	for {
+		if b.closed.Load() {
+			return nil, io.EOF
+		}
+		if atomic.LoadUint64(shmWord(b.MMap, bcOffsetGeneration)) != b.generation {
+			return nil, ErrShmReset
+		}
+
+		commit := atomic.LoadUint64(b.commit)
+		if commit == b.cursor {
+			if err := b.wait(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if commit-b.cursor > b.capacity {
+			return nil, b.overrun()
+		}
+
+		idx := b.cursor % b.capacity
+		length := binary.BigEndian.Uint32(b.data[idx:])
+		if length == bcPadding {
+			if b.lapped() {
+				return nil, b.overrun()
+			}
+			b.cursor += b.capacity - idx
+			continue
+		}
+		if idx+bcFrameHeaderSize+uint64(length) > b.capacity {
+			if b.lapped() {
+				return nil, b.overrun()
+			}
+			return nil, ErrShmBroadcastLayout
+		}
+
+		seq := binary.BigEndian.Uint64(b.data[idx+8:])
+		msg := make([]byte, length)
+		copy(msg, b.data[idx+bcFrameHeaderSize:])
+		if b.lapped() {
+			return nil, b.overrun() // Overwritten while copying
+		}
+
+		b.cursor += uint64(bcFrameHeaderSize+length+7) &^ 7
+		b.lastSeq = seq
+		b.refreshReadDeadline()
+		return msg, nil
+	}
+}
+
+// lapped reports whether the publisher started overwriting the frame at the cursor.
+func (b *ShmBroadcast) lapped() bool {
+	return atomic.LoadUint64(b.reserve) > b.cursor+b.capacity
+}
+
+func (b *ShmBroadcast) overrun() error {
+	err := &ShmOverrunError{Missed: atomic.LoadUint64(b.sequence) - b.lastSeq}
+	if b.autoResync.Load() {
+		b.Resync()
+	}
+	return err
+}
+
+// wait backs off while no message is available, checking the deadline and the publisher.
+func (b *ShmBroadcast) wait() error {
+	rd := b.readDeadline.Load()
+	if rd > 0 && time.Now().UnixNano() > rd {
+		return os.ErrDeadlineExceeded
+	}
+
+	now := time.Now().UnixNano()
+	if now-b.lastPeerCheck.Load() >= int64(shmPeerCheckInterval) {
+		b.lastPeerCheck.Store(now)
+		if atomic.LoadUint64(shmWord(b.MMap, bcOffsetStatus)) == StatusClosed {
+			return &PeerClosedError{Code: interfaces.CloseNormal}
+		}
+		if !publishing(b.MMap) {
+			return ErrShmPeerDead
+		}
+	}
+	time.Sleep(1 * time.Microsecond)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// Close detaches from the segment. A publisher marks it closed first: its subscribers
+// read the remaining messages, then get a *PeerClosedError.
+func (b *ShmBroadcast) Close() error {
+	if b.closed.Swap(true) {
+		return nil
+	}
+	if b.Publisher {
+		b.writeMu.Lock()
+		if atomic.LoadUint64(shmWord(b.MMap, bcOffsetGeneration)) == b.generation {
+			atomic.StoreUint64(shmWord(b.MMap, bcOffsetStatus), StatusClosed)
+		}
+		b.writeMu.Unlock()
+		b.beat.halt()
+		if b.handoff != nil {
+			_ = b.handoff.Close()
+		}
+	} else {
+		b.readMu.Lock() // Wait for the reader to leave the mapping
+		defer b.readMu.Unlock()
+	}
+
+	if err := b.MMap.Unmap(); err != nil {
+		_ = b.File.Close()
+		return err
+	}
+	return b.File.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *ShmBroadcast) refreshReadDeadline() {
+	if b.idleTimeout > 0 {
+		b.readDeadline.Store(time.Now().Add(b.idleTimeout).UnixNano())
+	}
+}
+
+// SetIdleTimeout makes reads fail when no message arrived for d (0 = wait forever).
+// Publisher liveness is tracked through its PID, not heartbeats.
+func (b *ShmBroadcast) SetIdleTimeout(d time.Duration) error {
+	b.idleTimeout = d
+	if d == 0 {
+		b.readDeadline.Store(0)
+	} else {
+		b.refreshReadDeadline()
+	}
+	return nil
+}
+
+func (b *ShmBroadcast) SetDeadline(t time.Time) error {
+	return b.SetReadDeadline(t)
+}
+
+func (b *ShmBroadcast) SetReadDeadline(t time.Time) error {
+	if t.IsZero() {
+		b.readDeadline.Store(0)
+		return nil
+	}
+	b.readDeadline.Store(t.UnixNano())
+	return nil
+}
+
+// SetWriteDeadline is a no-op: publishing never blocks.
+func (b *ShmBroadcast) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (b *ShmBroadcast) LocalAddr() net.Addr {
+	return ShmAddr{}
+}
+
+func (b *ShmBroadcast) RemoteAddr() net.Addr {
+	return ShmAddr{}
+}
+
+// ConnInfo describes the transport layer ("shm-broadcast").
+func (b *ShmBroadcast) ConnInfo() interfaces.ConnInfo {
+	return interfaces.ConnInfo{
+		Layers:      []string{"shm-broadcast"},
+		Transport:   interfaces.TransportShmBroadcast,
+		LocalAddr:   b.LocalAddr(),
+		RemoteAddr:  b.RemoteAddr(),
+		IdleTimeout: b.idleTimeout,
+		OpenedAt:    b.openedAt,
+	}
+}
@@ -0,0 +1,136 @@
+package transports
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestShmBroadcast(t *testing.T) {
+	setup := func(t *testing.T, size, subscribers int) (*ShmBroadcast, []*ShmBroadcast) {
+		path := filepath.Join(t.TempDir(), "ticks.shm")
+		pub, err := PublishShmBroadcast(path, size, ShmOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = pub.Close() })
+
+		subs := make([]*ShmBroadcast, subscribers)
+		for i := range subs {
+			if subs[i], err = SubscribeShmBroadcast(path, ShmOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			sub := subs[i]
+			t.Cleanup(func() { _ = sub.Close() })
+		}
+		return pub, subs
+	}
+
+	t.Run("FanOut", func(t *testing.T) {
+		// A small ring wraps many times: frames are padded, never split
+		pub, subs := setup(t, 1024, 3)
+		for i := 0; i < 500; i++ {
+			msg := fmt.Sprintf("tick-%d-%s", i, make([]byte, i%97))
+			if _, err := pub.Write([]byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+			for n, sub := range subs {
+				got, err := sub.ReadMessage()
+				if err != nil {
+					t.Fatalf("subscriber %d, message %d: %v", n, i, err)
+				}
+				if string(got) != msg {
+					t.Fatalf("subscriber %d: expected %q, got %q", n, msg, got)
+				}
+			}
+		}
+	})
+
+	t.Run("Overrun", func(t *testing.T) {
+		pub, subs := setup(t, 1024, 2)
+		slow, auto := subs[0], subs[1]
+		auto.SetAutoResync(true)
+
+		for i := 0; i < 100; i++ {
+			if _, err := pub.Write([]byte(fmt.Sprintf("tick-%03d", i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var overrun *ShmOverrunError
+		if _, err := slow.ReadMessage(); !errors.As(err, &overrun) || !errors.Is(err, ErrShmOverrun) {
+			t.Fatalf("expected an ShmOverrunError, got %v", err)
+		}
+		if overrun.Missed != 100 {
+			t.Errorf("expected 100 missed messages, got %d", overrun.Missed)
+		}
+		// Without resync the subscriber stays lapped
+		if _, err := slow.ReadMessage(); !errors.Is(err, ErrShmOverrun) {
+			t.Errorf("expected the overrun to persist until Resync, got %v", err)
+		}
+		slow.Resync()
+
+		if _, err := auto.ReadMessage(); !errors.Is(err, ErrShmOverrun) {
+			t.Fatalf("expected the auto-resync subscriber to report the overrun, got %v", err)
+		}
+
+		if _, err := pub.Write([]byte("latest")); err != nil {
+			t.Fatal(err)
+		}
+		for _, sub := range subs {
+			got, err := sub.ReadMessage()
+			if err != nil || string(got) != "latest" {
+				t.Errorf("expected 'latest' after resync, got %q (%v)", got, err)
+			}
+		}
+	})
+
+	t.Run("PublisherClose", func(t *testing.T) {
+		pub, subs := setup(t, 4096, 1)
+		if _, err := pub.Write([]byte("last words")); err != nil {
+			t.Fatal(err)
+		}
+		_ = pub.Close()
+
+		if got, err := subs[0].ReadMessage(); err != nil || string(got) != "last words" {
+			t.Fatalf("expected pending messages to be delivered, got %q (%v)", got, err)
+		}
+		if _, err := subs[0].ReadMessage(); !errors.Is(err, ErrPeerClosed) {
+			t.Errorf("expected ErrPeerClosed, got %v", err)
+		}
+		if _, err := subs[0].Write([]byte("x")); !errors.Is(err, ErrShmBroadcastRole) {
+			t.Errorf("expected subscribers to be read-only, got %v", err)
+		}
+	})
+
+	t.Run("PublisherCrash", func(t *testing.T) {
+		pub, subs := setup(t, 4096, 1)
+		// The publisher died long ago and its PID went to another live process
+		pub.beat.halt()
+		reuse(pub.MMap, bcOffsetPID, bcOffsetBeat)
+
+		_ = subs[0].SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := subs[0].ReadMessage(); !errors.Is(err, ErrShmPeerDead) {
+			t.Errorf("expected ErrShmPeerDead, got %v", err)
+		}
+		next, err := PublishShmBroadcast(pub.File.Name(), 4096, ShmOptions{})
+		if err != nil {
+			t.Fatalf("expected a new publisher to take over, got %v", err)
+		}
+		_ = next.Close()
+	})
+
+	t.Run("ReadOnlySubscriber", func(t *testing.T) {
+		_, subs := setup(t, 4096, 1)
+		defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+		defer func() {
+			if recover() == nil {
+				t.Error("expected writing to a subscriber mapping to fault")
+			}
+		}()
+		subs[0].data[0] = 1
+	})
+}
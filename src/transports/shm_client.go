@@ -15,7 +15,19 @@ import (
 // ConnectShm opens the file and memory maps it.
 // If the file is smaller than TotalSize, it is grown.
 func ConnectShm(path string, timeout time.Duration) (interfaces.TransportConnection, error) {
-	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	return ConnectShmWithOptions(path, timeout, ShmOptions{})
+}
+
+// ConnectShmWithOptions is ConnectShm with explicit placement and permissions.
+// With opts.Memfd, the segment descriptor is received from the listener instead.
+func ConnectShmWithOptions(path string, timeout time.Duration, opts ShmOptions) (interfaces.TransportConnection, error) {
+	var file *os.File
+	var err error
+	if opts.Memfd {
+		file, err = openMemfd(path, opts, timeout)
+	} else {
+		file, err = openShmFile(ResolveShmPath(path, opts.Dir), opts, false)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -41,10 +53,16 @@ func ConnectShm(path string, timeout time.Duration) (interfaces.TransportConnect
 		return nil, err
 	}
 
-	t := NewShmTransport(file, m, "client", timeout)
+	// Signal presence to listener (refused if another live client holds the segment)
+	if err := attachClient(m, timeout); err != nil {
+		_ = m.Unmap()
+		_ = file.Close()
+		return nil, err
+	}
 
-	// Signal presence to listener
-	atomic.StoreUint64(t.ClientStatus, StatusConnected)
+	t := NewShmTransport(file, m, "client", timeout)
+	t.generation = atomic.LoadUint64(shmWord(m, OffsetClientGeneration))
+	t.beat = startBeat(m, OffsetClientBeat)
 
 	return t, nil
 }
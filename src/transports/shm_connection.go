@@ -1,14 +1,16 @@
 package transports
 
 import (
-	"encoding/binary"
 	"io"
 	"net"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/metrics"
 	"github.com/edsrzf/mmap-go"
 )
 
@@ -37,6 +39,18 @@ const (
 	OffsetClientStatus   = 40
 	OffsetServerActivity = 48
 	OffsetClientActivity = 56
+
+	// Close codes (see goodbye.go), valid while the status is StatusClosing
+	OffsetServerCloseCode = 64
+	OffsetClientCloseCode = 72
+
+	// Crash recovery (see shm_recovery.go)
+	OffsetServerPID        = 80
+	OffsetClientPID        = 88
+	OffsetGeneration       = 96  // Bumped by every listener reset
+	OffsetClientGeneration = 104 // Generation the client attached to
+	OffsetServerBeat       = 112 // Liveness timestamps, refreshed even when idle
+	OffsetClientBeat       = 120
 )
 
 // Status Values
@@ -44,6 +58,8 @@ const (
 	StatusIdle      = 0
 	StatusListening = 1
 	StatusConnected = 2
+	StatusClosing   = 3 // Goodbye sent, close code published
+	StatusClosed    = 4 // Peer's goodbye acknowledged
 )
 
 // ShmTransport implements a Shared Memory Ring Buffer transport.
@@ -66,6 +82,31 @@ type ShmTransport struct {
 	writeDeadline            atomic.Int64
 	idleTimeout              time.Duration
 	closed                   atomic.Bool
+	writeMu                  sync.Mutex // The ring is single-producer: serialize writers
+	control                  controlDispatcher
+	version                  frameVersion
+	close                    closeState
+	trace                    traceState
+	tap                      frameTap
+	myStatus                 *uint64
+	peerStatus               *uint64
+	myCloseCode              *uint64
+	peerCloseCode            *uint64
+	peerPID                  *uint64
+	peerBeat                 *uint64
+	beat                     *shmBeat // Published by this side (nil = the listener's)
+	generationWord           *uint64
+	generation               uint64                         // Session generation, see sessionError
+	lastPeerCheck            atomic.Int64                   // Throttles PID liveness checks
+	readMu                   sync.Mutex                     // Lets a listener wait for in-flight reads before a reset
+	detach                   func() error                   // Releases the segment on Close (nil = unmap and close the file)
+	reservation              atomic.Pointer[shmReservation] // Pending ReserveWrite (see shm_zerocopy.go)
+	releasePending           atomic.Bool                    // Close left the segment to the pending reservation
+	peeked                   uint64                         // Bytes of the message held by PeekMessage (guarded by readMu)
+	metrics                  atomic.Pointer[metrics.Socket]
+	ringOut                  atomic.Int64 // Ring occupancy last reported to metrics
+	ringIn                   atomic.Int64
+	openedAt                 time.Time
 }
 
 // -----------------------------------------------------------------------------
@@ -79,6 +120,11 @@ func NewShmTransport(f *os.File, m mmap.MMap, role string, timeout time.Duration
 	var pHead, pTail, cHead, cTail *uint64
 	var pData, cData []byte
 	var myActivity, peerActivity *uint64
+	var myStatus, peerStatus, myCloseCode, peerCloseCode *uint64
+	srvCloseCode := (*uint64)(unsafe.Pointer(&m[OffsetServerCloseCode]))
+	cliCloseCode := (*uint64)(unsafe.Pointer(&m[OffsetClientCloseCode]))
+	peerPID := (*uint64)(unsafe.Pointer(&m[OffsetClientPID]))
+	peerBeat := (*uint64)(unsafe.Pointer(&m[OffsetClientBeat]))
 
 	// Buffer A is [MetaSize : MetaSize + BufferDataSize]
 	// Buffer B is [MetaSize + BufferDataSize : TotalSize]
@@ -95,6 +141,10 @@ func NewShmTransport(f *os.File, m mmap.MMap, role string, timeout time.Duration
 		cData = bufB
 		myActivity = cliActivity
 		peerActivity = srvActivity
+		myStatus, peerStatus = cliStatus, srvStatus
+		myCloseCode, peerCloseCode = cliCloseCode, srvCloseCode
+		peerPID = (*uint64)(unsafe.Pointer(&m[OffsetServerPID]))
+		peerBeat = (*uint64)(unsafe.Pointer(&m[OffsetServerBeat]))
 	} else {
 		// Server writes to B, reads from A
 		pHead = (*uint64)(unsafe.Pointer(&m[OffsetHeadB]))
@@ -105,6 +155,8 @@ func NewShmTransport(f *os.File, m mmap.MMap, role string, timeout time.Duration
 		cData = bufA
 		myActivity = srvActivity
 		peerActivity = cliActivity
+		myStatus, peerStatus = srvStatus, cliStatus
+		myCloseCode, peerCloseCode = srvCloseCode, cliCloseCode
 	}
 
 	t := &ShmTransport{
@@ -123,7 +175,16 @@ func NewShmTransport(f *os.File, m mmap.MMap, role string, timeout time.Duration
 		PeerActivity:             peerActivity,
 		lastObservedPeerActivity: atomic.LoadUint64(peerActivity),
 		idleTimeout:              timeout,
+		myStatus:                 myStatus,
+		peerStatus:               peerStatus,
+		myCloseCode:              myCloseCode,
+		peerCloseCode:            peerCloseCode,
+		peerPID:                  peerPID,
+		peerBeat:                 peerBeat,
+		generationWord:           (*uint64)(unsafe.Pointer(&m[OffsetGeneration])),
+		openedAt:                 time.Now(),
 	}
+	t.generation = atomic.LoadUint64(t.generationWord)
 
 	if timeout > 0 {
 		t.readDeadline.Store(time.Now().Add(timeout).UnixNano())
@@ -167,68 +228,164 @@ func (t *ShmTransport) SetIdleTimeout(d time.Duration) error {
 // -----------------------------------------------------------------------------
 
 func (t *ShmTransport) SetDeadline(deadline time.Time) error {
-	t.readDeadline.Store(deadline.UnixNano())
-	t.writeDeadline.Store(deadline.UnixNano())
+	t.readDeadline.Store(deadlineNanos(deadline))
+	t.writeDeadline.Store(deadlineNanos(deadline))
 	return nil
 }
 
 // -----------------------------------------------------------------------------
 
 func (t *ShmTransport) SetReadDeadline(deadline time.Time) error {
-	t.readDeadline.Store(deadline.UnixNano())
+	t.readDeadline.Store(deadlineNanos(deadline))
 	return nil
 }
 
 // -----------------------------------------------------------------------------
 
 func (t *ShmTransport) SetWriteDeadline(deadline time.Time) error {
-	t.writeDeadline.Store(deadline.UnixNano())
+	t.writeDeadline.Store(deadlineNanos(deadline))
 	return nil
 }
 
+// deadlineNanos converts a deadline to the stored form: 0 for the zero time (none).
+func deadlineNanos(deadline time.Time) int64 {
+	if deadline.IsZero() {
+		return 0
+	}
+	return deadline.UnixNano()
+}
+
+// deadlinePassed reports whether the deadline stored in d has passed.
+func deadlinePassed(d *atomic.Int64) bool {
+	nanos := d.Load()
+	return nanos > 0 && time.Now().UnixNano() > nanos
+}
+
 // -----------------------------------------------------------------------------
 
 // Write (Producer Role)
+// An empty write is sent as a heartbeat frame.
 func (t *ShmTransport) Write(p []byte) (n int, err error) {
-	lenData := uint64(len(p))
-	// Framing: 4-byte header
-	totalLen := 4 + lenData
+	frameType := interfaces.FrameData
+	if len(p) == 0 {
+		frameType = interfaces.FrameHeartbeat
+	}
+	if err := t.writeFrame(frameType, 0, nil, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteTraced writes p as one data frame carrying the trace context tc, once trace
+// contexts have been negotiated (see trace_context.go). Otherwise tc is dropped.
+func (t *ShmTransport) WriteTraced(p, tc []byte) (n int, err error) {
+	if len(p) == 0 {
+		return t.Write(p)
+	}
+	flags, prefix, err := t.trace.prefix(tc)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.writeFrame(interfaces.FrameData, flags, prefix, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// WriteControl sends a typed control frame. It requires frame version 1.
+func (t *ShmTransport) WriteControl(frameType interfaces.FrameType, payload []byte) error {
+	if t.version.get() == FrameVersionLegacy {
+		return ErrControlUnsupported
+	}
+	if len(payload) > MaxControlPayloadSize {
+		return ErrBadControlFrame
+	}
+	return t.writeFrame(frameType, 0, nil, payload)
+}
+
+// -----------------------------------------------------------------------------
+
+// SetControlHandler registers the callback receiving inbound control frames.
+func (t *ShmTransport) SetControlHandler(h interfaces.ControlHandler) {
+	t.control.set(h)
+}
+
+// SetFrameVersion switches the header format for every subsequent frame.
+// Only call it at a frame boundary agreed with the peer (end of the handshake).
+func (t *ShmTransport) SetFrameVersion(v uint8) error {
+	return t.version.set(v)
+}
+
+// FrameVersion returns the header version currently spoken on this connection.
+func (t *ShmTransport) FrameVersion() uint8 {
+	return t.version.get()
+}
+
+// EnableTraceContext lets data frames carry trace contexts in both directions.
+// Only call it once the peer agreed on it (end of the handshake).
+func (t *ShmTransport) EnableTraceContext() error {
+	return t.trace.enable(t.version.get())
+}
+
+// TraceContextEnabled reports whether trace contexts were negotiated.
+func (t *ShmTransport) TraceContextEnabled() bool {
+	return t.trace.enabled.Load()
+}
+
+// -----------------------------------------------------------------------------
+
+// writeFrame publishes one frame (header + prefix + body) into the produce ring.
+func (t *ShmTransport) writeFrame(frameType interfaces.FrameType, flags uint8, prefix, body []byte) error {
+	version := t.version.get()
+	header := append(encodeHeader(version, frameType, flags, len(prefix)+len(body)), prefix...)
+	lenData := uint64(len(body))
+	totalLen := uint64(len(header)) + lenData
 
 	if totalLen > BufferDataSize {
-		return 0, io.ErrShortBuffer
+		return io.ErrShortBuffer
 	}
 
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if err := t.writable(); err != nil {
+		return err
+	}
+	t.refreshWriteDeadline()
+	if deadlinePassed(&t.writeDeadline) {
+		return os.ErrDeadlineExceeded
+	}
 	for {
-		if t.closed.Load() {
-			return 0, io.ErrClosedPipe
+		if err := t.writable(); err != nil {
+			return err
 		}
 
 		tail := atomic.LoadUint64(t.ProduceTail)
 		head := atomic.LoadUint64(t.ProduceHead)
 
 		if tail-head+totalLen > BufferDataSize {
-			wd := t.writeDeadline.Load()
-			if wd > 0 && time.Now().UnixNano() > wd {
-				return 0, os.ErrDeadlineExceeded
+			if err := t.waitWritable(); err != nil {
+				return err
 			}
-			time.Sleep(1 * time.Microsecond)
 			continue
 		}
 
-		// 1. Write Header (4 bytes, BigEndian)
-		header := make([]byte, 4)
-		binary.BigEndian.PutUint32(header, uint32(lenData))
+		// 1. Write Header (4 bytes BigEndian, + type/flags in v1)
 		t.writeToRing(tail, header)
 
 		// 2. Write Data
 		if lenData > 0 {
-			t.writeToRing(tail+4, p)
+			t.writeToRing(tail+uint64(len(header)), body)
 		}
 
 		atomic.AddUint64(t.ProduceTail, totalLen)
 		t.refreshWriteDeadline()
+		t.reportRings()
+		t.tap.observe(interfaces.FrameOutbound, version, frameType, flags, prefix, body)
 
-		return int(lenData), nil
+		return nil
 	}
 }
 
@@ -250,16 +407,112 @@ func (t *ShmTransport) writeToRing(offset uint64, p []byte) {
 
 // Read (Consumer Role)
 func (t *ShmTransport) Read(p []byte) (n int, err error) {
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+
+	if t.peeked != 0 {
+		return 0, ErrShmPeekPending
+	}
+
+	head, headerSize, length, tc, err := t.nextDataFrame()
+	if err != nil {
+		return 0, err
+	}
+
+	// Check Buffer Size BEFORE consuming the frame
+	if uint64(len(p)) < length {
+		return 0, io.ErrShortBuffer
+	}
+
+	t.readFromRing(head+headerSize, p[:length])
+	t.release(headerSize + length)
+	t.observeData(tc, p[:length])
+
+	return int(length), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// ReadMessage for SHM reads exactly one frame.
+func (t *ShmTransport) ReadMessage() ([]byte, error) {
+	msg, _, err := t.ReadMessageTraced()
+	return msg, err
+}
+
+// ReadMessageTraced reads one message along with the trace context sent with it
+// (nil if none, see trace_context.go).
+func (t *ShmTransport) ReadMessageTraced() (msg, tc []byte, err error) {
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+
+	if t.peeked != 0 {
+		return nil, nil, ErrShmPeekPending
+	}
+
+	head, headerSize, length, tc, err := t.nextDataFrame()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Allocate and Read Body
+	buf := make([]byte, length)
+	t.readFromRing(head+headerSize, buf)
+	t.release(headerSize + length)
+	t.observeData(tc, buf)
+
+	return buf, tc, nil
+}
+
+// observeData reports a data frame read to the frame observer, rebuilding the trace
+// context prefix that nextDataFrame split off.
+func (t *ShmTransport) observeData(tc, payload []byte) {
+	if !t.tap.active() {
+		return
+	}
+	if tc == nil {
+		t.tap.observe(interfaces.FrameInbound, t.version.get(), interfaces.FrameData, 0, nil, payload)
+		return
+	}
+	prefix := append([]byte{byte(len(tc))}, tc...)
+	t.tap.observe(interfaces.FrameInbound, t.version.get(), interfaces.FrameData, FlagTraceContext, prefix, payload)
+}
+
+// -----------------------------------------------------------------------------
+
+// nextDataFrame spins until a complete, non-empty data frame sits at the consume head.
+// Heartbeats are discarded and control frames dispatched on the way. The frame is not
+// consumed: the caller copies the body out and calls release. A trace context is
+// returned apart, counted in headerSize.
+func (t *ShmTransport) nextDataFrame() (head, headerSize, length uint64, tc []byte, err error) {
+	if pc := t.close.peer.Load(); pc != nil {
+		return 0, 0, 0, nil, pc
+	}
+	t.refreshReadDeadline()
 	for {
 		if t.closed.Load() {
-			return 0, io.EOF
+			return 0, 0, 0, nil, io.EOF
+		}
+		if t.generationChanged() {
+			return 0, 0, 0, nil, ErrShmReset
 		}
 
+		// Status is loaded before the tail: frames written before a goodbye are seen first
+		peerStatus := atomic.LoadUint64(t.peerStatus)
+		version := t.version.get()
+		headerSize = uint64(t.version.headerSize())
 		tail := atomic.LoadUint64(t.ConsumeTail)
-		head := atomic.LoadUint64(t.ConsumeHead)
+		head = atomic.LoadUint64(t.ConsumeHead)
+
+		// Check if we have at least the header
+		if tail-head < headerSize {
+			// GOODBYE: Ring drained and the peer announced a graceful close
+			if peerStatus == StatusClosing {
+				pc := &PeerClosedError{Code: interfaces.CloseCode(atomic.LoadUint64(t.peerCloseCode))}
+				t.close.peer.Store(pc)
+				atomic.StoreUint64(t.myStatus, StatusClosed)
+				return 0, 0, 0, nil, pc
+			}
 
-		// Check if we have at least the 4-byte header
-		if tail-head < 4 {
 			// HEARTBEAT AUDIT FIX: Check if peer is active even without data
 			activity := atomic.LoadUint64(t.PeerActivity)
 			if activity > t.lastObservedPeerActivity {
@@ -267,44 +520,156 @@ func (t *ShmTransport) Read(p []byte) (n int, err error) {
 				t.lastObservedPeerActivity = activity
 			}
 
-			rd := t.readDeadline.Load()
-			if rd > 0 && time.Now().UnixNano() > rd {
-				return 0, os.ErrDeadlineExceeded
+			if err := t.sessionError(); err != nil {
+				return 0, 0, 0, nil, err
+			}
+
+			if deadlinePassed(&t.readDeadline) {
+				return 0, 0, 0, nil, os.ErrDeadlineExceeded
 			}
 			time.Sleep(1 * time.Microsecond)
 			continue
 		}
 
 		// 1. Read Header
-		header := make([]byte, 4)
+		header := make([]byte, headerSize)
 		t.readFromRing(head, header)
-		length := uint64(binary.BigEndian.Uint32(header))
+		frameLen, frameType, flags := decodeHeader(version, header)
+		length = uint64(frameLen)
+
+		// Padding left by ReserveWrite: skip to the ring start
+		if frameLen&framePaddingBit != 0 {
+			skip := headerSize + uint64(frameLen&^framePaddingBit)
+			if tail-head < skip {
+				time.Sleep(1 * time.Microsecond)
+				continue
+			}
+			t.release(skip)
+			continue
+		}
 
 		// 2. Check if entire frame is available
-		if tail-head < 4+length {
+		if tail-head < headerSize+length {
 			// Frame incomplete, wait
 			time.Sleep(1 * time.Microsecond)
 			continue
 		}
 
-		// 3. Handle Heartbeats (Length 0)
-		if length == 0 {
-			atomic.AddUint64(t.ConsumeHead, 4)
+		// 3. Handle Heartbeats and Control Frames
+		if frameType != interfaces.FrameData || length == 0 {
+			if err := t.consumeControl(head, headerSize, version, frameType, flags, frameLen); err != nil {
+				return 0, 0, 0, nil, err
+			}
 			continue
 		}
 
-		// 4. Read Body
-		if uint64(len(p)) < length {
-			return 0, io.ErrShortBuffer
+		// 4. Split the trace context off the payload (see trace_context.go)
+		if t.trace.traced(flags) {
+			prefix := make([]byte, 1)
+			t.readFromRing(head+headerSize, prefix)
+			skip := uint64(prefix[0]) + 1
+			if skip > length {
+				t.release(headerSize + length)
+				return 0, 0, 0, nil, ErrBadTraceContext
+			}
+			tc = make([]byte, skip-1)
+			t.readFromRing(head+headerSize+1, tc)
+			headerSize += skip
+			length -= skip
 		}
+		return head, headerSize, length, tc, nil
+	}
+}
 
-		t.readFromRing(head+4, p[:length])
+// consumeControl consumes the heartbeat or control frame at head, dispatching it.
+func (t *ShmTransport) consumeControl(head, headerSize uint64, version uint8, frameType interfaces.FrameType, flags uint8, length uint32) error {
+	if length > MaxControlPayloadSize {
+		return ErrBadControlFrame
+	}
+	body := make([]byte, length)
+	t.readFromRing(head+headerSize, body)
+	t.release(headerSize + uint64(length))
+	t.tap.observe(interfaces.FrameInbound, version, frameType, flags, nil, body)
+	if isSkippable(frameType, length) {
+		t.metrics.Load().HeartbeatSkipped()
+	} else {
+		t.control.dispatch(frameType, body)
+	}
+	return nil
+}
 
-		atomic.AddUint64(t.ConsumeHead, 4+length)
-		t.refreshReadDeadline()
+// PumpControl dispatches the control frames (pings, pongs, heartbeats) sitting in
+// the consume ring ahead of the next data frame.
+func (t *ShmTransport) PumpControl() error {
+	if !t.readMu.TryLock() {
+		return nil // A reader is in, and dispatches them
+	}
+	defer t.readMu.Unlock()
+
+	for t.peeked == 0 && !t.closed.Load() && !t.generationChanged() {
+		version := t.version.get()
+		headerSize := uint64(t.version.headerSize())
+		tail := atomic.LoadUint64(t.ConsumeTail)
+		head := atomic.LoadUint64(t.ConsumeHead)
+		if tail-head < headerSize {
+			return nil
+		}
+		header := make([]byte, headerSize)
+		t.readFromRing(head, header)
+		frameLen, frameType, flags := decodeHeader(version, header)
+		if frameLen&framePaddingBit != 0 {
+			skip := headerSize + uint64(frameLen&^framePaddingBit)
+			if tail-head < skip {
+				return nil
+			}
+			t.release(skip)
+			continue
+		}
+		if frameType == interfaces.FrameData && frameLen > 0 || tail-head < headerSize+uint64(frameLen) {
+			return nil // Left to the reader
+		}
+		if err := t.consumeControl(head, headerSize, version, frameType, flags, frameLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release hands n bytes at the consume head back to the producer.
+func (t *ShmTransport) release(n uint64) {
+	atomic.AddUint64(t.ConsumeHead, n)
+	t.refreshReadDeadline()
+	t.reportRings()
+}
+
+// -----------------------------------------------------------------------------
+
+// SetMetrics makes the transport record discarded heartbeats and ring occupancy into m.
+func (t *ShmTransport) SetMetrics(m *metrics.Socket) {
+	t.metrics.Store(m)
+	t.reportRings()
+}
+
+// SetFrameObserver makes the transport report every frame it writes or reads to o
+// (nil = stop reporting).
+func (t *ShmTransport) SetFrameObserver(o interfaces.FrameObserver) {
+	t.tap.set(o)
+}
 
-		return int(length), nil
+// reportRings publishes the change of both rings' occupancy since the last report.
+func (t *ShmTransport) reportRings() {
+	m := t.metrics.Load()
+	if m == nil || t.closed.Load() {
+		return
 	}
+	out := int64(atomic.LoadUint64(t.ProduceTail) - atomic.LoadUint64(t.ProduceHead))
+	in := int64(atomic.LoadUint64(t.ConsumeTail) - atomic.LoadUint64(t.ConsumeHead))
+	m.AddShmRing(out-t.ringOut.Swap(out), in-t.ringIn.Swap(in))
+}
+
+// forgetRings withdraws this transport's share of the ring occupancy (once closed).
+func (t *ShmTransport) forgetRings() {
+	t.metrics.Load().AddShmRing(-t.ringOut.Swap(0), -t.ringIn.Swap(0))
 }
 
 // readFromRing is a helper to handle wrapped reads.
@@ -323,65 +688,50 @@ func (t *ShmTransport) readFromRing(offset uint64, p []byte) {
 
 // -----------------------------------------------------------------------------
 
-// ReadMessage for SHM reads exactly one frame.
-func (t *ShmTransport) ReadMessage() ([]byte, error) {
-	for {
-		if t.closed.Load() {
-			return nil, io.EOF
-		}
-
-		tail := atomic.LoadUint64(t.ConsumeTail)
-		head := atomic.LoadUint64(t.ConsumeHead)
-
-		if tail-head < 4 {
-			// HEARTBEAT AUDIT FIX: Check if peer is active even without data
-			activity := atomic.LoadUint64(t.PeerActivity)
-			if activity > t.lastObservedPeerActivity {
-				t.refreshReadDeadline()
-				t.lastObservedPeerActivity = activity
-			}
-
-			rd := t.readDeadline.Load()
-			if rd > 0 && time.Now().UnixNano() > rd {
-				return nil, os.ErrDeadlineExceeded
-			}
-			time.Sleep(1 * time.Microsecond)
-			continue
-		}
-
-		// 1. Read Header
-		header := make([]byte, 4)
-		t.readFromRing(head, header)
-		length := uint64(binary.BigEndian.Uint32(header))
+func (t *ShmTransport) Close() error {
+	// Mark as closed BEFORE unmapping to stop spin-loops safely
+	if t.closed.Swap(true) {
+		return nil // Already closed
+	}
+	t.sendGoodbye()
+	return t.unmap()
+}
 
-		// 2. Check if entire frame is available
-		if tail-head < 4+length {
-			time.Sleep(1 * time.Microsecond)
-			continue
-		}
+// Abort closes the transport without a goodbye: the status word stays connected, as
+// after a crash, until the peer's liveness check notices.
+func (t *ShmTransport) Abort() error {
+	if t.closed.Swap(true) {
+		return nil // Already closed
+	}
+	return t.unmap()
+}
 
-		// 3. Handle Heartbeats (Length 0)
-		if length == 0 {
-			atomic.AddUint64(t.ConsumeHead, 4)
-			continue
+// unmap releases the segment once closed, after the in-flight reads and writes have
+// left the rings. A pending ReserveWrite keeps the segment mapped: CommitWrite or
+// AbortWrite releases it, so the reserved slice stays writable until then.
+func (t *ShmTransport) unmap() error {
+	t.forgetRings()
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+	if t.reservation.Load() != nil {
+		t.releasePending.Store(true)
+		// Release here only if the reservation ended meanwhile without taking it over
+		if t.reservation.Load() != nil || !t.releasePending.CompareAndSwap(true, false) {
+			return nil
 		}
-
-		// 4. Allocate and Read Body
-		buf := make([]byte, length)
-		t.readFromRing(head+4, buf)
-
-		atomic.AddUint64(t.ConsumeHead, 4+length)
-		t.refreshReadDeadline()
-		return buf, nil
 	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return t.releaseSegment()
 }
 
-// -----------------------------------------------------------------------------
-
-func (t *ShmTransport) Close() error {
-	// Mark as closed BEFORE unmapping to stop spin-loops safely
-	if t.closed.Swap(true) {
-		return nil // Already closed
+// releaseSegment unmaps the segment (or detaches from the listener's mapping).
+func (t *ShmTransport) releaseSegment() error {
+	if t.beat != nil {
+		t.beat.halt()
+	}
+	if t.detach != nil {
+		return t.detach()
 	}
 
 	// Flush? MMap usually syncs periodically.
@@ -394,6 +744,44 @@ func (t *ShmTransport) Close() error {
 
 // -----------------------------------------------------------------------------
 
+// SetCloseReason sets the code announced by the next Close (the reason text is not
+// carried over SHM).
+func (t *ShmTransport) SetCloseReason(code interfaces.CloseCode, reason string) {
+	t.close.setReason(code, reason)
+}
+
+// SetCloseAckTimeout makes Close wait up to d for the peer's acknowledgement (0 = don't wait).
+func (t *ShmTransport) SetCloseAckTimeout(d time.Duration) {
+	t.close.ackTimeout.Store(int64(d))
+}
+
+// sendGoodbye publishes the close code and flips our status word to StatusClosing,
+// then optionally polls the peer's status word for the acknowledgement.
+func (t *ShmTransport) sendGoodbye() {
+	if atomic.LoadUint64(t.myStatus) != StatusConnected || t.close.peer.Load() != nil || t.generationChanged() {
+		return // Never connected, the peer already said goodbye, or the segment was reset
+	}
+	code, _ := t.close.getReason()
+	atomic.StoreUint64(t.myCloseCode, uint64(code))
+	atomic.StoreUint64(t.myStatus, StatusClosing)
+
+	wait := time.Duration(t.close.ackTimeout.Load())
+	if wait <= 0 {
+		return
+	}
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		// StatusClosing covers a simultaneous close from both sides
+		if status := atomic.LoadUint64(t.peerStatus); status == StatusClosed || status == StatusClosing {
+			t.close.markAcked()
+			return
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
 // LocalAddr returns the local network address (SHM pseudo-address).
 func (t *ShmTransport) LocalAddr() net.Addr {
 	return ShmAddr{}
@@ -408,6 +796,21 @@ func (t *ShmTransport) RemoteAddr() net.Addr {
 
 // -----------------------------------------------------------------------------
 
+// ConnInfo describes the transport layer ("shm").
+func (t *ShmTransport) ConnInfo() interfaces.ConnInfo {
+	return interfaces.ConnInfo{
+		Layers:       []string{"shm"},
+		Transport:    interfaces.TransportShm,
+		LocalAddr:    t.LocalAddr(),
+		RemoteAddr:   t.RemoteAddr(),
+		FrameVersion: t.version.get(),
+		IdleTimeout:  t.idleTimeout,
+		OpenedAt:     t.openedAt,
+	}
+}
+
+// -----------------------------------------------------------------------------
+
 // ShmAddr implements net.Addr for Shared Memory.
 type ShmAddr struct{}
 
@@ -0,0 +1,111 @@
+//go:build linux
+
+package transports
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// createMemfd creates an anonymous segment and starts handing its descriptor to
+// clients dialing the abstract socket of path. The returned closer stops the handoff.
+func createMemfd(path string, opts ShmOptions) (*os.File, io.Closer, error) {
+	fd, err := unix.MemfdCreate("safe-socket:"+path, unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, nil, fmt.Errorf("shm: memfd_create: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), "memfd:"+path)
+
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: memfdSocketName(path), Net: "unix"})
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("shm: memfd handoff socket: %w", err)
+	}
+	go serveMemfd(ln, file, opts)
+	return file, ln, nil
+}
+
+// serveMemfd sends the segment descriptor to every trusted peer until ln is closed.
+func serveMemfd(ln *net.UnixListener, file *os.File, opts ShmOptions) {
+	for {
+		conn, err := ln.AcceptUnix()
+		if err != nil {
+			return
+		}
+		if err := checkMemfdPeer(conn, opts); err == nil {
+			_, _, _ = conn.WriteMsgUnix([]byte{1}, syscall.UnixRights(int(file.Fd())), nil)
+		}
+		_ = conn.Close()
+	}
+}
+
+// openMemfd receives the descriptor of the segment listening on path.
+func openMemfd(path string, opts ShmOptions, timeout time.Duration) (*os.File, error) {
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: memfdSocketName(path), Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("shm: memfd handoff: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The listener must be a process we would trust with a segment file
+	if err := checkMemfdPeer(conn, opts); err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+
+	buf := make([]byte, 1)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if errors.Is(err, io.EOF) {
+		return nil, ErrMemfdPeerRejected // Hung up on: our credentials were refused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shm: memfd handoff: %w", err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		return nil, errors.New("shm: memfd handoff: no descriptor received")
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		return nil, errors.New("shm: memfd handoff: no descriptor received")
+	}
+	return os.NewFile(uintptr(fds[0]), "memfd:"+path), nil
+}
+
+// checkMemfdPeer verifies the SO_PEERCRED of the other end of the handoff socket.
+func checkMemfdPeer(conn *net.UnixConn, opts ShmOptions) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return err
+	}
+	if credErr != nil {
+		return credErr
+	}
+
+	if allowedUID(int(cred.Uid), opts) {
+		return nil
+	}
+	if opts.Group != "" {
+		if gid, err := lookupGID(opts.Group); err == nil && int(cred.Gid) == gid {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: uid %d", ErrMemfdPeerRejected, cred.Uid)
+}
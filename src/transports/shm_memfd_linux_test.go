@@ -0,0 +1,50 @@
+package transports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShmMemfd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "anonymous.shm")
+	opts := ShmOptions{Memfd: true}
+
+	ln, err := ListenShmWithOptions(path, 2*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	client, err := ConnectShmWithOptions(path, 2*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Write([]byte("no file")); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != "no file" {
+		t.Errorf("expected 'no file', got %q", msg)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected nothing on disk, found %d entries", len(entries))
+	}
+
+	// Without a listener there is nothing to attach to
+	if _, err := ConnectShmWithOptions(filepath.Join(dir, "missing.shm"), time.Second, opts); err == nil {
+		t.Error("expected an error without a memfd listener")
+	}
+}
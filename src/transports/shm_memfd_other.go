@@ -0,0 +1,17 @@
+//go:build !linux
+
+package transports
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+func createMemfd(path string, opts ShmOptions) (*os.File, io.Closer, error) {
+	return nil, nil, ErrMemfdUnsupported
+}
+
+func openMemfd(path string, opts ShmOptions, timeout time.Duration) (*os.File, error) {
+	return nil, ErrMemfdUnsupported
+}
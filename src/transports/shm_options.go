@@ -0,0 +1,81 @@
+package transports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SHM segment placement and permissions.
+//
+// Segments are created with mode 0600 unless configured otherwise, never through a
+// symlink, and an existing segment is only opened if it belongs to the current user
+// (or the configured Owner). Bare names ("orders") are placed under /dev/shm when it
+// exists, so nothing lands in the working directory.
+
+// DefaultShmMode is the file mode of new SHM segments when ShmOptions.Mode is 0.
+const DefaultShmMode os.FileMode = 0600
+
+// DefaultShmDir is where bare segment names are placed when ShmOptions.Dir is empty
+// (only if the directory exists, e.g. on Linux).
+var DefaultShmDir = "/dev/shm"
+
+var (
+	ErrShmSymlink        = errors.New("shm: refusing to open a symlink")
+	ErrShmForeignOwner   = errors.New("shm: segment is owned by another user")
+	ErrShmNotRegular     = errors.New("shm: segment is not a regular file")
+	ErrMemfdUnsupported  = errors.New("shm: memfd segments are only supported on Linux")
+	ErrMemfdPeerRejected = errors.New("shm: memfd peer credentials rejected")
+)
+
+// ShmOptions configures how SHM segments are created and opened.
+type ShmOptions struct {
+	// Mode is the permission of a new segment (0 = DefaultShmMode). Listeners also
+	// restore it on an existing segment they own.
+	Mode os.FileMode
+
+	// Owner and Group (name or numeric id) are applied to a new segment; changing the
+	// owner requires privileges. An existing segment owned by Owner is accepted in
+	// addition to those of the current user.
+	Owner string
+	Group string
+
+	// Dir is where bare names are placed ("" = DefaultShmDir, "." = working directory).
+	Dir string
+
+	// Memfd backs the segment with an anonymous memfd instead of a file (Linux only).
+	// The listener hands its descriptor to clients over the abstract unix socket
+	// "@safe-socket/shm/<path>", checking their credentials against the current user,
+	// Owner and Group. Nothing is left on disk.
+	Memfd bool
+}
+
+func (o ShmOptions) mode() os.FileMode {
+	if o.Mode == 0 {
+		return DefaultShmMode
+	}
+	return o.Mode.Perm()
+}
+
+// -----------------------------------------------------------------------------
+
+// ResolveShmPath returns the file used for a segment path: bare names go under dir
+// (or DefaultShmDir if it exists), paths with a separator are used as given.
+func ResolveShmPath(path, dir string) string {
+	if strings.ContainsRune(path, '/') || strings.ContainsRune(path, filepath.Separator) {
+		return path
+	}
+	if dir == "" {
+		if info, err := os.Stat(DefaultShmDir); err != nil || !info.IsDir() {
+			return path
+		}
+		dir = DefaultShmDir
+	}
+	return filepath.Join(dir, path)
+}
+
+// memfdSocketName is the abstract unix socket a memfd listener hands its segment on.
+func memfdSocketName(path string) string {
+	return "@safe-socket/shm/" + path
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package transports
+
+import (
+	"fmt"
+	"os"
+)
+
+// openShmFile opens (or creates) the segment file. Ownership is not checked on this
+// platform: only symlinks are refused.
+func openShmFile(path string, opts ShmOptions, enforce bool) (*os.File, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrShmSymlink, path)
+	}
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, opts.mode())
+}
@@ -0,0 +1,105 @@
+//go:build unix
+
+package transports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShmPermissions(t *testing.T) {
+	t.Run("Mode", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, mode := range map[string]os.FileMode{"default.shm": 0, "shared.shm": 0640} {
+			ln, err := ListenShmWithOptions(name, time.Second, ShmOptions{Mode: mode, Dir: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = ln.Close()
+
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := mode
+			if want == 0 {
+				want = DefaultShmMode
+			}
+			if info.Mode().Perm() != want {
+				t.Errorf("%s: expected mode %v, got %v", name, want, info.Mode().Perm())
+			}
+		}
+
+		// A listener tightens a loose segment it owns
+		loose := filepath.Join(dir, "loose.shm")
+		if err := os.WriteFile(loose, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chmod(loose, 0666)
+		ln, err := ListenShmWithOptions(loose, time.Second, ShmOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = ln.Close()
+		if info, _ := os.Stat(loose); info.Mode().Perm() != DefaultShmMode {
+			t.Errorf("expected the loose segment to be reset to %v, got %v", DefaultShmMode, info.Mode().Perm())
+		}
+	})
+
+	t.Run("Symlink", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "target")
+		link := filepath.Join(dir, "link.shm")
+		if err := os.WriteFile(target, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ListenShm(link, time.Second); !errors.Is(err, ErrShmSymlink) {
+			t.Errorf("expected ErrShmSymlink from the listener, got %v", err)
+		}
+		if _, err := ConnectShm(link, time.Second); !errors.Is(err, ErrShmSymlink) {
+			t.Errorf("expected ErrShmSymlink from the client, got %v", err)
+		}
+		if info, _ := os.Stat(target); info.Size() != 0 {
+			t.Error("the symlink target was modified")
+		}
+	})
+
+	t.Run("ForeignOwner", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("changing file ownership requires root")
+		}
+		path := filepath.Join(t.TempDir(), "foreign.shm")
+		if err := os.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chown(path, 65534, 65534); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ConnectShm(path, time.Second); !errors.Is(err, ErrShmForeignOwner) {
+			t.Errorf("expected ErrShmForeignOwner, got %v", err)
+		}
+		// Unless that owner is expected
+		conn, err := ConnectShmWithOptions(path, time.Second, ShmOptions{Owner: "65534"})
+		if err != nil {
+			t.Fatalf("expected the configured owner to be accepted, got %v", err)
+		}
+		_ = conn.Close()
+	})
+
+	t.Run("BareName", func(t *testing.T) {
+		if got := ResolveShmPath("orders", "/run/app"); got != "/run/app/orders" {
+			t.Errorf("expected /run/app/orders, got %s", got)
+		}
+		if got := ResolveShmPath("./orders", ""); got != "./orders" {
+			t.Errorf("expected paths with a separator to be kept, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+//go:build unix
+
+package transports
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// openShmFile opens (or creates) the segment file without following symlinks.
+// A new file gets the configured mode and ownership; an existing one must be a
+// regular file owned by the current user or opts.Owner. Listeners (enforce) reset
+// the mode of an existing file they own.
+func openShmFile(path string, opts ShmOptions, enforce bool) (*os.File, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrShmSymlink, path)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL|syscall.O_NOFOLLOW, opts.mode())
+	if err == nil {
+		if err := initShmFile(file, opts); err != nil {
+			_ = file.Close()
+			_ = os.Remove(path)
+			return nil, err
+		}
+		return file, nil
+	}
+	if !errors.Is(err, os.ErrExist) {
+		return nil, shmOpenError(path, err)
+	}
+
+	file, err = os.OpenFile(path, os.O_RDWR|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, shmOpenError(path, err)
+	}
+	if err := checkShmFile(file, opts, enforce); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// shmOpenError maps the O_NOFOLLOW failure of a symlink swapped in after Lstat.
+func shmOpenError(path string, err error) error {
+	if errors.Is(err, syscall.ELOOP) {
+		return fmt.Errorf("%w: %s", ErrShmSymlink, path)
+	}
+	return err
+}
+
+// initShmFile applies the mode (bypassing the umask) and ownership of a new segment.
+func initShmFile(file *os.File, opts ShmOptions) error {
+	if err := file.Chmod(opts.mode()); err != nil {
+		return err
+	}
+	if opts.Owner == "" && opts.Group == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	var err error
+	if opts.Owner != "" {
+		if uid, err = lookupUID(opts.Owner); err != nil {
+			return err
+		}
+	}
+	if opts.Group != "" {
+		if gid, err = lookupGID(opts.Group); err != nil {
+			return err
+		}
+	}
+	if err := file.Chown(uid, gid); err != nil {
+		return fmt.Errorf("shm: set owner: %w", err)
+	}
+	return nil
+}
+
+// checkShmFile validates an existing segment before it is mapped.
+func checkShmFile(file *os.File, opts ShmOptions, enforce bool) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s", ErrShmNotRegular, file.Name())
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	owner := int(stat.Uid)
+	if !allowedUID(owner, opts) {
+		return fmt.Errorf("%w: %s belongs to uid %d", ErrShmForeignOwner, file.Name(), owner)
+	}
+	if enforce && owner == os.Geteuid() && info.Mode().Perm() != opts.mode() {
+		return file.Chmod(opts.mode())
+	}
+	return nil
+}
+
+// allowedUID reports whether a segment or memfd peer owned by uid may be trusted.
+func allowedUID(uid int, opts ShmOptions) bool {
+	if uid == os.Geteuid() {
+		return true
+	}
+	if opts.Owner == "" {
+		return false
+	}
+	owner, err := lookupUID(opts.Owner)
+	return err == nil && uid == owner
+}
+
+// -----------------------------------------------------------------------------
+
+func lookupUID(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return -1, fmt.Errorf("shm: owner: %w", err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+func lookupGID(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return -1, fmt.Errorf("shm: group: %w", err)
+	}
+	return strconv.Atoi(g.Gid)
+}
@@ -0,0 +1,251 @@
+package transports
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/edsrzf/mmap-go"
+)
+
+// SHM Crash Recovery
+//
+// Each side publishes its PID in the header and refreshes a liveness beat, even when
+// idle, and every listener reset bumps the segment generation. A client records the
+// generation it attached to:
+//
+//   - A restarted listener resets the rings under a new generation: the transports of
+//     the previous session fail with ErrShmReset instead of reading the new rings, and
+//     the client can reconnect.
+//   - A transport whose peer process is gone fails with ErrShmPeerDead; the listener
+//     then resets the segment on its next Accept, which lets a restarted client attach.
+//   - A listener or client refuses a segment held by another live process (ErrShmInUse).
+//
+// A peer is alive while its PID exists and its beat is recent: the PID of a dead
+// process may be given to another one, which does not beat. PIDs are tagged with
+// their PID namespace; one from another namespace cannot be probed, so only its beat
+// counts.
+
+var (
+	ErrShmInUse    = errors.New("shm: segment in use by another live process")
+	ErrShmPeerDead = errors.New("shm: peer process died")
+	ErrShmReset    = errors.New("shm: segment was reset by a new listener")
+)
+
+const (
+	// shmPeerCheckInterval throttles peer liveness checks while waiting on the rings.
+	shmPeerCheckInterval = 50 * time.Millisecond
+
+	// shmBeatInterval is the period of the liveness beat; a peer whose beat is older
+	// than shmBeatTimeout is dead whatever its PID.
+	shmBeatInterval = time.Second
+	shmBeatTimeout  = 15 * time.Second
+
+	// shmAttachTimeout bounds how long a client waits for the listener to release a
+	// dead client's session when no timeout is configured.
+	shmAttachTimeout = 5 * time.Second
+)
+
+// shmWord returns the header word at offset.
+func shmWord(m mmap.MMap, offset int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&m[offset]))
+}
+
+// pidNamespace tags the PID namespace of this process (0 = unknown).
+var pidNamespace = func() uint32 {
+	link, err := os.Readlink("/proc/self/ns/pid") // "pid:[4026531836]"
+	if err != nil {
+		return 0
+	}
+	var inode uint64
+	if _, err := fmt.Sscanf(link, "pid:[%d]", &inode); err != nil {
+		return 0
+	}
+	return uint32(inode)
+}()
+
+// ownerWord is the PID word published by this process: the PID in the low 32 bits,
+// the namespace tag in the high ones.
+func ownerWord() uint64 {
+	return uint64(pidNamespace)<<32 | uint64(os.Getpid())
+}
+
+// ownerPID returns the PID of a PID word.
+func ownerPID(word uint64) int {
+	return int(uint32(word))
+}
+
+// probeable reports whether the PID of word belongs to this PID namespace (or an unknown one).
+func probeable(word uint64) bool {
+	tag := uint32(word >> 32)
+	return tag == 0 || pidNamespace == 0 || tag == pidNamespace
+}
+
+// self reports whether a PID word is this process.
+func self(word uint64) bool {
+	return ownerPID(word) == os.Getpid() && probeable(word)
+}
+
+// alive reports whether the process of a PID word may still run (this one included).
+func alive(word uint64) bool {
+	pid := ownerPID(word)
+	if pid == 0 {
+		return false
+	}
+	return !probeable(word) || self(word) || processAlive(pid)
+}
+
+// liveOwner reports whether the PID stored at offset is another process still running.
+func liveOwner(m mmap.MMap, offset int) bool {
+	word := atomic.LoadUint64(shmWord(m, offset))
+	return alive(word) && !self(word)
+}
+
+// beating reports whether a liveness beat is recent.
+func beating(beat *uint64) bool {
+	return time.Now().UnixNano()-int64(atomic.LoadUint64(beat)) < int64(shmBeatTimeout)
+}
+
+// listening reports whether a running listener serves the segment.
+func listening(m mmap.MMap) bool {
+	status := atomic.LoadUint64(shmWord(m, OffsetServerStatus))
+	return (status == StatusListening || status == StatusConnected) &&
+		alive(atomic.LoadUint64(shmWord(m, OffsetServerPID))) && beating(shmWord(m, OffsetServerBeat))
+}
+
+// -----------------------------------------------------------------------------
+
+// shmBeat refreshes a liveness beat until halted.
+type shmBeat struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func startBeat(m mmap.MMap, offset int) *shmBeat {
+	b := &shmBeat{stop: make(chan struct{}), done: make(chan struct{})}
+	word := shmWord(m, offset)
+	atomic.StoreUint64(word, uint64(time.Now().UnixNano()))
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(shmBeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				atomic.StoreUint64(word, uint64(time.Now().UnixNano()))
+			}
+		}
+	}()
+	return b
+}
+
+// halt stops the beat and waits for it: the segment can then be unmapped.
+func (b *shmBeat) halt() {
+	b.once.Do(func() { close(b.stop) })
+	<-b.done
+}
+
+// -----------------------------------------------------------------------------
+
+// claimSegment checks that no other live listener holds the segment, then resets it.
+func claimSegment(m mmap.MMap) error {
+	status := atomic.LoadUint64(shmWord(m, OffsetServerStatus))
+	if (status == StatusListening || status == StatusConnected) && liveOwner(m, OffsetServerPID) && beating(shmWord(m, OffsetServerBeat)) {
+		return fmt.Errorf("%w: listener pid %d", ErrShmInUse, ownerPID(atomic.LoadUint64(shmWord(m, OffsetServerPID))))
+	}
+	resetSegment(m)
+	return nil
+}
+
+// resetSegment starts a new session: the generation is bumped first so that transports
+// of the previous session stop touching the rings, then the header is cleared.
+func resetSegment(m mmap.MMap) {
+	atomic.AddUint64(shmWord(m, OffsetGeneration), 1)
+
+	for _, offset := range []int{OffsetHeadA, OffsetTailA, OffsetHeadB, OffsetTailB,
+		OffsetServerCloseCode, OffsetClientCloseCode, OffsetClientPID, OffsetClientGeneration} {
+		atomic.StoreUint64(shmWord(m, offset), 0)
+	}
+	now := uint64(time.Now().UnixNano())
+	atomic.StoreUint64(shmWord(m, OffsetServerActivity), now)
+	atomic.StoreUint64(shmWord(m, OffsetClientActivity), now)
+	atomic.StoreUint64(shmWord(m, OffsetServerBeat), now)
+	atomic.StoreUint64(shmWord(m, OffsetServerPID), ownerWord())
+	atomic.StoreUint64(shmWord(m, OffsetClientStatus), StatusIdle)
+	atomic.StoreUint64(shmWord(m, OffsetServerStatus), StatusListening)
+}
+
+// attachClient takes the client slot of the segment for the current generation.
+// A slot left by a dead (or departed) client is only taken once a live listener
+// has reset it, so that the listener sees a clean session.
+func attachClient(m mmap.MMap, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = shmAttachTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	status := shmWord(m, OffsetClientStatus)
+
+	for {
+		current := atomic.LoadUint64(status)
+		switch {
+		case current == StatusConnected && alive(atomic.LoadUint64(shmWord(m, OffsetClientPID))) && beating(shmWord(m, OffsetClientBeat)):
+			return fmt.Errorf("%w: client pid %d", ErrShmInUse, ownerPID(atomic.LoadUint64(shmWord(m, OffsetClientPID))))
+		case current == StatusIdle || !listening(m):
+			// Free slot, or no listener to reset it (the next one will)
+		default:
+			// Previous client gone: wait for the listener's reset
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%w: the listener has not released the previous session", ErrShmInUse)
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		// The generation is read after the status: a reset publishes it first
+		generation := atomic.LoadUint64(shmWord(m, OffsetGeneration))
+		atomic.StoreUint64(shmWord(m, OffsetClientBeat), uint64(time.Now().UnixNano()))
+		atomic.StoreUint64(shmWord(m, OffsetClientPID), ownerWord())
+		atomic.StoreUint64(shmWord(m, OffsetClientGeneration), generation)
+		atomic.StoreUint64(shmWord(m, OffsetClientCloseCode), 0)
+		if atomic.CompareAndSwapUint64(status, current, StatusConnected) {
+			return nil
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// generationChanged reports whether a listener reset the segment since this
+// transport's session started.
+func (t *ShmTransport) generationChanged() bool {
+	return atomic.LoadUint64(t.generationWord) != t.generation
+}
+
+// sessionError reports why the session is over (segment reset, dead peer), if it is.
+// Peer liveness is only checked every shmPeerCheckInterval.
+func (t *ShmTransport) sessionError() error {
+	if t.generationChanged() {
+		return ErrShmReset
+	}
+	now := time.Now().UnixNano()
+	if now-t.lastPeerCheck.Load() < int64(shmPeerCheckInterval) {
+		return nil
+	}
+	t.lastPeerCheck.Store(now)
+
+	status := atomic.LoadUint64(t.peerStatus)
+	if status != StatusListening && status != StatusConnected {
+		return nil
+	}
+	if word := atomic.LoadUint64(t.peerPID); word != 0 && !(alive(word) && beating(t.peerBeat)) {
+		return ErrShmPeerDead
+	}
+	return nil
+}
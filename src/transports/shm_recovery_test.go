@@ -0,0 +1,242 @@
+package transports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// deadPID is above any pid_max: no process can have it.
+const deadPID = 1 << 30
+
+// crash simulates the death of the process owning t: no goodbye, and its PID is gone.
+func crash(t *ShmTransport, pidOffset int) {
+	t.closed.Store(true)
+	if t.beat != nil {
+		t.beat.halt()
+	}
+	atomic.StoreUint64(shmWord(t.MMap, pidOffset), deadPID)
+}
+
+// reuse simulates the death of the process owning a PID word, long enough ago that
+// another live process (our parent) was given its PID.
+func reuse(m []byte, pidOffset, beatOffset int) {
+	atomic.StoreUint64(shmWord(m, pidOffset), uint64(os.Getppid()))
+	atomic.StoreUint64(shmWord(m, beatOffset), uint64(time.Now().Add(-2*shmBeatTimeout).UnixNano()))
+}
+
+func TestShmRecovery(t *testing.T) {
+	exchange := func(t *testing.T, client, server interface {
+		Write([]byte) (int, error)
+		ReadMessage() ([]byte, error)
+	}) {
+		t.Helper()
+		if _, err := client.Write([]byte("fresh")); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := server.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != "fresh" {
+			t.Errorf("expected 'fresh', got %q", msg)
+		}
+	}
+
+	t.Run("ClientRestart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "client-restart.shm")
+		ln, err := ListenShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		first, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Some traffic in the first session
+		if _, err := first.Write([]byte("stale")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := server.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+		crash(first.(*ShmTransport), OffsetClientPID)
+
+		if _, err := server.ReadMessage(); !errors.Is(err, ErrShmPeerDead) {
+			t.Fatalf("expected ErrShmPeerDead, got %v", err)
+		}
+
+		accepted := make(chan *ShmTransport, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+			}
+			accepted <- conn.(*ShmTransport)
+		}()
+
+		second, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatalf("expected the restarted client to reattach, got %v", err)
+		}
+		defer func() { _ = second.Close() }()
+		exchange(t, second, <-accepted)
+	})
+
+	t.Run("ServerRestart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "server-restart.shm")
+		first, err := ListenShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := first.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		crash(server.(*ShmTransport), OffsetServerPID)
+		first.(*ShmListener).closed.Store(true) // Abandoned without goodbye
+		first.(*ShmListener).beat.halt()
+		defer func() { _ = first.(*ShmListener).mmap.Unmap() }()
+
+		restarted, err := ListenShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatalf("expected the segment of a dead listener to be reclaimed, got %v", err)
+		}
+		defer func() { _ = restarted.Close() }()
+
+		// The old session is told about the reset instead of reading the new rings
+		if _, err := client.ReadMessage(); !errors.Is(err, ErrShmReset) {
+			t.Fatalf("expected ErrShmReset, got %v", err)
+		}
+		if _, err := client.Write([]byte("late")); !errors.Is(err, ErrShmReset) {
+			t.Errorf("expected writes to fail with ErrShmReset, got %v", err)
+		}
+		_ = client.Close()
+
+		reattached, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = reattached.Close() }()
+		conn, err := restarted.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exchange(t, reattached, conn)
+	})
+
+	t.Run("InUse", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "in-use.shm")
+		ln, err := ListenShm(path, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		// Another live process (our parent) serves and uses the segment
+		m := ln.(*ShmListener).mmap
+		atomic.StoreUint64(shmWord(m, OffsetServerPID), uint64(os.Getppid()))
+		if _, err := ListenShm(path, time.Second); !errors.Is(err, ErrShmInUse) {
+			t.Errorf("expected ErrShmInUse for a second listener, got %v", err)
+		}
+
+		atomic.StoreUint64(shmWord(m, OffsetServerPID), uint64(os.Getpid()))
+		if _, err := ConnectShm(path, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		atomic.StoreUint64(shmWord(m, OffsetClientPID), uint64(os.Getppid()))
+		if _, err := ConnectShm(path, time.Second); !errors.Is(err, ErrShmInUse) {
+			t.Errorf("expected ErrShmInUse for a second client, got %v", err)
+		}
+	})
+	t.Run("ReusedPID", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "reused-pid.shm")
+		first, err := ListenShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The listener died long ago and a live process now has its PID
+		first.(*ShmListener).closed.Store(true)
+		first.(*ShmListener).beat.halt()
+		defer func() { _ = first.(*ShmListener).mmap.Unmap() }()
+		reuse(first.(*ShmListener).mmap, OffsetServerPID, OffsetServerBeat)
+
+		ln, err := ListenShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatalf("expected the segment of a dead listener to be reclaimed, got %v", err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		client, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Same for the client
+		client.(*ShmTransport).closed.Store(true)
+		client.(*ShmTransport).beat.halt()
+		reuse(client.(*ShmTransport).MMap, OffsetClientPID, OffsetClientBeat)
+		if _, err := server.ReadMessage(); !errors.Is(err, ErrShmPeerDead) {
+			t.Fatalf("expected ErrShmPeerDead, got %v", err)
+		}
+
+		accepted := make(chan *ShmTransport, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+			}
+			accepted <- conn.(*ShmTransport)
+		}()
+		second, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatalf("expected a new client to attach, got %v", err)
+		}
+		defer func() { _ = second.Close() }()
+		exchange(t, second, <-accepted)
+	})
+
+	t.Run("OtherNamespace", func(t *testing.T) {
+		if pidNamespace == 0 {
+			t.Skip("PID namespaces unknown on this platform")
+		}
+		path := filepath.Join(t.TempDir(), "other-namespace.shm")
+		ln, err := ListenShm(path, 0) // No idle timeout: the read deadline below applies
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+		client, err := ConnectShm(path, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = client.Close() }()
+		server, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A client PID that does not exist here, but from another namespace: its beat decides
+		atomic.StoreUint64(shmWord(client.(*ShmTransport).MMap, OffsetClientPID), uint64(pidNamespace+1)<<32|deadPID)
+		_ = server.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, err := server.ReadMessage(); !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("expected the beating client to be alive, got %v", err)
+		}
+	})
+}
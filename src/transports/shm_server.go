@@ -2,8 +2,10 @@ package transports
 
 import (
 	"errors"
+	"io"
 	"net"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -12,51 +14,76 @@ import (
 )
 
 // ShmListener implements interfaces.TransportListener for Shared Memory.
+// It serves one client at a time: Accept returns once a client attached, and the next
+// Accept waits for that session to end, resets the segment and waits for a new client.
 type ShmListener struct {
-	path        string
-	timeout     time.Duration
-	transport   *ShmTransport
-	acceptCount int32
+	path    string
+	timeout time.Duration
+	file    *os.File
+	mmap    mmap.MMap
+	handoff io.Closer // memfd descriptor handoff socket (nil for files)
+	beat    *shmBeat  // Liveness of the listener and its sessions
+
+	acceptMu sync.Mutex // One Accept at a time
+	mu       sync.Mutex
+	current  *ShmTransport // Session returned by the last Accept
+	closed   atomic.Bool
 }
 
 // -----------------------------------------------------------------------------
 
 // ListenShm creates (or opens) the SHM file and prepares it for a client connection.
 func ListenShm(path string, timeout time.Duration) (interfaces.TransportListener, error) {
-	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	return ListenShmWithOptions(path, timeout, ShmOptions{})
+}
+
+// ListenShmWithOptions is ListenShm with explicit placement and permissions.
+func ListenShmWithOptions(path string, timeout time.Duration, opts ShmOptions) (interfaces.TransportListener, error) {
+	var file *os.File
+	var handoff io.Closer
+	var err error
+	if opts.Memfd {
+		file, handoff, err = createMemfd(path, opts)
+	} else {
+		file, err = openShmFile(ResolveShmPath(path, opts.Dir), opts, true)
+	}
 	if err != nil {
 		return nil, err
 	}
+	closeAll := func() {
+		if handoff != nil {
+			_ = handoff.Close()
+		}
+		_ = file.Close()
+	}
 
 	// Ensure file is the correct size
 	if err := file.Truncate(int64(TotalSize)); err != nil {
-		_ = file.Close()
+		closeAll()
 		return nil, err
 	}
 
 	m, err := mmap.Map(file, mmap.RDWR, 0)
 	if err != nil {
-		_ = file.Close()
+		closeAll()
 		return nil, err
 	}
 
-	t := NewShmTransport(file, m, "server", timeout)
-
-	// Reset metadata for a clean start
-	atomic.StoreUint64(t.ProduceHead, 0)
-	atomic.StoreUint64(t.ProduceTail, 0)
-	atomic.StoreUint64(t.ConsumeHead, 0)
-	atomic.StoreUint64(t.ConsumeTail, 0)
-	atomic.StoreUint64(t.ServerStatus, StatusListening)
-	atomic.StoreUint64(t.ClientStatus, StatusIdle)
-	now := uint64(time.Now().UnixNano())
-	atomic.StoreUint64(t.MyActivity, now)
-	atomic.StoreUint64(t.PeerActivity, now)
+	// Refuse a segment served by another live listener, otherwise start a new
+	// generation: clients of a crashed listener see the reset and reattach
+	if err := claimSegment(m); err != nil {
+		_ = m.Unmap()
+		closeAll()
+		return nil, err
+	}
 
 	return &ShmListener{
-		path:      path,
-		timeout:   timeout,
-		transport: t,
+		path:    path,
+		timeout: timeout,
+		file:    file,
+		mmap:    m,
+		handoff: handoff,
+		beat:    startBeat(m, OffsetServerBeat),
 	}, nil
 }
 
@@ -65,35 +92,123 @@ func ListenShm(path string, timeout time.Duration) (interfaces.TransportListener
 // Accept waits for a client to attach to the SHM file.
 // Note: Current implementation is 1-to-1 (Point-to-Point).
 func (l *ShmListener) Accept() (interfaces.TransportConnection, error) {
-	if !atomic.CompareAndSwapInt32(&l.acceptCount, 0, 1) {
-		// In a real multi-tenant scenario, we would wait for a new file request.
-		// For now, we only support one client per SHM path.
-		return nil, errors.New("SHM listener only supports one concurrent connection")
+	l.acceptMu.Lock()
+	defer l.acceptMu.Unlock()
+
+	l.mu.Lock()
+	previous := l.current
+	l.mu.Unlock()
+	if previous != nil {
+		if err := l.poll(func() bool { return l.sessionEnded(previous) }); err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		if !l.closed.Load() {
+			retire(previous)
+			resetSegment(l.mmap)
+		}
+		l.mu.Unlock()
 	}
 
-	// Wait for client to set ClientStatus to Connected
-	// Using a relatively slow poll here as Accept is not in the hot path.
-	for atomic.LoadUint64(l.transport.ClientStatus) != StatusConnected {
-		if l.transport.closed.Load() {
-			return nil, errors.New("listener closed")
+	// Wait for a client to attach to the current generation
+	err := l.poll(func() bool {
+		return atomic.LoadUint64(shmWord(l.mmap, OffsetClientStatus)) == StatusConnected &&
+			atomic.LoadUint64(shmWord(l.mmap, OffsetClientGeneration)) == atomic.LoadUint64(shmWord(l.mmap, OffsetGeneration))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed.Load() {
+		return nil, errors.New("listener closed")
+	}
+
+	// Acknowledge connection
+	t := NewShmTransport(l.file, l.mmap, "server", l.timeout)
+	t.detach = func() error { return nil } // The listener owns the mapping
+	atomic.StoreUint64(t.myCloseCode, 0)
+	atomic.StoreUint64(t.ServerStatus, StatusConnected)
+	l.current = t
+	return t, nil
+}
+
+// poll waits until cond holds, evaluating it under the lock that guards the mapping.
+// Using a relatively slow poll here as Accept is not in the hot path.
+func (l *ShmListener) poll(cond func() bool) error {
+	for {
+		l.mu.Lock()
+		if l.closed.Load() {
+			l.mu.Unlock()
+			return errors.New("listener closed")
+		}
+		ok := cond()
+		l.mu.Unlock()
+		if ok {
+			return nil
 		}
 		time.Sleep(10 * time.Millisecond)
 	}
+}
 
-	// Acknowledge connection
-	atomic.StoreUint64(l.transport.ServerStatus, StatusConnected)
+// sessionEnded reports whether the previous session is over: closed here, the client
+// said goodbye and everything it sent was read, or the client is gone.
+func (l *ShmListener) sessionEnded(t *ShmTransport) bool {
+	if t.closed.Load() || t.sessionError() != nil {
+		return true
+	}
+	status := atomic.LoadUint64(t.peerStatus)
+	drained := atomic.LoadUint64(t.ConsumeHead) == atomic.LoadUint64(t.ConsumeTail)
+	return (status == StatusClosing || status == StatusClosed) && drained
+}
 
-	return l.transport, nil
+// retire stops a finished session and waits for its in-flight reads and writes,
+// which must not touch the rings once they are reset.
+func retire(t *ShmTransport) {
+	t.closed.Store(true)
+	t.forgetRings()
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
 }
 
 // -----------------------------------------------------------------------------
 
+// Close ends the current session (goodbye to the client) and releases the segment.
 func (l *ShmListener) Close() error {
-	return l.transport.Close()
+	if l.closed.Swap(true) {
+		return nil
+	}
+	if l.handoff != nil {
+		_ = l.handoff.Close()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	if l.current != nil {
+		err = l.current.Close()
+		retire(l.current)
+	} else {
+		// Not serving anyone: let another listener take the segment
+		atomic.CompareAndSwapUint64(shmWord(l.mmap, OffsetServerStatus), StatusListening, StatusIdle)
+	}
+
+	l.beat.halt()
+	if unmapErr := l.mmap.Unmap(); unmapErr != nil && err == nil {
+		err = unmapErr
+	}
+	if closeErr := l.file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
 }
 
 // -----------------------------------------------------------------------------
 
 func (l *ShmListener) Addr() net.Addr {
-	return l.transport.LocalAddr()
+	return ShmAddr{}
 }
@@ -0,0 +1,227 @@
+package transports
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// Zero-Copy SHM
+//
+// ReserveWrite hands the caller a slice inside the produce ring, published by
+// CommitWrite; PeekMessage returns a slice inside the consume ring, valid until
+// ReleaseMessage. Payloads thus cross processes without being copied.
+//
+// A reserved payload never wraps around the ring end: when it would, the writer
+// first publishes a padding frame covering the rest of the ring. A padding frame has
+// the top bit of its length set, the remaining bits giving the bytes to skip after its
+// header. Frames written by Write still wrap, so PeekMessage falls back to a copy for
+// the (rare) frame that straddles the ring end.
+//
+// Padding frames are only understood by peers of this version or later: use the
+// zero-copy writes only when both sides are up to date.
+
+const (
+	framePaddingBit = 1 << 31
+
+	// MaxZeroCopySize bounds ReserveWrite so that a payload plus its padding always fits.
+	MaxZeroCopySize = BufferDataSize/2 - 8
+)
+
+var (
+	ErrShmNoReservation = errors.New("shm: no pending write reservation")
+	ErrShmPeekPending   = errors.New("shm: peeked message not released")
+	ErrShmNoPeek        = errors.New("shm: no peeked message to release")
+)
+
+// ZeroCopyConnection is implemented by transports exposing their ring to the caller.
+type ZeroCopyConnection interface {
+	ReserveWrite(n int) ([]byte, error)
+	CommitWrite(n int) error
+	AbortWrite()
+	PeekMessage() ([]byte, error)
+	ReleaseMessage() error
+}
+
+// shmReservation is the frame being filled between ReserveWrite and CommitWrite.
+type shmReservation struct {
+	frame uint64 // Position of the frame header (after the padding, if any)
+	size  int    // Bytes reserved for the payload
+}
+
+// -----------------------------------------------------------------------------
+
+// ReserveWrite waits for room for an n-byte payload and returns it as a slice of the
+// produce ring. Fill it, then publish with CommitWrite (or drop it with AbortWrite).
+// Other writes block until then.
+func (t *ShmTransport) ReserveWrite(n int) ([]byte, error) {
+	if n < 0 || n > MaxZeroCopySize {
+		return nil, io.ErrShortBuffer
+	}
+
+	t.writeMu.Lock()
+	if err := t.writable(); err != nil {
+		t.writeMu.Unlock()
+		return nil, err
+	}
+	headerSize := uint64(t.version.headerSize())
+	tail := atomic.LoadUint64(t.ProduceTail)
+	idx := tail % BufferDataSize
+
+	// Pad to the ring start when the payload would wrap (a wrapping header is fine)
+	var pad uint64
+	if idx+headerSize < BufferDataSize && idx+headerSize+uint64(n) > BufferDataSize {
+		pad = BufferDataSize - idx
+	}
+	need := pad + headerSize + uint64(n)
+
+	t.refreshWriteDeadline()
+	if deadlinePassed(&t.writeDeadline) {
+		t.writeMu.Unlock()
+		return nil, os.ErrDeadlineExceeded
+	}
+	for {
+		if err := t.writable(); err != nil {
+			t.writeMu.Unlock()
+			return nil, err
+		}
+		head := atomic.LoadUint64(t.ProduceHead)
+		if tail-head+need <= BufferDataSize {
+			break
+		}
+		if err := t.waitWritable(); err != nil {
+			t.writeMu.Unlock()
+			return nil, err
+		}
+	}
+
+	if pad > 0 {
+		header := encodeHeader(t.version.get(), interfaces.FrameData, 0, int(pad-headerSize))
+		header[0] |= framePaddingBit >> 24
+		t.writeToRing(tail, header)
+	}
+	r := &shmReservation{frame: tail + pad, size: n}
+	t.reservation.Store(r)
+
+	start := (r.frame + headerSize) % BufferDataSize
+	return t.ProduceData[start : start+uint64(n) : start+uint64(n)], nil
+}
+
+// CommitWrite publishes the first n bytes of the reserved slice as one message.
+func (t *ShmTransport) CommitWrite(n int) error {
+	r := t.reservation.Swap(nil)
+	if r == nil {
+		return ErrShmNoReservation
+	}
+	defer t.endReservation()
+
+	if n < 0 || n > r.size {
+		return io.ErrShortBuffer
+	}
+	if err := t.writable(); err != nil {
+		return err
+	}
+	version := t.version.get()
+	header := encodeHeader(version, interfaces.FrameData, 0, n)
+	t.writeToRing(r.frame, header)
+
+	atomic.StoreUint64(t.ProduceTail, r.frame+uint64(len(header))+uint64(n))
+	t.refreshWriteDeadline()
+	t.reportRings()
+	if t.tap.active() {
+		start := (r.frame + uint64(len(header))) % BufferDataSize
+		t.tap.observe(interfaces.FrameOutbound, version, interfaces.FrameData, 0, nil, t.ProduceData[start:start+uint64(n)])
+	}
+	return nil
+}
+
+// AbortWrite drops the pending reservation without publishing anything.
+func (t *ShmTransport) AbortWrite() {
+	if t.reservation.Swap(nil) != nil {
+		t.endReservation()
+	}
+}
+
+// endReservation lets other writers in, releasing the segment first if Close left
+// it to the reservation (see unmap).
+func (t *ShmTransport) endReservation() {
+	if t.releasePending.CompareAndSwap(true, false) {
+		_ = t.releaseSegment()
+	}
+	t.writeMu.Unlock()
+}
+
+// writable reports why nothing may be written anymore, if so.
+func (t *ShmTransport) writable() error {
+	if t.closed.Load() {
+		return io.ErrClosedPipe
+	}
+	if t.generationChanged() {
+		return ErrShmReset // Never write into the rings of a new session
+	}
+	return nil
+}
+
+// waitWritable backs off while the ring is full, failing on a dead session or deadline.
+func (t *ShmTransport) waitWritable() error {
+	if err := t.sessionError(); err != nil {
+		return err
+	}
+	if deadlinePassed(&t.writeDeadline) {
+		return os.ErrDeadlineExceeded
+	}
+	time.Sleep(1 * time.Microsecond)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// PeekMessage returns the next message as a slice of the consume ring, without
+// consuming it. The slice is valid until ReleaseMessage; reads fail until then.
+// It is a copy when the frame wraps around the ring end (written by Write).
+func (t *ShmTransport) PeekMessage() ([]byte, error) {
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+
+	if t.peeked != 0 {
+		return nil, ErrShmPeekPending
+	}
+	head, headerSize, length, tc, err := t.nextDataFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg []byte
+	start := (head + headerSize) % BufferDataSize
+	if start+length <= BufferDataSize {
+		msg = t.ConsumeData[start : start+length : start+length]
+	} else {
+		msg = make([]byte, length)
+		t.readFromRing(head+headerSize, msg)
+	}
+	t.peeked = headerSize + length
+	t.observeData(tc, msg)
+	return msg, nil
+}
+
+// ReleaseMessage consumes the message returned by PeekMessage, handing its space
+// back to the producer.
+func (t *ShmTransport) ReleaseMessage() error {
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+
+	if t.peeked == 0 {
+		return ErrShmNoPeek
+	}
+	if t.generationChanged() {
+		t.peeked = 0
+		return ErrShmReset
+	}
+	t.release(t.peeked)
+	t.peeked = 0
+	return nil
+}
@@ -0,0 +1,184 @@
+package transports
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// shmPair connects a client and server transport on a fresh segment.
+func shmPair(t *testing.T) (client, server *ShmTransport) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "zerocopy.shm")
+	ln, err := ListenShm(path, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	conn, err := ConnectShm(path, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn.(*ShmTransport), accepted.(*ShmTransport)
+}
+
+// inRing reports whether p aliases ring.
+func inRing(p, ring []byte) bool {
+	start := uintptr(unsafe.Pointer(&ring[0]))
+	addr := uintptr(unsafe.Pointer(&p[0]))
+	return addr >= start && addr+uintptr(len(p)) <= start+uintptr(len(ring))
+}
+
+// moveRing places the (empty) client-to-server ring at pos, as if pos bytes had been exchanged.
+func moveRing(client *ShmTransport, pos uint64) {
+	atomic.StoreUint64(client.ProduceHead, pos)
+	atomic.StoreUint64(client.ProduceTail, pos)
+}
+
+func TestShmZeroCopy(t *testing.T) {
+	send := func(t *testing.T, client *ShmTransport, payload []byte) {
+		t.Helper()
+		buf, err := client.ReserveWrite(len(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !inRing(buf, client.ProduceData) {
+			t.Fatal("reserved slice is not inside the ring")
+		}
+		copy(buf, payload)
+		if err := client.CommitWrite(len(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive := func(t *testing.T, server *ShmTransport, want []byte, zeroCopy bool) {
+		t.Helper()
+		msg, err := server.PeekMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(msg, want) {
+			t.Fatalf("payload mismatch (%d bytes, want %d)", len(msg), len(want))
+		}
+		if inRing(msg, server.ConsumeData) != zeroCopy {
+			t.Errorf("expected zero-copy=%v", zeroCopy)
+		}
+		if err := server.ReleaseMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		client, server := shmPair(t)
+		send(t, client, []byte("zero-copy"))
+		receive(t, server, []byte("zero-copy"), true)
+
+		// Regular reads and writes interleave with the zero-copy ones
+		if _, err := client.Write([]byte("copied")); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := server.ReadMessage()
+		if err != nil || string(msg) != "copied" {
+			t.Fatalf("expected 'copied', got %q (%v)", msg, err)
+		}
+	})
+
+	t.Run("PadsAtRingEnd", func(t *testing.T) {
+		client, server := shmPair(t)
+		moveRing(client, 3*BufferDataSize-1000)
+
+		payload := bytes.Repeat([]byte{0xAB}, 4096)
+		send(t, client, payload)
+		receive(t, server, payload, true)
+		send(t, client, []byte("after"))
+		receive(t, server, []byte("after"), true)
+
+		if head := atomic.LoadUint64(server.ConsumeHead); head != atomic.LoadUint64(server.ConsumeTail) {
+			t.Errorf("ring not drained: head %d, tail %d", head, atomic.LoadUint64(server.ConsumeTail))
+		}
+	})
+
+	t.Run("WrappedWriteIsCopied", func(t *testing.T) {
+		client, server := shmPair(t)
+		moveRing(client, BufferDataSize-1000)
+
+		payload := bytes.Repeat([]byte{0xCD}, 4096)
+		if _, err := client.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		receive(t, server, payload, false)
+	})
+
+	t.Run("Misuse", func(t *testing.T) {
+		client, server := shmPair(t)
+		if err := client.CommitWrite(1); !errors.Is(err, ErrShmNoReservation) {
+			t.Errorf("expected ErrShmNoReservation, got %v", err)
+		}
+		if err := server.ReleaseMessage(); !errors.Is(err, ErrShmNoPeek) {
+			t.Errorf("expected ErrShmNoPeek, got %v", err)
+		}
+		if _, err := client.ReserveWrite(MaxZeroCopySize + 1); err == nil {
+			t.Error("expected oversized reservation to fail")
+		}
+
+		// An aborted reservation publishes nothing
+		if _, err := client.ReserveWrite(16); err != nil {
+			t.Fatal(err)
+		}
+		client.AbortWrite()
+		send(t, client, []byte("kept"))
+
+		if msg, err := server.PeekMessage(); err != nil || string(msg) != "kept" {
+			t.Fatalf("expected 'kept', got %q (%v)", msg, err)
+		}
+		if _, err := server.ReadMessage(); !errors.Is(err, ErrShmPeekPending) {
+			t.Errorf("expected ErrShmPeekPending, got %v", err)
+		}
+		if _, err := server.PeekMessage(); !errors.Is(err, ErrShmPeekPending) {
+			t.Errorf("expected ErrShmPeekPending, got %v", err)
+		}
+		if err := server.ReleaseMessage(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("CloseWhileReserved", func(t *testing.T) {
+		client, _ := shmPair(t)
+		buf, err := client.ReserveWrite(16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		closed := make(chan error, 1)
+		go func() { closed <- client.Close() }()
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Close waited for the reservation")
+		}
+
+		// The segment stays mapped until the reservation ends
+		copy(buf, "late")
+		if err := client.CommitWrite(len(buf)); !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("expected io.ErrClosedPipe, got %v", err)
+		}
+		if client.releasePending.Load() {
+			t.Error("expected CommitWrite to release the segment")
+		}
+		if _, err := client.ReserveWrite(16); !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("expected io.ErrClosedPipe, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package transports
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TLS Material
+//
+// Certificates, keys and CA bundles come from (first match wins):
+//   - a certificate provider callback, asked at every handshake;
+//   - PEM bytes (e.g. fetched from a vault);
+//   - files, optionally polled for changes so that a long-running listener picks up
+//     rotated certificates and CA bundles without being restarted.
+//
+// A caller-supplied *tls.Config is used as the base of the final configuration.
+
+// TLSOptions gathers the TLS settings of ListenTLSWithOptions and ConnectTLSWithOptions.
+type TLSOptions struct {
+	// Base configuration, cloned (nil = empty config).
+	Config *tls.Config
+
+	// Certificate (server) or client certificate (mTLS client).
+	CertProvider func() (*tls.Certificate, error)
+	CertPEM      []byte
+	KeyPEM       []byte
+	CertFile     string
+	KeyFile      string
+
+	// CA bundle: verifies clients (server) or the server (client).
+	CAPEM  []byte
+	CAFile string
+
+	// ReloadInterval polls CertFile, KeyFile and CAFile for changes (listeners only, 0 = never).
+	ReloadInterval time.Duration
+
+	// Protocol policy (0/nil = base config or Go defaults)
+	MinVersion       uint16
+	MaxVersion       uint16
+	CipherSuites     []uint16 // TLS 1.0-1.2 only: Go does not make TLS 1.3 suites configurable
+	CurvePreferences []tls.CurveID
+	NextProtos       []string // ALPN, in preference order
+
+	// Server only: client certificate policy (NoClientCert = require and verify when a
+	// CA bundle is set, none otherwise).
+	ClientAuth tls.ClientAuthType
+
+	// Client only
+	ServerName         string
+	InsecureSkipVerify bool
+	PinnedSPKI         []string // base64 SHA-256 of the SubjectPublicKeyInfo (see SPKIPin)
+}
+
+// -----------------------------------------------------------------------------
+
+// hasCert reports whether a certificate source other than the base config is set.
+func (o *TLSOptions) hasCert() bool {
+	return o.CertProvider != nil || len(o.CertPEM) > 0 || (o.CertFile != "" && o.KeyFile != "")
+}
+
+// hasCA reports whether a CA bundle is set.
+func (o *TLSOptions) hasCA() bool {
+	return len(o.CAPEM) > 0 || o.CAFile != ""
+}
+
+// loadCert reads the certificate from PEM bytes or files.
+func (o *TLSOptions) loadCert() (*tls.Certificate, error) {
+	var cert tls.Certificate
+	var err error
+	if len(o.CertPEM) > 0 {
+		cert, err = tls.X509KeyPair(o.CertPEM, o.KeyPEM)
+	} else {
+		cert, err = tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key pair: %w", err)
+	}
+	return &cert, nil
+}
+
+// loadCA reads the CA bundle from PEM bytes or a file.
+func (o *TLSOptions) loadCA() (*x509.CertPool, error) {
+	bundle := o.CAPEM
+	if len(bundle) == 0 {
+		var err error
+		if bundle, err = os.ReadFile(o.CAFile); err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no certificates found in CA bundle")
+	}
+	return pool, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// serverTLSConfig builds a listener configuration. The returned stop function ends the
+// file watcher, if any.
+func serverTLSConfig(o TLSOptions) (*tls.Config, func(), error) {
+	config := &tls.Config{}
+	if o.Config != nil {
+		config = o.Config.Clone()
+	}
+	if !o.hasCert() && len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return nil, nil, errors.New("TLS listener requires a certificate (CertFile/KeyFile, CertPEM/KeyPEM, TLSConfig or CertProvider)")
+	}
+	if err := o.applyPolicy(config); err != nil {
+		return nil, nil, err
+	}
+	config.ClientAuth = o.clientAuth(config.ClientAuth)
+
+	var r *tlsReloader
+	if o.ReloadInterval > 0 && ((o.CertProvider == nil && len(o.CertPEM) == 0 && o.CertFile != "") ||
+		(len(o.CAPEM) == 0 && o.CAFile != "")) {
+		var err error
+		if r, err = newTLSReloader(o); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 1. Certificate
+	switch {
+	case o.CertProvider != nil:
+		provider := o.CertProvider
+		config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return provider() }
+	case r != nil && r.certFile != "":
+		config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return r.cert.Load(), nil }
+	case o.hasCert():
+		cert, err := o.loadCert()
+		if err != nil {
+			return nil, nil, err
+		}
+		config.Certificates = []tls.Certificate{*cert}
+	}
+
+	// 2. CA (mTLS)
+	switch {
+	case r != nil && r.caFile != "":
+		// The pool is read per handshake so that a rotated bundle applies to new clients
+		base := config.Clone()
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := base.Clone()
+			c.ClientCAs = r.ca.Load()
+			return c, nil
+		}
+	case o.hasCA():
+		pool, err := o.loadCA()
+		if err != nil {
+			return nil, nil, err
+		}
+		config.ClientCAs = pool
+	}
+
+	if r == nil {
+		return config, func() {}, nil
+	}
+	return config, r.stop, nil
+}
+
+// clientTLSConfig builds a dialer configuration.
+func clientTLSConfig(o TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{}
+	if o.Config != nil {
+		config = o.Config.Clone()
+	}
+	if o.ServerName != "" {
+		config.ServerName = o.ServerName
+	}
+	if o.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	if err := o.applyPolicy(config); err != nil {
+		return nil, err
+	}
+	if err := o.applyPins(config); err != nil {
+		return nil, err
+	}
+
+	// 1. Client Certificate (mTLS)
+	switch {
+	case o.CertProvider != nil:
+		provider := o.CertProvider
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return provider() }
+	case o.hasCert():
+		cert, err := o.loadCert()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{*cert}
+	}
+
+	// 2. CA (custom roots)
+	if o.hasCA() {
+		pool, err := o.loadCA()
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// tlsReloader polls certificate and CA files and swaps the loaded material when
+// their modification time changes. A failed reload (e.g. the key pair caught mid-
+// rotation) keeps the previous material and is retried at the next tick.
+type tlsReloader struct {
+	opts     TLSOptions
+	certFile string
+	caFile   string
+	cert     atomic.Pointer[tls.Certificate]
+	ca       atomic.Pointer[x509.CertPool]
+	stamps   map[string]time.Time
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newTLSReloader(o TLSOptions) (*tlsReloader, error) {
+	r := &tlsReloader{opts: o, stamps: make(map[string]time.Time), done: make(chan struct{})}
+	if o.CertProvider == nil && len(o.CertPEM) == 0 {
+		r.certFile = o.CertFile
+	}
+	if len(o.CAPEM) == 0 {
+		r.caFile = o.CAFile
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	go r.watch(o.ReloadInterval)
+	return r, nil
+}
+
+// reload loads the files whose modification time changed.
+func (r *tlsReloader) reload() error {
+	if r.certFile != "" {
+		if stamps, changed := r.changed(r.certFile, r.opts.KeyFile); changed {
+			cert, err := r.opts.loadCert()
+			if err != nil {
+				return err
+			}
+			r.cert.Store(cert)
+			r.commit(stamps)
+		}
+	}
+	if r.caFile != "" {
+		if stamps, changed := r.changed(r.caFile); changed {
+			pool, err := r.opts.loadCA()
+			if err != nil {
+				return err
+			}
+			r.ca.Store(pool)
+			r.commit(stamps)
+		}
+	}
+	return nil
+}
+
+// changed stats the files (before they are read, so that a write racing the load is
+// seen at the next tick) and reports whether any of them changed.
+func (r *tlsReloader) changed(paths ...string) (map[string]time.Time, bool) {
+	stamps := make(map[string]time.Time, len(paths))
+	changed := false
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, true // Let the load report it
+		}
+		stamps[path] = info.ModTime()
+		if !info.ModTime().Equal(r.stamps[path]) {
+			changed = true
+		}
+	}
+	return stamps, changed
+}
+
+func (r *tlsReloader) commit(stamps map[string]time.Time) {
+	for path, t := range stamps {
+		r.stamps[path] = t
+	}
+}
+
+func (r *tlsReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = r.reload()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *tlsReloader) stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
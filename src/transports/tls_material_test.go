@@ -0,0 +1,200 @@
+package transports
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// testCA issues PEM certificates valid for 127.0.0.1.
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pem    []byte
+	serial int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), serial: 1}
+}
+
+func (ca *testCA) issue(t *testing.T, cn string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// serveTLS accepts connections and completes their TLS handshake until the listener closes.
+func serveTLS(ln interfaces.TransportListener) {
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = conn.ReadMessage()
+				_ = conn.Close()
+			}()
+		}
+	}()
+}
+
+// serverCN dials the listener and returns the CN of the certificate it presented.
+func serverCN(t *testing.T, addr string, opts TLSOptions) string {
+	t.Helper()
+	conn, err := ConnectTLSWithOptions(addr, 2*time.Second, opts)
+	if err != nil {
+		t.Fatalf("ConnectTLSWithOptions failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	state, ok := TLSStateOf(conn)
+	if !ok || len(state.PeerCertificates) == 0 {
+		t.Fatal("expected a TLS session with a peer certificate")
+	}
+	return state.PeerCertificates[0].Subject.CommonName
+}
+
+func TestTLSMaterial(t *testing.T) {
+	ca := newTestCA(t)
+
+	t.Run("PEM", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, "in-memory")
+		clientCert, clientKey := ca.issue(t, "client")
+		ln, err := ListenTLSWithOptions("127.0.0.1:0", 2*time.Second, TLSOptions{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: ca.pem})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+		serveTLS(ln)
+
+		// mTLS both ways with in-memory material only
+		opts := TLSOptions{CertPEM: clientCert, KeyPEM: clientKey, CAPEM: ca.pem}
+		if cn := serverCN(t, ln.Addr().String(), opts); cn != "in-memory" {
+			t.Errorf("expected CN 'in-memory', got %q", cn)
+		}
+	})
+
+	t.Run("ProviderAndBaseConfig", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, "provided")
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var calls atomic.Int32
+		provider := func() (*tls.Certificate, error) {
+			calls.Add(1)
+			return &cert, nil
+		}
+		ln, err := ListenTLSWithOptions("127.0.0.1:0", 2*time.Second, TLSOptions{CertProvider: provider})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+		serveTLS(ln)
+
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(ca.pem)
+		opts := TLSOptions{Config: &tls.Config{RootCAs: roots}}
+		for i := 0; i < 2; i++ {
+			if cn := serverCN(t, ln.Addr().String(), opts); cn != "provided" {
+				t.Errorf("expected CN 'provided', got %q", cn)
+			}
+		}
+		if calls.Load() != 2 {
+			t.Errorf("expected the provider to be asked at each handshake, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("HotReload", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+		write := func(cn string, stamp time.Time) {
+			certPEM, keyPEM := ca.issue(t, cn)
+			for path, data := range map[string][]byte{certFile: certPEM, keyFile: keyPEM, caFile: ca.pem} {
+				if err := os.WriteFile(path, data, 0600); err != nil {
+					t.Fatal(err)
+				}
+				_ = os.Chtimes(path, stamp, stamp)
+			}
+		}
+		write("v1", time.Now().Add(-time.Hour))
+
+		ln, err := ListenTLSWithOptions("127.0.0.1:0", 2*time.Second, TLSOptions{
+			CertFile: certFile, KeyFile: keyFile, CAFile: caFile, ReloadInterval: 10 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+		serveTLS(ln)
+
+		clientCert, clientKey := ca.issue(t, "client")
+		opts := TLSOptions{CertPEM: clientCert, KeyPEM: clientKey, CAFile: caFile}
+		if cn := serverCN(t, ln.Addr().String(), opts); cn != "v1" {
+			t.Fatalf("expected CN 'v1', got %q", cn)
+		}
+
+		// Rotate the files: new connections get the new certificate, same listener
+		write("v2", time.Now())
+		deadline := time.Now().Add(2 * time.Second)
+		for serverCN(t, ln.Addr().String(), opts) != "v2" {
+			if time.Now().After(deadline) {
+				t.Fatal("rotated certificate was not picked up")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("MissingCertificate", func(t *testing.T) {
+		if _, err := ListenTLSWithOptions("127.0.0.1:0", time.Second, TLSOptions{}); err == nil {
+			t.Error("expected an error for a listener without certificate")
+		}
+	})
+}
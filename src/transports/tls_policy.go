@@ -0,0 +1,116 @@
+package transports
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPinMismatch is returned by the TLS handshake when the server certificate (or its
+// verified chain) matches no pinned public key.
+var ErrPinMismatch = errors.New("server certificate does not match any pinned public key")
+
+// -----------------------------------------------------------------------------
+
+// applyPolicy sets the protocol policy (versions, suites, curves, ALPN) on a config.
+// Unset fields keep the base config values (Go defaults otherwise).
+func (o *TLSOptions) applyPolicy(config *tls.Config) error {
+	if o.MinVersion != 0 {
+		config.MinVersion = o.MinVersion
+	}
+	if o.MaxVersion != 0 {
+		config.MaxVersion = o.MaxVersion
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return fmt.Errorf("TLS min version %s is above max version %s",
+			tls.VersionName(config.MinVersion), tls.VersionName(config.MaxVersion))
+	}
+	if len(o.CipherSuites) > 0 {
+		config.CipherSuites = o.CipherSuites
+	}
+	if len(o.CurvePreferences) > 0 {
+		config.CurvePreferences = o.CurvePreferences
+	}
+	if len(o.NextProtos) > 0 {
+		config.NextProtos = o.NextProtos
+	}
+	return nil
+}
+
+// clientAuth returns the server's client certificate policy: the configured mode, or
+// RequireAndVerifyClientCert when a CA bundle is set.
+func (o *TLSOptions) clientAuth(base tls.ClientAuthType) tls.ClientAuthType {
+	switch {
+	case o.ClientAuth != tls.NoClientCert:
+		return o.ClientAuth
+	case o.hasCA():
+		return tls.RequireAndVerifyClientCert
+	}
+	return base
+}
+
+// -----------------------------------------------------------------------------
+
+// SPKIPin returns the pin of a certificate: base64(SHA-256(SubjectPublicKeyInfo)),
+// the format expected by TLSOptions.PinnedSPKI.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// applyPins makes the client handshake fail unless the server proves it holds a pinned
+// public key. Pinning also applies with InsecureSkipVerify, which allows trusting a
+// self-signed server by its key alone: only the leaf counts then, since any certificate
+// can be appended to a chain. Otherwise the pin may be anywhere in a verified chain.
+func (o *TLSOptions) applyPins(config *tls.Config) error {
+	if len(o.PinnedSPKI) == 0 {
+		return nil
+	}
+
+	pins := make([][]byte, 0, len(o.PinnedSPKI))
+	for _, pin := range o.PinnedSPKI {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(raw) != sha256.Size {
+			return fmt.Errorf("invalid SPKI pin %q: expected base64 SHA-256", pin)
+		}
+		pins = append(pins, raw)
+	}
+	pinned := func(cert *x509.Certificate) bool {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if subtle.ConstantTimeCompare(sum[:], pin) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	insecure, next := config.InsecureSkipVerify, config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if next != nil {
+			if err := next(cs); err != nil {
+				return err
+			}
+		}
+		if insecure {
+			if len(cs.PeerCertificates) > 0 && pinned(cs.PeerCertificates[0]) {
+				return nil
+			}
+			return ErrPinMismatch
+		}
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				if pinned(cert) {
+					return nil
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+	return nil
+}
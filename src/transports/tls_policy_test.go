@@ -0,0 +1,137 @@
+package transports
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTLSPolicy(t *testing.T) {
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "server")
+	block, _ := pem.Decode(certPEM)
+	leaf, _ := x509.ParseCertificate(block.Bytes)
+
+	listen := func(t *testing.T, opts TLSOptions) string {
+		opts.CertPEM, opts.KeyPEM = certPEM, keyPEM
+		ln, err := ListenTLSWithOptions("127.0.0.1:0", 2*time.Second, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = ln.Close() })
+		serveTLS(ln)
+		return ln.Addr().String()
+	}
+	dial := func(addr string, opts TLSOptions) (tls.ConnectionState, error) {
+		conn, err := ConnectTLSWithOptions(addr, 2*time.Second, opts)
+		if err != nil {
+			return tls.ConnectionState{}, err
+		}
+		defer func() { _ = conn.Close() }()
+		state, _ := TLSStateOf(conn)
+		return state, nil
+	}
+
+	t.Run("Versions", func(t *testing.T) {
+		addr := listen(t, TLSOptions{MinVersion: tls.VersionTLS13})
+		if _, err := dial(addr, TLSOptions{CAPEM: ca.pem, MaxVersion: tls.VersionTLS12}); err == nil {
+			t.Error("expected a TLS 1.2 client to be refused by a TLS 1.3-only server")
+		}
+
+		addr = listen(t, TLSOptions{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}})
+		state, err := dial(addr, TLSOptions{CAPEM: ca.pem})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Version != tls.VersionTLS12 || state.CipherSuite != tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 {
+			t.Errorf("expected TLS 1.2 with the configured suite, got %s / %s",
+				tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+		}
+
+		if _, err := ConnectTLSWithOptions(addr, time.Second, TLSOptions{MinVersion: tls.VersionTLS13, MaxVersion: tls.VersionTLS12}); err == nil {
+			t.Error("expected an error for min version above max version")
+		}
+	})
+
+	t.Run("ALPN", func(t *testing.T) {
+		addr := listen(t, TLSOptions{NextProtos: []string{"safe-socket/2", "safe-socket/1"}})
+		state, err := dial(addr, TLSOptions{CAPEM: ca.pem, NextProtos: []string{"safe-socket/1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.NegotiatedProtocol != "safe-socket/1" {
+			t.Errorf("expected ALPN 'safe-socket/1', got %q", state.NegotiatedProtocol)
+		}
+	})
+
+	t.Run("Pinning", func(t *testing.T) {
+		addr := listen(t, TLSOptions{})
+
+		// The right key is trusted on its own, even without a CA
+		if _, err := dial(addr, TLSOptions{InsecureSkipVerify: true, PinnedSPKI: []string{"sha256/" + SPKIPin(leaf)}}); err != nil {
+			t.Errorf("expected the pinned server to be accepted, got %v", err)
+		}
+
+		// A verified chain may carry the pin anywhere, but a valid chain with other keys is refused
+		if _, err := dial(addr, TLSOptions{CAPEM: ca.pem, PinnedSPKI: []string{SPKIPin(ca.cert)}}); err != nil {
+			t.Errorf("expected the pinned CA to be accepted, got %v", err)
+		}
+		if _, err := dial(addr, TLSOptions{CAPEM: ca.pem, PinnedSPKI: []string{SPKIPin(newTestCA(t).cert)}}); !errors.Is(err, ErrPinMismatch) {
+			t.Errorf("expected ErrPinMismatch, got %v", err)
+		}
+
+		// Without verification only the leaf counts: appending the pinned certificate to
+		// another key's chain proves nothing
+		otherCert, otherKey := newTestCA(t).issue(t, "attacker")
+		ln, err := ListenTLSWithOptions("127.0.0.1:0", 2*time.Second, TLSOptions{CertPEM: append(otherCert, certPEM...), KeyPEM: otherKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+		serveTLS(ln)
+		if _, err := dial(ln.Addr().String(), TLSOptions{InsecureSkipVerify: true, PinnedSPKI: []string{SPKIPin(leaf)}}); !errors.Is(err, ErrPinMismatch) {
+			t.Errorf("expected ErrPinMismatch for a pinned certificate appended to the chain, got %v", err)
+		}
+
+		if _, err := dial(addr, TLSOptions{PinnedSPKI: []string{"not-a-pin"}}); err == nil {
+			t.Error("expected an error for a malformed pin")
+		}
+	})
+
+	t.Run("ClientAuthRequest", func(t *testing.T) {
+		opts := TLSOptions{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: ca.pem, ClientAuth: tls.VerifyClientCertIfGiven}
+		ln, err := ListenTLSWithOptions("127.0.0.1:0", 2*time.Second, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		received := make(chan error, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				received <- err
+				return
+			}
+			defer func() { _ = conn.Close() }()
+			_, err = conn.ReadMessage()
+			received <- err
+		}()
+
+		// A client without certificate is let in when certificates are only requested
+		conn, err := ConnectTLSWithOptions(ln.Addr().String(), 2*time.Second, TLSOptions{CAPEM: ca.pem})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = conn.Close() }()
+		if _, err := conn.Write([]byte("anonymous")); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-received; err != nil {
+			t.Errorf("expected the anonymous client to be served, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package transports
+
+import (
+	"crypto/tls"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// tlsStater is implemented by transports able to report their TLS session.
+type tlsStater interface {
+	TLSConnectionState() (tls.ConnectionState, bool)
+}
+
+// -----------------------------------------------------------------------------
+
+// TLSConnectionState returns the TLS session state, or false on plain TCP.
+func (s *FramedTCPSocket) TLSConnectionState() (tls.ConnectionState, bool) {
+	tc, ok := s.Conn.(*tls.Conn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+	return tc.ConnectionState(), true
+}
+
+// -----------------------------------------------------------------------------
+
+// TLSStateOf walks a wrapper chain (via Unwrap) down to the TLS transport and returns
+// its session state. It returns false when the chain is not TLS.
+func TLSStateOf(conn interfaces.TransportConnection) (tls.ConnectionState, bool) {
+	ts, ok := unwrapTo[tlsStater](conn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+	return ts.TLSConnectionState()
+}
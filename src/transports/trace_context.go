@@ -0,0 +1,79 @@
+package transports
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+// Trace Context Propagation
+//
+// Once both peers agreed on it during the Hello handshake (HelloMsg.traceContext), a
+// data frame may carry the trace context of its sender, flagged in the v1 header:
+//
+// [0-3] : Length - trace context included
+// [4]   : FrameData
+// [5]   : Flags - FlagTraceContext
+// [6]   : Trace context length (1-255)
+// [7-.] : Trace context, then the payload
+//
+// The format of the context belongs to the tracer (e.g. a W3C traceparent). Read and
+// ReadMessage strip it; ReadMessageTraced returns it along with the payload.
+const (
+	FlagTraceContext uint8 = 0x01
+
+	// MaxTraceContextSize is the largest trace context a frame can carry.
+	MaxTraceContextSize = 255
+)
+
+// ErrBadTraceContext is returned for oversized contexts and malformed traced frames.
+var ErrBadTraceContext = errors.New("malformed trace context")
+
+// traceState records whether trace contexts were negotiated on a connection.
+type traceState struct {
+	enabled atomic.Bool
+}
+
+func (t *traceState) enable(version uint8) error {
+	if version == FrameVersionLegacy {
+		return ErrControlUnsupported
+	}
+	t.enabled.Store(true)
+	return nil
+}
+
+// prefix returns the header flags and the body prefix carrying tc (none when trace
+// contexts are off or tc is empty).
+func (t *traceState) prefix(tc []byte) (uint8, []byte, error) {
+	if len(tc) == 0 || !t.enabled.Load() {
+		return 0, nil, nil
+	}
+	if len(tc) > MaxTraceContextSize {
+		return 0, nil, ErrBadTraceContext
+	}
+	return FlagTraceContext, append([]byte{byte(len(tc))}, tc...), nil
+}
+
+// traced reports whether a frame with these flags starts with a trace context.
+func (t *traceState) traced(flags uint8) bool {
+	return flags&FlagTraceContext != 0 && t.enabled.Load()
+}
+
+// splitTraceContext separates the trace context from the payload of a traced body.
+func splitTraceContext(body []byte) (tc, payload []byte, err error) {
+	if len(body) == 0 || int(body[0])+1 > len(body) {
+		return nil, nil, ErrBadTraceContext
+	}
+	n := int(body[0]) + 1
+	return body[1:n:n], body[n:], nil
+}
+
+// -----------------------------------------------------------------------------
+
+// TraceContextOf walks a wrapper chain (via Unwrap) down to the transport able to carry
+// trace contexts. It returns nil when the chain has none (e.g. UDP).
+func TraceContextOf(conn interfaces.TransportConnection) interfaces.TraceContextConnection {
+	tc, _ := unwrapTo[interfaces.TraceContextConnection](conn)
+	return tc
+}
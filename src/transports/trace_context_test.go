@@ -0,0 +1,116 @@
+package transports
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+)
+
+type tracedConnection interface {
+	interfaces.TransportConnection
+	interfaces.ControlConnection
+	interfaces.TraceContextConnection
+}
+
+// exerciseTraceContext checks that trace contexts only travel once enabled, and that
+// every read path strips them from the payload.
+func exerciseTraceContext(t *testing.T, writer, reader tracedConnection) {
+	tc := []byte("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// 1. Not negotiated yet: the context is dropped
+	_ = writer.SetFrameVersion(FrameVersionTyped)
+	_ = reader.SetFrameVersion(FrameVersionTyped)
+	if _, err := writer.WriteTraced([]byte("untraced"), tc); err != nil {
+		t.Fatalf("WriteTraced failed: %v", err)
+	}
+	msg, got, err := reader.ReadMessageTraced()
+	if err != nil || string(msg) != "untraced" || got != nil {
+		t.Fatalf("expected 'untraced' without context, got %q %q (%v)", msg, got, err)
+	}
+
+	// 2. Negotiated: ReadMessageTraced returns the context, Read and ReadMessage strip it
+	if err := writer.EnableTraceContext(); err != nil {
+		t.Fatalf("EnableTraceContext failed: %v", err)
+	}
+	if err := reader.EnableTraceContext(); err != nil {
+		t.Fatalf("EnableTraceContext failed: %v", err)
+	}
+	for _, payload := range []string{"traced", "read-message", "read"} {
+		if _, err := writer.WriteTraced([]byte(payload), tc); err != nil {
+			t.Fatalf("WriteTraced failed: %v", err)
+		}
+	}
+	msg, got, err = reader.ReadMessageTraced()
+	if err != nil || string(msg) != "traced" || string(got) != string(tc) {
+		t.Errorf("expected 'traced' with context, got %q %q (%v)", msg, got, err)
+	}
+	if msg, err := reader.ReadMessage(); err != nil || string(msg) != "read-message" {
+		t.Errorf("expected 'read-message', got %q (%v)", msg, err)
+	}
+	buf := make([]byte, 64)
+	if n, err := reader.Read(buf); err != nil || string(buf[:n]) != "read" {
+		t.Errorf("expected 'read', got %q (%v)", buf[:n], err)
+	}
+
+	// 3. Plain writes still carry no context
+	if _, err := writer.Write([]byte("plain")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if msg, got, err := reader.ReadMessageTraced(); err != nil || string(msg) != "plain" || got != nil {
+		t.Errorf("expected 'plain' without context, got %q %q (%v)", msg, got, err)
+	}
+
+	// 4. Oversized contexts are refused
+	if _, err := writer.WriteTraced([]byte("x"), make([]byte, MaxTraceContextSize+1)); !errors.Is(err, ErrBadTraceContext) {
+		t.Errorf("expected ErrBadTraceContext, got %v", err)
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	t.Run("FramedTCP", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, _ := ln.Accept()
+			accepted <- conn
+		}()
+		clientConn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewFramedTCPSocket(clientConn, 2*time.Second)
+		server := NewFramedTCPSocket(<-accepted, 2*time.Second)
+		defer func() { _ = client.Close() }()
+		defer func() { _ = server.Close() }()
+
+		exerciseTraceContext(t, client, server)
+	})
+
+	t.Run("SHM", func(t *testing.T) {
+		client, server := shmPair(t)
+		exerciseTraceContext(t, client, server)
+
+		// Peeked messages come without their context
+		_, _ = client.WriteTraced([]byte("peeked"), []byte("ctx"))
+		msg, err := server.PeekMessage()
+		if err != nil || string(msg) != "peeked" {
+			t.Errorf("expected 'peeked', got %q (%v)", msg, err)
+		}
+		_ = server.ReleaseMessage()
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		client, _ := shmPair(t)
+		if err := client.EnableTraceContext(); !errors.Is(err, ErrControlUnsupported) {
+			t.Errorf("expected ErrControlUnsupported on frame version 0, got %v", err)
+		}
+	})
+}
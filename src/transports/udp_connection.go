@@ -1,12 +1,13 @@
 package transports
 
 import (
+	"io"
 	"net"
 	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
 )
 
-// UdpSocket implements interfaces.TransportConnection over UDP.
-// Note: UDP is unreliable and unordered.
 // UdpSocket implements interfaces.TransportConnection over UDP.
 // Note: UDP is unreliable and unordered.
 type UdpSocket struct {
@@ -16,6 +17,8 @@ type UdpSocket struct {
 	// Server-Side: "Transient" socket fields
 	TransientRemoteAddr *net.UDPAddr // If set, Write() uses WriteToUDP
 	RecvBuf             []byte       // If set, Read() returns this buffer first (one-shot)
+
+	openedAt time.Time
 }
 
 // -----------------------------------------------------------------------------
@@ -24,6 +27,7 @@ func NewUdpSocket(conn *net.UDPConn, timeout time.Duration) *UdpSocket {
 	s := &UdpSocket{
 		Conn:        conn,
 		idleTimeout: timeout,
+		openedAt:    time.Now(),
 	}
 	s.refreshReadDeadline()
 	s.refreshWriteDeadline()
@@ -37,6 +41,7 @@ func NewTransientUdpSocket(conn *net.UDPConn, addr *net.UDPAddr, data []byte, ti
 		TransientRemoteAddr: addr,
 		RecvBuf:             data,
 		idleTimeout:         timeout,
+		openedAt:            time.Now(),
 	}
 	s.refreshReadDeadline()
 	s.refreshWriteDeadline()
@@ -88,57 +93,63 @@ func (s *UdpSocket) Write(p []byte) (n int, err error) {
 
 // -----------------------------------------------------------------------------
 
-// Read reads a datagram.
-// Note: If 'p' is smaller than the incoming datagram, the excess data is discarded by the OS.
+// Read reads a datagram. Empty datagrams are heartbeats and are skipped.
+// If 'p' is smaller than the datagram, Read returns io.ErrShortBuffer and keeps the
+// datagram for the next Read.
 func (s *UdpSocket) Read(p []byte) (n int, err error) {
-	s.refreshReadDeadline()
-	// If we have a pre-read buffer (Transient Server Socket), return it immediately
-	if s.RecvBuf != nil {
-		n := copy(p, s.RecvBuf)
-		s.RecvBuf = nil // consumed
-		return n, nil
+	// Pending datagram: the first packet of a Transient Server Socket, or one that did not fit
+	data := s.RecvBuf
+	if len(data) == 0 {
+		if data, err = s.readDatagram(); err != nil {
+			return 0, err
+		}
 	}
-
-	// OPTIMIZATION: Removed SetReadDeadline logic from hot path.
-	return s.Conn.Read(p)
+	if len(p) < len(data) {
+		s.RecvBuf = data
+		return 0, io.ErrShortBuffer
+	}
+	s.RecvBuf = nil // consumed
+	return copy(p, data), nil
 }
 
 // -----------------------------------------------------------------------------
 
-// ReadMessage for UDP allocates a buffer large enough for a standard UDP packet (64KB max),
-// reads, and returns the sliced data.
+// ReadMessage for UDP returns the next datagram, skipping heartbeats.
 func (s *UdpSocket) ReadMessage() ([]byte, error) {
-	s.refreshReadDeadline()
 	// If we have a pre-read buffer (Transient Server Socket), return it immediately
-	if s.RecvBuf != nil {
-		result := make([]byte, len(s.RecvBuf))
-		copy(result, s.RecvBuf)
+	if len(s.RecvBuf) > 0 {
+		result := s.RecvBuf
 		s.RecvBuf = nil // consumed
 		return result, nil
 	}
+	return s.readDatagram()
+}
 
-	// OPTIMIZATION: Removed SetReadDeadline logic from hot path.
-
+// readDatagram reads the next non-empty datagram into a buffer of its own.
+func (s *UdpSocket) readDatagram() ([]byte, error) {
 	// Max UDP packet size is technically ~65535.
-	// We allocate a temp buffer.
 	tmp := make([]byte, 65535)
-
-	n, remoteAddr, err := s.Conn.ReadFromUDP(tmp)
-	if err != nil {
-		return nil, err
+	for {
+		s.refreshReadDeadline()
+		n, remoteAddr, err := s.Conn.ReadFromUDP(tmp)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue // Heartbeat: the read deadline was refreshed
+		}
+
+		// On an unconnected (server) socket, replies go to the last sender.
+		// Note: Thread safety issue here if sharing socket, but UDP socket per-packet model usually implies single thread or copy.
+		if s.Conn.RemoteAddr() == nil {
+			s.TransientRemoteAddr = remoteAddr
+		}
+
+		// Return a copy of exactly n bytes: 64KB arrays are not kept alive per message
+		result := make([]byte, n)
+		copy(result, tmp[:n])
+		return result, nil
 	}
-
-	// For Transient logic (server side reply), we store the remote addr
-	// Note: Thread safety issue here if sharing socket, but UDP socket per-packet model usually implies single thread or copy.
-	// But actually, UdpSocket struct is updated with TransientRemoteAddr.
-	s.TransientRemoteAddr = remoteAddr
-
-	// Return a copy of exactly n bytes
-	// Or return slice? Slice keeps underlying array alive. Copy is safer for memory if array is huge.
-	// Given 64KB is small, we can copy to fit.
-	result := make([]byte, n)
-	copy(result, tmp[:n])
-	return result, nil
 }
 
 // -----------------------------------------------------------------------------
@@ -184,3 +195,17 @@ func (s *UdpSocket) RemoteAddr() net.Addr {
 	}
 	return s.Conn.RemoteAddr()
 }
+
+// -----------------------------------------------------------------------------
+
+// ConnInfo describes the transport layer ("udp").
+func (s *UdpSocket) ConnInfo() interfaces.ConnInfo {
+	return interfaces.ConnInfo{
+		Layers:      []string{"udp"},
+		Transport:   interfaces.TransportUDP,
+		LocalAddr:   s.LocalAddr(),
+		RemoteAddr:  s.RemoteAddr(),
+		IdleTimeout: s.idleTimeout,
+		OpenedAt:    s.openedAt,
+	}
+}
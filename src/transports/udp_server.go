@@ -46,20 +46,25 @@ func (l *UdpListener) Accept() (interfaces.TransportConnection, error) {
 	// 4KB should be enough for control frames / hello messages.
 	buf := make([]byte, 4096)
 
-	if l.Timeout > 0 {
-		_ = l.Conn.SetReadDeadline(time.Now().Add(l.Timeout))
+	for {
+		if l.Timeout > 0 {
+			_ = l.Conn.SetReadDeadline(time.Now().Add(l.Timeout))
+		}
+
+		// ReadFromUDP to get data AND sender address
+		n, remoteAddr, err := l.Conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue // Heartbeat: not a message, opens nothing
+		}
+
+		// Create a Transient Socket wrapping this specific packet interaction
+		// The next Read() on this socket will return 'buf[:n]'.
+		// The next Write() on this socket will send to 'remoteAddr'.
+		return NewTransientUdpSocket(l.Conn, remoteAddr, buf[:n], l.Timeout), nil
 	}
-
-	// ReadFromUDP to get data AND sender address
-	n, remoteAddr, err := l.Conn.ReadFromUDP(buf)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create a Transient Socket wrapping this specific packet interaction
-	// The next Read() on this socket will return 'buf[:n]'.
-	// The next Write() on this socket will send to 'remoteAddr'.
-	return NewTransientUdpSocket(l.Conn, remoteAddr, buf[:n], l.Timeout), nil
 }
 
 // -----------------------------------------------------------------------------
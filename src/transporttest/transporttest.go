@@ -0,0 +1,419 @@
+// Package transporttest checks that a transport behaves like the built-in ones, in the
+// spirit of golang.org/x/net/nettest: give TestConn a function making connected pairs
+// and it runs the conformance suite against them.
+//
+//	func TestConformance(t *testing.T) {
+//		transporttest.TestConn(t, func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+//			... // c1 dialed, c2 accepted, no idle timeout
+//		}, transporttest.Options{})
+//	}
+//
+// The suite checks:
+//   - Framing: every Write is read back as one message, in order, both ways, also under concurrent writers.
+//   - Heartbeats: an empty Write is a heartbeat, never surfacing in Read or ReadMessage.
+//   - Short buffers: Read into a buffer smaller than the message fails with io.ErrShortBuffer and leaves the message for the next Read.
+//   - Idle timeout: every read waits up to the idle timeout for traffic, heartbeats included, then fails with a timeout; 0 disables it.
+//   - Deadlines: past, future and zero deadlines behave like net.Conn ones, with errors matching os.ErrDeadlineExceeded and net.Error.Timeout; a timed-out write sends nothing.
+//   - Close: Close unblocks a pending read and later calls fail instead of blocking, and closing twice is harmless.
+//   - EOF: once the peer closed, the messages it wrote are still read, then reads fail with io.EOF or transports.ErrPeerClosed (stream transports).
+package transporttest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+)
+
+// MakePair returns a connected pair of connections: c1 dialed, c2 accepted (it must
+// already have consumed anything the transport needed to accept c1). Both start
+// without idle timeout. stop releases what the pair used; it is called once the
+// connections were closed, or not.
+type MakePair func() (c1, c2 interfaces.TransportConnection, stop func(), err error)
+
+// Options describes what the transport under test does not guarantee.
+type Options struct {
+	// Datagram transports (UDP) cannot tell their peer closed: EOF checks are skipped.
+	Datagram bool
+
+	// MaxMessageSize is the largest message the transport carries (0 = 1 MB).
+	MaxMessageSize int
+}
+
+// blockTimeout bounds every operation of the suite: a transport that blocks for longer
+// fails instead of hanging the test binary.
+const blockTimeout = 5 * time.Second
+
+// -----------------------------------------------------------------------------
+
+// TestConn runs the conformance suite against the pairs made by mp.
+func TestConn(t *testing.T, mp MakePair, opts Options) {
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 1 << 20
+	}
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, c1, c2 interfaces.TransportConnection, opts Options)
+	}{
+		{"Framing", testFraming},
+		{"ConcurrentWrites", testConcurrentWrites},
+		{"Heartbeats", testHeartbeats},
+		{"ShortBuffer", testShortBuffer},
+		{"IdleTimeout", testIdleTimeout},
+		{"Deadlines", testDeadlines},
+		{"Close", testClose},
+		{"PeerClose", testPeerClose},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "PeerClose" && opts.Datagram {
+				t.Skip("datagram transports cannot tell their peer closed")
+			}
+			c1, c2, stop, err := mp()
+			if err != nil {
+				t.Fatalf("unable to make a pair: %v", err)
+			}
+			defer stop()
+			defer func() {
+				_ = c1.Close()
+				_ = c2.Close()
+			}()
+			tt.fn(t, c1, c2, opts)
+		})
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func testFraming(t *testing.T, c1, c2 interfaces.TransportConnection, opts Options) {
+	var msgs [][]byte
+	for i, size := range []int{1, 10, 1000, opts.MaxMessageSize} {
+		msgs = append(msgs, pattern(i, size))
+	}
+
+	// Both ways: the accepted end answers, then the dialing end writes again
+	for _, dir := range []struct {
+		name    string
+		w, r    interfaces.TransportConnection
+		useRead bool
+	}{
+		{"c1->c2", c1, c2, false},
+		{"c2->c1", c2, c1, true},
+		{"c1->c2 again", c1, c2, true},
+	} {
+		errc := make(chan error, 1)
+		go func() {
+			for _, m := range msgs {
+				if n, err := dir.w.Write(m); err != nil || n != len(m) {
+					errc <- fmt.Errorf("Write(%d bytes) = %d, %v", len(m), n, err)
+					return
+				}
+			}
+			errc <- nil
+		}()
+		buf := make([]byte, opts.MaxMessageSize)
+		for i, want := range msgs {
+			var got []byte
+			var err error
+			if dir.useRead {
+				var n int
+				n, err = readTimely(t, dir.r, buf)
+				got = buf[:n]
+			} else {
+				got, err = readMessageTimely(t, dir.r)
+			}
+			if err != nil {
+				t.Fatalf("%s: message %d: %v", dir.name, i, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("%s: message %d: expected %d bytes as written, got %d", dir.name, i, len(want), len(got))
+			}
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("%s: %v", dir.name, err)
+		}
+	}
+}
+
+func testConcurrentWrites(t *testing.T, c1, c2 interfaces.TransportConnection, opts Options) {
+	const writers, perWriter = 4, 25
+	size := min(4096, opts.MaxMessageSize)
+
+	var wg sync.WaitGroup
+	errc := make(chan error, writers)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				if _, err := c1.Write(pattern(w*perWriter+i, size)); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}()
+	}
+
+	seen := map[byte]bool{}
+	for range writers * perWriter {
+		msg, err := readMessageTimely(t, c2)
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if len(msg) != size || !bytes.Equal(msg, pattern(int(msg[0]), size)) {
+			t.Fatalf("messages of concurrent writers interleaved (%d bytes)", len(msg))
+		}
+		seen[msg[0]] = true
+	}
+	wg.Wait()
+	close(errc)
+	if err := <-errc; err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if len(seen) != writers*perWriter {
+		t.Errorf("expected %d distinct messages, got %d", writers*perWriter, len(seen))
+	}
+}
+
+func testHeartbeats(t *testing.T, c1, c2 interfaces.TransportConnection, _ Options) {
+	for _, empty := range [][]byte{nil, {}} {
+		if n, err := c1.Write(empty); n != 0 || err != nil {
+			t.Fatalf("empty Write = %d, %v", n, err)
+		}
+	}
+	write(t, c1, "after heartbeats")
+	expectMessage(t, c2, "after heartbeats")
+
+	write(t, c1, "")
+	write(t, c1, "read")
+	buf := make([]byte, 64)
+	if n, err := readTimely(t, c2, buf); err != nil || string(buf[:n]) != "read" {
+		t.Fatalf("Read: expected 'read', got %q (%v)", buf[:n], err)
+	}
+}
+
+func testShortBuffer(t *testing.T, c1, c2 interfaces.TransportConnection, _ Options) {
+	write(t, c1, "hello, world")
+	if n, err := readTimely(t, c2, make([]byte, 4)); n != 0 || !errors.Is(err, io.ErrShortBuffer) {
+		t.Fatalf("Read into a short buffer = %d, %v, expected io.ErrShortBuffer", n, err)
+	}
+	buf := make([]byte, 64)
+	if n, err := readTimely(t, c2, buf); err != nil || string(buf[:n]) != "hello, world" {
+		t.Fatalf("the message must survive a short Read, got %q (%v)", buf[:n], err)
+	}
+}
+
+func testIdleTimeout(t *testing.T, c1, c2 interfaces.TransportConnection, _ Options) {
+	const idle = 150 * time.Millisecond
+	if err := c2.SetIdleTimeout(idle); err != nil {
+		t.Fatalf("SetIdleTimeout: %v", err)
+	}
+
+	// 1. Every read waits for the idle timeout, even after a quiet period
+	time.Sleep(2 * idle)
+	writeAfter(c1, idle/3, "late")
+	expectMessage(t, c2, "late")
+
+	// 2. Data and heartbeats refresh it
+	go func() {
+		for range 6 {
+			time.Sleep(idle / 3)
+			_, _ = c1.Write(nil)
+		}
+		_, _ = c1.Write([]byte("kept alive"))
+	}()
+	expectMessage(t, c2, "kept alive")
+
+	// 3. Silence expires it, without breaking the connection
+	start := time.Now()
+	if _, err := readMessageTimely(t, c2); !isTimeout(err) {
+		t.Fatalf("expected an idle timeout, got %v", err)
+	}
+	if d := time.Since(start); d < idle/2 {
+		t.Errorf("idle timeout after %v, expected about %v", d, idle)
+	}
+
+	// 4. 0 disables it
+	if err := c2.SetIdleTimeout(0); err != nil {
+		t.Fatalf("SetIdleTimeout: %v", err)
+	}
+	writeAfter(c1, 2*idle, "no timeout")
+	expectMessage(t, c2, "no timeout")
+}
+
+func testDeadlines(t *testing.T, c1, c2 interfaces.TransportConnection, _ Options) {
+	// 1. A past read deadline fails reads at once
+	_ = c2.SetReadDeadline(time.Now().Add(-time.Second))
+	if _, err := readMessageTimely(t, c2); !isTimeout(err) {
+		t.Fatalf("past read deadline: expected a timeout, got %v", err)
+	}
+
+	// 2. A future one fails a pending read once passed
+	start := time.Now()
+	_ = c2.SetReadDeadline(start.Add(100 * time.Millisecond))
+	if _, err := readMessageTimely(t, c2); !isTimeout(err) {
+		t.Fatalf("read deadline: expected a timeout, got %v", err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("read deadline of 100ms expired after %v", d)
+	}
+
+	// 3. The zero time clears it
+	_ = c2.SetReadDeadline(time.Time{})
+	writeAfter(c1, 150*time.Millisecond, "cleared")
+	expectMessage(t, c2, "cleared")
+
+	// 4. SetDeadline sets both
+	_ = c2.SetDeadline(time.Now().Add(-time.Second))
+	if _, err := readMessageTimely(t, c2); !isTimeout(err) {
+		t.Errorf("past deadline: expected a read timeout, got %v", err)
+	}
+	if _, err := c2.Write([]byte("never")); !isTimeout(err) {
+		t.Errorf("past deadline: expected a write timeout, got %v", err)
+	}
+	_ = c2.SetDeadline(time.Time{})
+	write(t, c1, "both cleared")
+	expectMessage(t, c2, "both cleared")
+
+	// 5. A past write deadline fails writes without writing anything. As with net.Conn,
+	// the connection may not write anymore afterwards (TLS), but must not have sent
+	// part of the message.
+	_ = c1.SetWriteDeadline(time.Now().Add(-time.Second))
+	if _, err := c1.Write([]byte("never")); !isTimeout(err) {
+		t.Fatalf("past write deadline: expected a timeout, got %v", err)
+	}
+	_ = c1.SetWriteDeadline(time.Time{})
+	if _, err := c1.Write([]byte("written")); err == nil {
+		expectMessage(t, c2, "written")
+	}
+}
+
+func testClose(t *testing.T, _, c2 interfaces.TransportConnection, _ Options) {
+	// 1. Close unblocks a pending read
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c2.ReadMessage()
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := c2.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("a read pending at Close returned no error")
+		}
+	case <-time.After(blockTimeout):
+		t.Fatal("Close did not unblock the pending read")
+	}
+
+	// 2. Later calls fail, closing again is harmless
+	_ = c2.Close()
+	if _, err := c2.Write([]byte("closed")); err == nil {
+		t.Error("Write after Close returned no error")
+	}
+	if _, err := readMessageTimely(t, c2); err == nil {
+		t.Error("ReadMessage after Close returned no error")
+	}
+}
+
+func testPeerClose(t *testing.T, c1, c2 interfaces.TransportConnection, _ Options) {
+	write(t, c1, "last words")
+	if err := c1.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	expectMessage(t, c2, "last words")
+	for range 2 {
+		if _, err := readMessageTimely(t, c2); !errors.Is(err, io.EOF) && !errors.Is(err, transports.ErrPeerClosed) {
+			t.Fatalf("after the peer closed: expected io.EOF or ErrPeerClosed, got %v", err)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// pattern returns a message of size bytes identified by its first byte.
+func pattern(id, size int) []byte {
+	msg := make([]byte, size)
+	for i := range msg {
+		msg[i] = byte(id + i*31)
+	}
+	return msg
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout() && errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+func write(t *testing.T, c interfaces.TransportConnection, msg string) {
+	t.Helper()
+	if _, err := c.Write([]byte(msg)); err != nil {
+		t.Fatalf("Write(%q): %v", msg, err)
+	}
+}
+
+func writeAfter(c interfaces.TransportConnection, d time.Duration, msg string) {
+	go func() {
+		time.Sleep(d)
+		_, _ = c.Write([]byte(msg))
+	}()
+}
+
+func expectMessage(t *testing.T, c interfaces.TransportConnection, want string) {
+	t.Helper()
+	if got, err := readMessageTimely(t, c); err != nil || string(got) != want {
+		t.Fatalf("expected %q, got %q (%v)", want, got, err)
+	}
+}
+
+// readMessageTimely is ReadMessage, failing the test when it blocks for too long.
+func readMessageTimely(t *testing.T, c interfaces.TransportConnection) ([]byte, error) {
+	t.Helper()
+	type result struct {
+		msg []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		msg, err := c.ReadMessage()
+		done <- result{msg, err}
+	}()
+	select {
+	case r := <-done:
+		return r.msg, r.err
+	case <-time.After(blockTimeout):
+		t.Fatalf("ReadMessage blocked for %v", blockTimeout)
+		return nil, nil
+	}
+}
+
+// readTimely is Read, failing the test when it blocks for too long.
+func readTimely(t *testing.T, c interfaces.TransportConnection, p []byte) (int, error) {
+	t.Helper()
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := c.Read(p)
+		done <- result{n, err}
+	}()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-time.After(blockTimeout):
+		t.Fatalf("Read blocked for %v", blockTimeout)
+		return 0, nil
+	}
+}
@@ -0,0 +1,189 @@
+package transporttest_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bastien-Antigravity/safe-socket/src/interfaces"
+	"github.com/Bastien-Antigravity/safe-socket/src/transports"
+	"github.com/Bastien-Antigravity/safe-socket/src/transporttest"
+)
+
+var memNames atomic.Uint64
+
+// pair connects a client to a listener and accepts it.
+func pair(ln interfaces.TransportListener, dial func() (interfaces.TransportConnection, error)) (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+	stop = func() { _ = ln.Close() }
+	type result struct {
+		conn interfaces.TransportConnection
+		err  error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		accepted <- result{conn, err}
+	}()
+	if c1, err = dial(); err != nil {
+		stop()
+		return nil, nil, nil, err
+	}
+	r := <-accepted
+	if r.err != nil {
+		_ = c1.Close()
+		stop()
+		return nil, nil, nil, r.err
+	}
+	return c1, r.conn, stop, nil
+}
+
+// v1 switches both ends of a pair to frame version 1 (no checksums).
+func v1(mp transporttest.MakePair) transporttest.MakePair {
+	return func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+		if c1, c2, stop, err = mp(); err != nil {
+			return
+		}
+		transports.ControlOf(c1).SetFrameVersion(1)
+		transports.ControlOf(c2).SetFrameVersion(1)
+		return
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func TestFramedTCP(t *testing.T) {
+	mp := func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+		ln, err := transports.Listen("127.0.0.1:0", 0)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return pair(ln, func() (interfaces.TransportConnection, error) {
+			return transports.Connect(ln.Addr().String(), 0)
+		})
+	}
+	t.Run("v2", func(t *testing.T) { transporttest.TestConn(t, mp, transporttest.Options{}) })
+	t.Run("v1", func(t *testing.T) { transporttest.TestConn(t, v1(mp), transporttest.Options{}) })
+}
+
+func TestTLS(t *testing.T) {
+	certPEM, keyPEM := selfSignedCert(t)
+	mp := func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+		ln, err := transports.ListenTLSWithOptions("127.0.0.1:0", 0, transports.TLSOptions{CertPEM: certPEM, KeyPEM: keyPEM})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// The client's handshake needs the accepted end to answer it
+		dialed := make(chan interfaces.TransportConnection, 1)
+		dialErr := make(chan error, 1)
+		go func() {
+			conn, err := transports.ConnectTLSWithOptions(ln.Addr().String(), 0, transports.TLSOptions{
+				Config: &tls.Config{InsecureSkipVerify: true},
+			})
+			dialErr <- err
+			dialed <- conn
+		}()
+		if c2, err = ln.Accept(); err != nil {
+			_ = ln.Close()
+			return nil, nil, nil, err
+		}
+		if err = c2.(*transports.FramedTCPSocket).Conn.(*tls.Conn).Handshake(); err != nil {
+			_ = ln.Close()
+			return nil, nil, nil, err
+		}
+		if err = <-dialErr; err != nil {
+			_ = ln.Close()
+			return nil, nil, nil, err
+		}
+		return <-dialed, c2, func() { _ = ln.Close() }, nil
+	}
+	transporttest.TestConn(t, mp, transporttest.Options{})
+}
+
+func TestShm(t *testing.T) {
+	mp := func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+		path := filepath.Join(t.TempDir(), "conformance.shm")
+		ln, err := transports.ListenShm(path, 0)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return pair(ln, func() (interfaces.TransportConnection, error) {
+			return transports.ConnectShm(path, 0)
+		})
+	}
+	t.Run("v2", func(t *testing.T) { transporttest.TestConn(t, mp, transporttest.Options{}) })
+	t.Run("v1", func(t *testing.T) { transporttest.TestConn(t, v1(mp), transporttest.Options{}) })
+}
+
+func TestMem(t *testing.T) {
+	mp := func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+		name := fmt.Sprintf("conformance-%d", memNames.Add(1))
+		ln, err := transports.ListenMem(name, 0)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return pair(ln, func() (interfaces.TransportConnection, error) {
+			return transports.ConnectMem(name, 0)
+		})
+	}
+	transporttest.TestConn(t, mp, transporttest.Options{})
+}
+
+func TestUDP(t *testing.T) {
+	mp := func() (c1, c2 interfaces.TransportConnection, stop func(), err error) {
+		ln, err := transports.ListenUDP("127.0.0.1:0", 0)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// The listener accepts a peer on its first datagram, which c2 then reads
+		c1, c2, stop, err = pair(ln, func() (interfaces.TransportConnection, error) {
+			conn, err := transports.ConnectUDP(ln.Addr().String(), 0)
+			if err == nil {
+				_, err = conn.Write([]byte("pair"))
+			}
+			return conn, err
+		})
+		if err == nil {
+			_, err = c2.ReadMessage()
+		}
+		return c1, c2, stop, err
+	}
+	transporttest.TestConn(t, mp, transporttest.Options{Datagram: true, MaxMessageSize: 60000})
+}
+
+// -----------------------------------------------------------------------------
+
+func selfSignedCert(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"SafeSocket Test"}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+}